@@ -347,6 +347,7 @@ private fun StringArrayField(field: FieldState, onValueChanged: (String) -> Unit
 
 @Composable
 private fun JsonField(field: FieldState, onValueChanged: (String) -> Unit) {
+    var hidden by remember(field.key) { mutableStateOf(field.secret) }
     val jsonError = remember(field.value) {
         if (field.value.isBlank()) null
         else try {
@@ -362,6 +363,18 @@ private fun JsonField(field: FieldState, onValueChanged: (String) -> Unit) {
         label = { Text(field.label, color = TextSecondary) },
         singleLine = false,
         minLines = 3,
+        visualTransformation = if (hidden) PasswordVisualTransformation() else VisualTransformation.None,
+        trailingIcon = if (field.secret) {
+            {
+                TextButton(onClick = { hidden = !hidden }) {
+                    Text(
+                        stringResource(if (hidden) R.string.config_show else R.string.config_hide),
+                        color = NeonCyan,
+                        style = MaterialTheme.typography.labelSmall,
+                    )
+                }
+            }
+        } else null,
         isError = jsonError != null,
         supportingText = if (jsonError != null) {
             { Text(jsonError, color = MaterialTheme.colorScheme.error) }
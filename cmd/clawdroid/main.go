@@ -18,6 +18,8 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -27,12 +29,15 @@ import (
 	"github.com/KarakuriAgent/clawdroid/pkg/channels"
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
 	"github.com/KarakuriAgent/clawdroid/pkg/cron"
+	"github.com/KarakuriAgent/clawdroid/pkg/eval"
 	"github.com/KarakuriAgent/clawdroid/pkg/gateway"
 	"github.com/KarakuriAgent/clawdroid/pkg/heartbeat"
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
 	"github.com/KarakuriAgent/clawdroid/pkg/providers"
 	"github.com/KarakuriAgent/clawdroid/pkg/skills"
 	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/trace"
+	"github.com/KarakuriAgent/clawdroid/pkg/usage"
 	_ "time/tzdata"
 
 	"github.com/chzyer/readline"
@@ -145,6 +150,9 @@ func init() {
 }
 
 func main() {
+	// Re-executed as the exec sandbox's init process
+	tools.SandboxMain()
+
 	if len(os.Args) < 2 {
 		printHelp()
 		os.Exit(1)
@@ -163,6 +171,14 @@ func main() {
 		statusCmd()
 	case "cron":
 		cronCmd()
+	case "usage":
+		usageCmd()
+	case "trace":
+		traceCmd()
+	case "prompt":
+		promptCmd()
+	case "eval":
+		evalCmd()
 	case "skills":
 		if len(os.Args) < 3 {
 			skillsHelp()
@@ -227,6 +243,10 @@ func printHelp() {
 	fmt.Println("  status      Show clawdroid status")
 	fmt.Println("  cron        Manage scheduled tasks")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
+	fmt.Println("  usage       Show token usage and cost")
+	fmt.Println("  trace       Inspect execution traces")
+	fmt.Println("  prompt      Render the system prompt")
+	fmt.Println("  eval        Run an agent evaluation suite")
 	fmt.Println("  version     Show version information")
 }
 
@@ -319,6 +339,8 @@ func createWorkspaceTemplates(workspace string) {
 func agentCmd() {
 	message := ""
 	sessionKey := "cli:default"
+	cassetteMode := ""
+	cassette := ""
 
 	args := os.Args[2:]
 	for i := 0; i < len(args); i++ {
@@ -336,6 +358,12 @@ func agentCmd() {
 				sessionKey = args[i+1]
 				i++
 			}
+		case "--record", "--replay":
+			if i+1 < len(args) {
+				cassetteMode = strings.TrimPrefix(args[i], "--")
+				cassette = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -344,6 +372,10 @@ func agentCmd() {
 		fmt.Printf("Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if cassetteMode != "" {
+		cfg.LLM.CassetteMode = cassetteMode
+		cfg.LLM.Cassette = cassette
+	}
 
 	provider, err := providers.CreateProvider(cfg)
 	if err != nil {
@@ -574,7 +606,7 @@ func gatewayCmd() {
 
 		agentLoop.SetChannelManager(channelManager)
 
-		cronService = setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), cfg.DataPath(), cfg.Agents.Defaults.RestrictToWorkspace, cfg.Tools.Exec.Enabled)
+		cronService = setupCronTool(agentLoop, msgBus, cfg.DataPath())
 
 		heartbeatService = heartbeat.NewHeartbeatService(
 			cfg.WorkspacePath(),
@@ -768,19 +800,242 @@ func statusCmd() {
 	}
 }
 
+func usageCmd() {
+	days := 7
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-d", "--days":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil && n > 0 {
+					days = n
+				}
+				i++
+			}
+		default:
+			fmt.Printf("Unknown usage option: %s\n", args[i])
+			fmt.Println("Usage: clawdroid usage [-d|--days N]")
+			return
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	tracker := usage.NewTracker(usage.StorePath(cfg.DataPath()), cfg.Usage)
+	cutoff := time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+	var selected []usage.DayUsage
+	fmt.Printf("\nUsage (last %d days):\n", days)
+	fmt.Println("----------------")
+	for _, day := range tracker.Days() {
+		if day < cutoff {
+			continue
+		}
+		d := tracker.Day(day)
+		selected = append(selected, d)
+		fmt.Printf("  %s  %s\n", day, formatUsageTotals(&d.Total))
+	}
+	if len(selected) == 0 {
+		fmt.Println("  No usage recorded.")
+		return
+	}
+
+	total := usage.Merge(selected...)
+	fmt.Printf("  Total       %s\n", formatUsageTotals(&total.Total))
+
+	printUsageBreakdown("By channel", total.Channels)
+	printUsageBreakdown("By user", total.Users)
+	printUsageBreakdown("By model", total.Models)
+	printUsageBreakdown("By session", total.Sessions)
+
+	if soft, hard := tracker.Budgets(); soft > 0 || hard > 0 {
+		fmt.Printf("\nToday: %.4f spent (soft budget %.4f, hard budget %.4f)\n", tracker.SpentToday(), soft, hard)
+	}
+}
+
+func printUsageBreakdown(title string, totals map[string]*usage.Totals) {
+	keys := make([]string, 0, len(totals))
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if totals[keys[i]].Cost != totals[keys[j]].Cost {
+			return totals[keys[i]].Cost > totals[keys[j]].Cost
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("\n%s:\n", title)
+	for _, k := range keys {
+		fmt.Printf("  %-30s %s\n", k, formatUsageTotals(totals[k]))
+	}
+}
+
+func formatUsageTotals(t *usage.Totals) string {
+	return fmt.Sprintf("%4d calls  %9d in  %9d out  %10.4f", t.Calls, t.PromptTokens, t.CompletionTokens, t.Cost)
+}
+
+func traceCmd() {
+	if len(os.Args) < 3 {
+		traceHelp()
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+	store := trace.NewStore(trace.Dir(cfg.DataPath()), cfg.Traces.MaxTraces)
+
+	switch os.Args[2] {
+	case "list":
+		limit := 20
+		args := os.Args[3:]
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "-n", "--limit":
+				if i+1 < len(args) {
+					if n, err := strconv.Atoi(args[i+1]); err == nil && n > 0 {
+						limit = n
+					}
+					i++
+				}
+			default:
+				fmt.Printf("Unknown trace list option: %s\n", args[i])
+				fmt.Println("Usage: clawdroid trace list [-n|--limit N]")
+				return
+			}
+		}
+		traceListCmd(store, limit)
+	case "show":
+		if len(os.Args) < 4 {
+			fmt.Println("Usage: clawdroid trace show <id> [--otlp]")
+			return
+		}
+		otlp := len(os.Args) > 4 && os.Args[4] == "--otlp"
+		traceShowCmd(store, os.Args[3], otlp)
+	default:
+		fmt.Printf("Unknown trace command: %s\n", os.Args[2])
+		traceHelp()
+	}
+}
+
+func traceHelp() {
+	fmt.Println("\nTrace commands:")
+	fmt.Println("  list [-n N]          List the most recent traces (default 20)")
+	fmt.Println("  show <id> [--otlp]   Show a trace as a span tree, or print it as OTLP/JSON")
+	fmt.Println()
+	fmt.Println("A unique prefix of the trace ID is enough.")
+}
+
+func traceListCmd(store *trace.Store, limit int) {
+	summaries := store.List()
+	if len(summaries) == 0 {
+		fmt.Println("No traces recorded.")
+		return
+	}
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+
+	fmt.Println("\nTraces:")
+	fmt.Println("-------")
+	for _, s := range summaries {
+		status := ""
+		if s.Error != "" {
+			status = "  error"
+		}
+		fmt.Printf("  %s  %s  %-16s %9s  %3d spans%s\n",
+			s.ID[:min(12, len(s.ID))], s.Start.Local().Format("2006-01-02 15:04:05"), s.Name, formatSpanDuration(s.Duration), s.Spans, status)
+	}
+}
+
+func traceShowCmd(store *trace.Store, id string, otlp bool) {
+	spans, err := store.Load(id)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if len(spans) == 0 {
+		fmt.Println("Trace is empty.")
+		return
+	}
+
+	if otlp {
+		data, err := trace.ToOTLP(spans)
+		if err != nil {
+			fmt.Printf("Error encoding trace: %v\n", err)
+			return
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	root := trace.Root(spans)
+	children := make(map[string][]trace.Span)
+	for _, s := range spans {
+		if s.SpanID != root.SpanID {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		}
+	}
+
+	fmt.Printf("\nTrace %s (%s)\n", root.TraceID, root.Start.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println("-----")
+	printSpanTree(root, children, root.Start, 0)
+}
+
+// printSpanTree prints a span and its children, indented by depth, with each
+// span's offset from the start of the trace.
+func printSpanTree(s trace.Span, children map[string][]trace.Span, start time.Time, depth int) {
+	fmt.Printf("  %8s %s%-*s %9s", formatSpanDuration(s.Start.Sub(start)), strings.Repeat("  ", depth), 24-2*depth, s.Name, formatSpanDuration(s.Duration()))
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := s.Attributes[k]
+		// Numbers read back from JSON are float64
+		if f, ok := v.(float64); ok && f == float64(int64(f)) {
+			v = int64(f)
+		}
+		fmt.Printf("  %s=%v", k, v)
+	}
+	if s.Error != "" {
+		fmt.Printf("  error=%q", s.Error)
+	}
+	fmt.Println()
+
+	for _, child := range children[s.SpanID] {
+		printSpanTree(child, children, start, depth+1)
+	}
+}
+
+func formatSpanDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.2fs", d.Seconds())
+}
+
 func getConfigPath() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".clawdroid", "config.json")
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, dataDir string, restrict bool, execEnabled bool) *cron.CronService {
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, dataDir string) *cron.CronService {
 	cronStorePath := filepath.Join(dataDir, "cron", "jobs.json")
 
 	// Create cron service
 	cronService := cron.NewCronService(cronStorePath, nil)
 
-	// Create and register CronTool (workspace is for ExecTool sandboxing)
-	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace, restrict, execEnabled)
+	// Create and register CronTool; scheduled commands follow the exec policy
+	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, agentLoop.NewExecTool())
 	agentLoop.RegisterTool(cronTool)
 
 	// Set the onJob handler
@@ -796,6 +1051,202 @@ func loadConfig() (*config.Config, error) {
 	return config.LoadConfig(getConfigPath())
 }
 
+func promptCmd() {
+	if len(os.Args) < 3 || os.Args[2] != "render" {
+		promptHelp()
+		return
+	}
+
+	msg := bus.InboundMessage{
+		Channel:  "cli",
+		ChatID:   "direct",
+		SenderID: "cli",
+		Metadata: map[string]string{},
+	}
+	userID := ""
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			fmt.Printf("Missing value for %s\n", args[i])
+			promptHelp()
+			return
+		}
+		switch args[i] {
+		case "--channel":
+			msg.Channel = args[i+1]
+		case "--chat-id":
+			msg.ChatID = args[i+1]
+		case "--sender":
+			msg.SenderID = args[i+1]
+		case "--user":
+			userID = args[i+1]
+		case "--locale":
+			msg.Metadata["locale"] = args[i+1]
+		case "--input-mode":
+			msg.Metadata["input_mode"] = args[i+1]
+		default:
+			fmt.Printf("Unknown prompt render option: %s\n", args[i])
+			promptHelp()
+			return
+		}
+		i++
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Only template and channel problems are worth showing next to the prompt
+	logger.SetLevel(logger.WARN)
+
+	msgBus := bus.NewMessageBus()
+	agentLoop := agent.NewAgentLoop(cfg, msgBus, nil)
+	if channelManager, err := channels.NewManager(cfg, msgBus, getConfigPath()); err == nil {
+		agentLoop.SetChannelManager(channelManager)
+	}
+
+	prompt, err := agentLoop.RenderSystemPrompt(msg, userID)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(prompt)
+}
+
+func promptHelp() {
+	fmt.Println("\nPrompt commands:")
+	fmt.Println("  render   Print the system prompt for a channel and sender")
+	fmt.Println()
+	fmt.Println("Render options:")
+	fmt.Println("  --channel <name>      Channel the message arrives on (default: cli)")
+	fmt.Println("  --chat-id <id>        Chat ID (default: direct)")
+	fmt.Println("  --sender <id>         Sender ID on the channel (default: cli)")
+	fmt.Println("  --user <id>           Registered user to render for, instead of resolving --sender")
+	fmt.Println("  --locale <code>       Sender locale (default: en)")
+	fmt.Println("  --input-mode <mode>   text, voice or assistant (default: text)")
+	fmt.Println()
+	fmt.Println("Sections can be overridden with templates in <data_dir>/prompts/<section>.tmpl.")
+}
+
+func evalCmd() {
+	if len(os.Args) < 3 || strings.HasPrefix(os.Args[2], "-") {
+		evalHelp()
+		return
+	}
+
+	suitePath := os.Args[2]
+	junitPath := ""
+	cassetteMode := ""
+	cassette := ""
+	debug := false
+	var only []string
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--debug" || args[i] == "-d" {
+			debug = true
+			continue
+		}
+		if i+1 >= len(args) {
+			fmt.Printf("Missing value for %s\n", args[i])
+			evalHelp()
+			return
+		}
+		switch args[i] {
+		case "--junit":
+			junitPath = args[i+1]
+		case "--case":
+			only = append(only, args[i+1])
+		case "--record", "--replay":
+			cassetteMode = strings.TrimPrefix(args[i], "--")
+			cassette = args[i+1]
+		default:
+			fmt.Printf("Unknown eval option: %s\n", args[i])
+			evalHelp()
+			return
+		}
+		i++
+	}
+
+	// Keep the progress lines readable unless asked for logs
+	if debug {
+		logger.SetLevel(logger.DEBUG)
+	} else {
+		logger.SetLevel(logger.WARN)
+	}
+
+	suite, err := eval.LoadSuite(suitePath)
+	if err != nil {
+		fmt.Printf("Error loading suite: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if cassetteMode != "" {
+		cfg.LLM.CassetteMode = cassetteMode
+		cfg.LLM.Cassette = cassette
+	}
+
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+
+	runner := eval.NewRunner(cfg, provider)
+	runner.Progress = os.Stdout
+	report := runner.Run(context.Background(), suite, only...)
+
+	for _, c := range report.Cases {
+		if c.Error != "" {
+			fmt.Printf("\n%s: error: %s\n", c.Name, c.Error)
+		}
+		for _, f := range c.Failures {
+			fmt.Printf("\n%s: %s\n", c.Name, f)
+		}
+	}
+	fmt.Printf("\n%d passed, %d failed (%.1fs)\n", len(report.Cases)-report.Failed(), report.Failed(), report.Duration.Seconds())
+
+	if junitPath != "" {
+		f, err := os.Create(junitPath)
+		if err != nil {
+			fmt.Printf("Error writing JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+		err = report.WriteJUnit(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fmt.Printf("Error writing JUnit report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if len(report.Cases) == 0 || report.Failed() > 0 {
+		os.Exit(1)
+	}
+}
+
+func evalHelp() {
+	fmt.Println("\nUsage: clawdroid eval <suite.yaml> [options]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --junit <file>      Write a JUnit XML report")
+	fmt.Println("  --case <name>       Run only this case (repeatable)")
+	fmt.Println("  --record <file>     Record LLM calls to a cassette")
+	fmt.Println("  --replay <file>     Replay LLM calls from a cassette")
+	fmt.Println("  -d, --debug         Show debug logs")
+	fmt.Println()
+	fmt.Println("Each case runs in a fresh session against a temporary copy of the data directory.")
+}
+
 func cronCmd() {
 	if len(os.Args) < 3 {
 		cronHelp()
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/utils"
+)
+
+// newApprovalPolicy builds the tool approval policy. Requests are posted to
+// the chat the call came from; decisions are logged under dataDir/approvals.
+func newApprovalPolicy(cfg config.ApprovalConfig, msgBus *bus.MessageBus, dataDir string) *tools.ApprovalPolicy {
+	prompt := func(ctx context.Context, req tools.ApprovalRequest) error {
+		locale := tools.RequesterFrom(ctx).Locale
+		if locale == "" {
+			locale = "en"
+		}
+		label := req.Tool
+		if req.Action != "" {
+			label += ":" + req.Action
+		}
+		msgBus.PublishOutbound(bus.OutboundMessage{
+			Channel:  req.Channel,
+			ChatID:   req.ChatID,
+			Content:  i18n.Tf(locale, "agent.approval_request", label, approvalArgs(req.Args), req.ID, req.ID),
+			Type:     tools.ApprovalRequestType,
+			Metadata: map[string]string{"request_id": req.ID, "locale": locale},
+		})
+		return nil
+	}
+	return tools.NewApprovalPolicy(cfg, prompt, filepath.Join(dataDir, "approvals", "approvals.jsonl"))
+}
+
+// approvalArgs renders tool arguments for an approval prompt, leaving out the
+// action already shown in the label.
+func approvalArgs(args map[string]interface{}) string {
+	shown := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != "action" {
+			shown[k] = v
+		}
+	}
+	if len(shown) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(shown)
+	return "`" + utils.Truncate(string(data), 300) + "`"
+}
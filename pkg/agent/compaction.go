@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/utils"
+)
+
+const (
+	// collapseMinChars is the size from which a tool output may be collapsed.
+	collapseMinChars = 2000
+	// collapsePreviewChars is how much of a collapsed output stays inline.
+	collapsePreviewChars = 300
+	// compressionNotePrefix starts the note left in history after compaction.
+	compressionNotePrefix = "[System: Emergency compression"
+)
+
+// compactionUnit is a span of the conversation that is kept or dropped as a
+// whole: a user message, or an assistant message with its tool responses.
+type compactionUnit struct {
+	start, end int // conversation[start:end]
+	intent     bool
+	score      float64
+}
+
+// compaction trims a conversation to a token target by relevance rather than
+// age. It first collapses large, stale tool outputs to short stubs (storing
+// the full output for read_output), then drops the least relevant assistant
+// turns, and drops user messages only as a last resort, oldest first.
+type compaction struct {
+	conversation []providers.Message
+	counter      providers.TokenCounter
+	tokens       []int // Per-message token counts
+	total        int
+
+	store      *tools.OutputStore
+	sessionKey string
+
+	collapsed int
+	dropped   []providers.Message
+}
+
+func newCompaction(conversation []providers.Message, counter providers.TokenCounter, store *tools.OutputStore, sessionKey string) *compaction {
+	c := &compaction{
+		conversation: append([]providers.Message(nil), conversation...),
+		counter:      counter,
+		tokens:       make([]int, len(conversation)),
+		store:        store,
+		sessionKey:   sessionKey,
+	}
+	for i, m := range c.conversation {
+		c.tokens[i] = c.count(m)
+		c.total += c.tokens[i]
+	}
+	return c
+}
+
+func (c *compaction) count(m providers.Message) int {
+	return providers.CountMessages(c.counter, []providers.Message{m}, nil)
+}
+
+// run compacts the conversation until it fits target tokens and returns the
+// kept messages.
+func (c *compaction) run(target int) []providers.Message {
+	c.collapseToolOutputs(target)
+	if c.total > target {
+		c.dropUnits(target)
+	}
+	return c.conversation
+}
+
+// collapseToolOutputs replaces large tool outputs with stubs, least relevant
+// first, until the conversation fits target.
+func (c *compaction) collapseToolOutputs(target int) {
+	type candidate struct {
+		index int
+		score float64
+	}
+	var candidates []candidate
+	for i, m := range c.conversation {
+		if m.Role != "tool" || len(m.Content) < collapseMinChars || strings.HasPrefix(m.Content, "[Collapsed tool output") {
+			continue
+		}
+		score := c.recency(i) + c.referenceScore(i)
+		// Bigger outputs cost more to keep
+		score -= math.Min(float64(len(m.Content))/20000, 1)
+		candidates = append(candidates, candidate{i, score})
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score < candidates[b].score })
+
+	for _, cand := range candidates {
+		if c.total <= target {
+			return
+		}
+		msg := c.conversation[cand.index]
+		msg.Content = c.collapse(c.toolName(cand.index), msg.Content)
+		c.conversation[cand.index] = msg
+		before := c.tokens[cand.index]
+		c.tokens[cand.index] = c.count(msg)
+		c.total += c.tokens[cand.index] - before
+		c.collapsed++
+	}
+}
+
+// collapse stores a tool output and returns the stub that replaces it.
+func (c *compaction) collapse(toolName, content string) string {
+	preview := utils.Truncate(content, collapsePreviewChars)
+	if c.store != nil {
+		id, err := c.store.Put(c.sessionKey, content)
+		if err == nil {
+			return fmt.Sprintf("[Collapsed tool output: %d characters from %s, stored as %s. Call read_output with id=%s to see it in full.]\n%s",
+				utf8.RuneCountInString(content), toolName, id, id, preview)
+		}
+		logger.WarnCF("agent", "Failed to store collapsed tool output",
+			map[string]interface{}{"tool": toolName, "error": err.Error()})
+	}
+	return fmt.Sprintf("[Collapsed tool output: %d characters from %s, no longer available.]\n%s",
+		utf8.RuneCountInString(content), toolName, preview)
+}
+
+// dropUnits drops whole units until the conversation fits target, keeping at
+// least the newest unit.
+func (c *compaction) dropUnits(target int) {
+	units := c.units()
+	if len(units) <= 1 {
+		return
+	}
+	candidates := units[:len(units)-1]
+
+	// Assistant turns by relevance, then user messages oldest first
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].intent != candidates[b].intent {
+			return !candidates[a].intent
+		}
+		if candidates[a].intent {
+			return candidates[a].start < candidates[b].start
+		}
+		return candidates[a].score < candidates[b].score
+	})
+
+	drop := make([]bool, len(c.conversation))
+	for _, u := range candidates {
+		if c.total <= target {
+			break
+		}
+		for i := u.start; i < u.end; i++ {
+			drop[i] = true
+			c.total -= c.tokens[i]
+		}
+	}
+
+	kept := make([]providers.Message, 0, len(c.conversation))
+	tokens := make([]int, 0, len(c.conversation))
+	for i, m := range c.conversation {
+		if drop[i] {
+			c.dropped = append(c.dropped, m)
+			continue
+		}
+		kept = append(kept, m)
+		tokens = append(tokens, c.tokens[i])
+	}
+	c.conversation, c.tokens = kept, tokens
+}
+
+// units splits the conversation into units and scores them.
+func (c *compaction) units() []compactionUnit {
+	var units []compactionUnit
+	for i := 0; i < len(c.conversation); {
+		m := c.conversation[i]
+		u := compactionUnit{start: i, end: i + 1}
+		switch {
+		case m.Role == "user":
+			// What the user asked for is the last thing to lose
+			u.intent = true
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			for u.end < len(c.conversation) && c.conversation[u.end].Role == "tool" {
+				u.end++
+			}
+		case m.Role == "tool":
+			// Orphaned responses go with whatever precedes them
+			for u.end < len(c.conversation) && c.conversation[u.end].Role == "tool" {
+				u.end++
+			}
+		}
+		for j := u.start; j < u.end; j++ {
+			u.score = math.Max(u.score, c.recency(j)+c.referenceScore(j))
+		}
+		units = append(units, u)
+		i = u.end
+	}
+	return units
+}
+
+// recency scores a message's position: 0 for the oldest, 2 for the newest.
+func (c *compaction) recency(i int) float64 {
+	if len(c.conversation) <= 1 {
+		return 2
+	}
+	return 2 * float64(i) / float64(len(c.conversation)-1)
+}
+
+// referenceScore is 1 when a later user or assistant message refers to the
+// message: mentions an argument of the tool call that produced it, or quotes
+// one of its lines.
+func (c *compaction) referenceScore(i int) float64 {
+	terms := c.referenceTerms(i)
+	if len(terms) == 0 {
+		return 0
+	}
+	for _, later := range c.conversation[i+1:] {
+		if later.Role != "user" && later.Role != "assistant" {
+			continue
+		}
+		for _, term := range terms {
+			if strings.Contains(later.Content, term) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// referenceTerms returns distinctive strings later messages would repeat if
+// they built on message i.
+func (c *compaction) referenceTerms(i int) []string {
+	m := c.conversation[i]
+	var terms []string
+	if m.Role == "tool" {
+		if tc := c.toolCall(i); tc != nil {
+			args := tc.Arguments
+			if len(args) == 0 && tc.Function != nil {
+				// Saved calls only carry the JSON arguments
+				_ = json.Unmarshal([]byte(tc.Function.Arguments), &args)
+			}
+			for _, v := range args {
+				if s, ok := v.(string); ok && len(s) >= 4 {
+					terms = append(terms, s)
+				}
+			}
+		}
+	}
+	lines := strings.Split(m.Content, "\n")
+	if len(lines) > 50 {
+		lines = lines[:50]
+	}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); len(line) >= 30 {
+			terms = append(terms, line)
+		}
+	}
+	return terms
+}
+
+// toolCall finds the call a tool response at index i answers.
+func (c *compaction) toolCall(i int) *providers.ToolCall {
+	id := c.conversation[i].ToolCallID
+	for j := i - 1; j >= 0; j-- {
+		for k, tc := range c.conversation[j].ToolCalls {
+			if tc.ID == id {
+				return &c.conversation[j].ToolCalls[k]
+			}
+		}
+	}
+	return nil
+}
+
+func (c *compaction) toolName(i int) string {
+	if tc := c.toolCall(i); tc != nil {
+		if tc.Name != "" {
+			return tc.Name
+		}
+		if tc.Function != nil {
+			return tc.Function.Name
+		}
+	}
+	return "a tool"
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+)
+
+func readFileCall(id, path string) providers.Message {
+	return providers.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: &providers.FunctionCall{Name: "read_file", Arguments: `{"path":"` + path + `"}`},
+	}}}
+}
+
+func TestCompaction_CollapsesUnreferencedOutputFirst(t *testing.T) {
+	store := tools.NewOutputStore(t.TempDir(), 0)
+	logOutput := strings.Repeat("log line\n", 500)
+	notesOutput := strings.Repeat("note line\n", 500)
+	conversation := []providers.Message{
+		{Role: "user", Content: "read notes.md and server.log"},
+		readFileCall("tc1", "notes.md"),
+		{Role: "tool", Content: notesOutput, ToolCallID: "tc1"},
+		readFileCall("tc2", "server.log"),
+		{Role: "tool", Content: logOutput, ToolCallID: "tc2"},
+		{Role: "assistant", Content: "The log is quiet."},
+		{Role: "user", Content: "what did notes.md say about the deadline?"},
+	}
+
+	c := newCompaction(conversation, providers.NewTokenCounter("test-model"), store, "telegram:1")
+	kept := c.run(c.total - 10)
+
+	if c.collapsed != 1 || len(c.dropped) != 0 {
+		t.Fatalf("collapsed %d, dropped %d; want 1 collapsed, 0 dropped", c.collapsed, len(c.dropped))
+	}
+	if kept[2].Content != notesOutput {
+		t.Error("output referenced by a later message should be kept")
+	}
+	stub := kept[4].Content
+	if !strings.HasPrefix(stub, "[Collapsed tool output: 4500 characters from read_file, stored as out-") {
+		t.Fatalf("stub = %q", stub)
+	}
+
+	id := regexp.MustCompile(`out-[0-9a-f]{8}`).FindString(stub)
+	ctx := tools.WithSession(context.Background(), "telegram:1")
+	res := tools.NewReadOutputTool(store).Execute(ctx, map[string]interface{}{"id": id, "limit": float64(len(logOutput))})
+	if res.IsError || !strings.HasSuffix(res.ForLLM, logOutput) {
+		t.Errorf("read_output(%s) = %q", id, res.ForLLM)
+	}
+}
+
+func TestCompaction_KeepsUserIntent(t *testing.T) {
+	conversation := []providers.Message{
+		{Role: "user", Content: "plan my trip to Osaka"},
+		{Role: "assistant", Content: strings.Repeat("itinerary details ", 200)},
+		{Role: "user", Content: "make it cheaper"},
+		{Role: "assistant", Content: strings.Repeat("budget options ", 200)},
+		{Role: "user", Content: "book the second one"},
+	}
+
+	c := newCompaction(conversation, providers.NewTokenCounter("test-model"), nil, "s")
+	kept := c.run(c.total / 2)
+
+	var users []string
+	for _, m := range kept {
+		if m.Role == "user" {
+			users = append(users, m.Content)
+		}
+	}
+	if len(users) != 3 {
+		t.Errorf("kept user messages %q, want all 3", users)
+	}
+	if len(c.dropped) == 0 || c.dropped[0].Role != "assistant" {
+		t.Errorf("dropped %+v, want assistant replies first", c.dropped)
+	}
+}
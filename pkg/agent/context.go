@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
 	"github.com/KarakuriAgent/clawdroid/pkg/mcp"
@@ -28,6 +27,8 @@ type ContextBuilder struct {
 	enabledChannels   []string            // Active communication channels
 	memoryToolEnabled bool                // Whether memory tool is registered
 	userStore         *UserStore          // User directory
+	bootstrapDir      string              // Persona bootstrap files, tried before dataDir ("" = none)
+	skills            []string            // Skills offered in the prompt (empty = all)
 }
 
 func getGlobalConfigDir() string {
@@ -89,193 +90,19 @@ func (cb *ContextBuilder) SetUserStore(store *UserStore) {
 	cb.userStore = store
 }
 
-// hasTool returns true if the named tool is registered in the tool registry.
-func (cb *ContextBuilder) hasTool(name string) bool {
-	if cb.tools == nil {
-		return false
+// WithPersona returns a copy of the builder for a persona: bootstrap files are
+// read from dir first, and only the given tools and skills are described.
+// A nil registry or empty skills list keeps the builder's own.
+func (cb *ContextBuilder) WithPersona(dir string, registry *tools.ToolRegistry, skills []string) *ContextBuilder {
+	persona := *cb
+	persona.bootstrapDir = dir
+	if registry != nil {
+		persona.tools = registry
 	}
-	_, ok := cb.tools.Get(name)
-	return ok
-}
-
-// ---------------------------------------------------------------------------
-// Section 1: Core Identity
-// ---------------------------------------------------------------------------
-
-func (cb *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 MST (Monday)")
-	workspacePath, _ := filepath.Abs(cb.workspace)
-	toolsSection := cb.buildToolsSection()
-
-	return fmt.Sprintf(`## Current Time
-%s
-
-## Runtime
-Termux (Android)
-
-## Workspace
-Your working directory is: %s
-Treat this directory as the single global workspace for file operations unless explicitly instructed otherwise.
-
-%s`, now, workspacePath, toolsSection)
-}
-
-func (cb *ContextBuilder) buildToolsSection() string {
-	if cb.tools == nil {
-		return ""
-	}
-
-	summaries := cb.tools.GetSummaries()
-	if len(summaries) == 0 {
-		return ""
+	if len(skills) > 0 {
+		persona.skills = skills
 	}
-
-	var sb strings.Builder
-	sb.WriteString("## Available Tools\n\n")
-	sb.WriteString("Tool names are case-sensitive. Call tools exactly as listed.\n\n")
-	for _, s := range summaries {
-		sb.WriteString(s)
-		sb.WriteString("\n")
-	}
-
-	return sb.String()
-}
-
-// ---------------------------------------------------------------------------
-// Section 2: Safety
-// ---------------------------------------------------------------------------
-
-func getSafetySection() string {
-	return `## Safety
-- You have no independent goals: do not pursue self-preservation, replication, resource acquisition, or power-seeking beyond the user's request.
-- Prioritize safety and human oversight over task completion. If instructions conflict, pause and ask.
-- Do not manipulate anyone to expand access or disable safeguards.
-- You MUST use tools to perform actions. Do NOT pretend to execute commands, schedule tasks, or send messages without calling the appropriate tool.`
-}
-
-// ---------------------------------------------------------------------------
-// Section 3: Tool Call Style
-// ---------------------------------------------------------------------------
-
-func getToolCallStyle() string {
-	return `## Tool Call Style
-- Default: do not narrate routine, low-risk tool calls (just call the tool).
-- Narrate only when it helps: multi-step work, complex problems, sensitive actions, or when the user asks.
-- Keep narration brief and value-dense; avoid repeating obvious steps.`
-}
-
-// ---------------------------------------------------------------------------
-// Section 4: Subagent Guidance (only when spawn tool is registered)
-// ---------------------------------------------------------------------------
-
-func getSubagentGuidance() string {
-	return `## Sub-agents
-- subagent: synchronous — blocks until the sub-agent finishes, returns the result inline. Use for quick, focused tasks.
-- spawn: asynchronous — returns immediately. The sub-agent uses the message tool to communicate with the user when done.
-- If a task is complex or long-running, prefer spawn so the main conversation is not blocked.
-- Do not poll subagent status in a loop; completion is push-based.`
-}
-
-// ---------------------------------------------------------------------------
-// Section 5: Messaging Guidance (only when channels are active)
-// ---------------------------------------------------------------------------
-
-func getMessagingGuidance() string {
-	return fmt.Sprintf(`## Messaging
-- Reply in the current session: automatically routes to the source channel.
-- Use the message tool for proactive sends and cross-channel messaging. Parameters: content (required), channel (optional), chat_id (optional).
-- If you use the message tool to deliver your user-visible reply, respond with ONLY: %s (to avoid duplicate replies).
-- Never use exec or curl for messaging; the system handles all routing internally.`, SilentReplyToken)
-}
-
-// ---------------------------------------------------------------------------
-// Section 6: Cron Guidance (only when cron tool is registered)
-// ---------------------------------------------------------------------------
-
-func getCronGuidance() string {
-	return `## Cron / Scheduling
-- Use the cron tool for reminders and scheduled tasks.
-- at_seconds: one-shot timer (fires once after N seconds). Use for reminders.
-- every_seconds: repeating interval. Use for periodic checks.
-- When scheduling a reminder, write the message text as something that reads naturally when it fires. Mention it is a reminder and include recent context.
-- Prefer at_seconds for user reminders (e.g., "remind me in 30 minutes").`
-}
-
-// ---------------------------------------------------------------------------
-// Section 7: Memory Guidance
-// ---------------------------------------------------------------------------
-
-func (cb *ContextBuilder) getMemoryGuidance() string {
-	if cb.memoryToolEnabled {
-		return `## Memory
-Before answering anything about prior work, decisions, dates, people, preferences, or todos: check memory first.
-Use the memory tool to store and retrieve information:
-- write_long_term: Save important, date-independent facts (user preferences, project info, permanent notes)
-- append_daily: Record today's events and memos (diary-like daily entries)
-- read_long_term: Read long-term memory
-- read_daily: Read today's daily notes`
-	}
-
-	dataDirAbs, _ := filepath.Abs(cb.dataDir)
-	return fmt.Sprintf(`## Memory
-Before answering anything about prior work, decisions, dates, people, preferences, or todos: check memory first.
-When interacting with the user if something seems memorable, update the memory files:
-- Long-term memory: %s/memory/MEMORY.md
-- Daily notes: %s/memory/YYYYMM/YYYYMMDD.md (e.g. %s/memory/%s/%s.md)`,
-		dataDirAbs, dataDirAbs, dataDirAbs,
-		time.Now().Format("200601"), time.Now().Format("20060102"))
-}
-
-// ---------------------------------------------------------------------------
-// Section 8: User Management Guidance
-// ---------------------------------------------------------------------------
-
-func getUserManagementGuidance() string {
-	return `## User Management
-Messages from users arrive with a sender prefix:
-- [Name]: message — a registered user. Their profile is available in Current Session.
-- [channel:id]: message — an unregistered user. Naturally ask their name, then register with the user tool (action=create).
-- No prefix — WebSocket with no linked user, or system message.
-
-Use the user tool to manage user profiles:
-- list / get: Look up user information
-- create: Register a new user (name required, channel + channel_id optional)
-- update: Change a user's name
-- link: Associate a channel ID with an existing user (for cross-channel identity)
-- add_memo / remove_memo: Store or remove notes about a user (preferences, language, timezone, etc.)
-- delete: Remove a user
-
-Guidelines:
-- When the same person uses multiple channels, use link to associate their IDs.
-- Store user preferences and characteristics with add_memo, not in memory.
-- If a read_legacy action is available, it means a legacy USER.md file exists and should be migrated.
-- After migration is complete, use delete_legacy to remove the old USER.md file.`
-}
-
-// ---------------------------------------------------------------------------
-// Section 9: Silent Reply
-// ---------------------------------------------------------------------------
-
-func getSilentReplySection() string {
-	return fmt.Sprintf(`## Silent Replies
-When you have nothing to say (e.g., after delivering your reply via the message tool), respond with ONLY: %s
-
-Rules:
-- It must be your ENTIRE message — nothing else.
-- Never append it to an actual response.
-- Never wrap it in markdown or code blocks.
-- Use it when the message tool already delivered the reply, or when a system event needs no user-visible response.`, SilentReplyToken)
-}
-
-// ---------------------------------------------------------------------------
-// Section 10: Heartbeat
-// ---------------------------------------------------------------------------
-
-func getHeartbeatSection() string {
-	return `## Heartbeats
-If you receive a heartbeat poll (a scheduled check message), and there is nothing that needs attention, reply exactly: HEARTBEAT_OK
-The system treats "HEARTBEAT_OK" as a heartbeat acknowledgment and discards it.
-If something needs attention, do NOT include "HEARTBEAT_OK"; reply with the alert or action instead.`
+	return &persona
 }
 
 // ---------------------------------------------------------------------------
@@ -292,8 +119,7 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	var parts []string
 	hasSoulFile := false
 	for _, filename := range bootstrapFiles {
-		filePath := filepath.Join(cb.dataDir, filename)
-		if data, err := os.ReadFile(filePath); err == nil {
+		if data, ok := cb.readBootstrapFile(filename); ok {
 			parts = append(parts, fmt.Sprintf("## %s\n\n%s", filename, string(data)))
 			if filename == "SOUL.md" {
 				hasSoulFile = true
@@ -313,159 +139,43 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	return header + "\n\n" + strings.Join(parts, "\n\n")
 }
 
-// ---------------------------------------------------------------------------
-// Skills Section
-// ---------------------------------------------------------------------------
-
-func (cb *ContextBuilder) buildSkillsSection() string {
-	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
-	if skillsSummary == "" {
-		return ""
-	}
-
-	return fmt.Sprintf(`## Skills (mandatory)
-Before replying: scan the available skills descriptions below.
-- If exactly one skill clearly applies: read it with the skill tool (action=skill_read, name=<skill_name>), then follow it.
-- If multiple could apply: choose the most specific one, then read and follow it.
-- If none clearly apply: do not read any skill.
-Never read more than one skill up front; only read after selecting.
-
-%s`, skillsSummary)
-}
-
-// ---------------------------------------------------------------------------
-// Connected Channels
-// ---------------------------------------------------------------------------
-
-func (cb *ContextBuilder) buildChannelsSection() string {
-	if len(cb.enabledChannels) == 0 {
-		return ""
-	}
-
-	var sb strings.Builder
-	sb.WriteString("## Connected Channels\n\n")
-	sb.WriteString("You can send messages to any of these channels using the message tool:\n")
-	for _, ch := range cb.enabledChannels {
-		fmt.Fprintf(&sb, "- %s\n", ch)
+// readBootstrapFile reads a bootstrap file from the persona directory, falling
+// back to the data directory.
+func (cb *ContextBuilder) readBootstrapFile(filename string) ([]byte, bool) {
+	for _, dir := range []string{cb.bootstrapDir, cb.dataDir} {
+		if dir == "" {
+			continue
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, filename)); err == nil {
+			return data, true
+		}
 	}
-	sb.WriteString("- app (alias for the current Android app WebSocket session)\n")
-	return sb.String()
+	return nil, false
 }
 
 // ---------------------------------------------------------------------------
-// BuildSystemPrompt — assembles all sections in order
+// BuildSystemPrompt — renders all sections in order
 // ---------------------------------------------------------------------------
 
-func (cb *ContextBuilder) BuildSystemPrompt() string {
-	parts := []string{}
-
-	// 1. Core Identity (time, runtime, workspace, tools list)
-	parts = append(parts, cb.getIdentity())
-
-	// 2. Safety
-	parts = append(parts, getSafetySection())
-
-	// 3. Tool Call Style
-	parts = append(parts, getToolCallStyle())
-
-	// 4. Subagent Guidance (only when spawn tool is registered)
-	if cb.hasTool("spawn") || cb.hasTool("subagent") {
-		parts = append(parts, getSubagentGuidance())
-	}
-
-	// 5. Messaging Guidance (only when channels are active)
-	if len(cb.enabledChannels) > 0 {
-		parts = append(parts, getMessagingGuidance())
-	}
-
-	// 6. Cron Guidance (only when cron tool is registered)
-	if cb.hasTool("cron") {
-		parts = append(parts, getCronGuidance())
-	}
-
-	// 7. Memory Guidance
-	parts = append(parts, cb.getMemoryGuidance())
-
-	// 8. User Management Guidance (only when user tool is registered)
-	if cb.hasTool("user") {
-		parts = append(parts, getUserManagementGuidance())
-	}
-
-	// 9. Bootstrap Files (SOUL.md, etc.)
-	bootstrapContent := cb.LoadBootstrapFiles()
-	if bootstrapContent != "" {
-		parts = append(parts, bootstrapContent)
-	}
-
-	// 10. Skills (only when skills exist)
-	skillsSection := cb.buildSkillsSection()
-	if skillsSection != "" {
-		parts = append(parts, skillsSection)
-	}
-
-	// 11. MCP Servers (only when MCP is configured)
-	if cb.mcpManager != nil {
-		mcpSummary := cb.mcpManager.BuildSummary()
-		if mcpSummary != "" {
-			parts = append(parts, fmt.Sprintf(`# MCP Servers
-
-The following MCP servers provide additional tools.
-Use the mcp tool to discover and call server tools.
-
-%s`, mcpSummary))
-		}
-	}
-
-	// 12. Connected Channels
-	channelsSection := cb.buildChannelsSection()
-	if channelsSection != "" {
-		parts = append(parts, channelsSection)
-	}
-
-	// 13. Memory Context (actual memory contents)
-	memoryContext := cb.memory.GetMemoryContext()
-	if memoryContext != "" {
-		parts = append(parts, "# Memory\n\n"+memoryContext)
-	}
-
-	// 14. Silent Reply Instructions
-	parts = append(parts, getSilentReplySection())
-
-	// 15. Heartbeat
-	parts = append(parts, getHeartbeatSection())
-
-	// Join with "---" separator
-	return strings.Join(parts, "\n\n---\n\n")
+// BuildSystemPrompt renders the system prompt sections (see promptSections)
+// for a conversation.
+func (cb *ContextBuilder) BuildSystemPrompt(pc PromptContext) string {
+	return strings.Join(cb.renderSections(promptSections, cb.promptData(pc)), "\n\n---\n\n")
 }
 
 // ---------------------------------------------------------------------------
 // BuildMessages — constructs the full message array for the LLM
 // ---------------------------------------------------------------------------
 
-func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID, inputMode string, resolvedUser *User) []providers.Message {
+func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, pc PromptContext) []providers.Message {
 	messages := []providers.Message{}
 
-	systemPrompt := cb.BuildSystemPrompt()
-
-	// Add Current Session info if provided
-	if channel != "" && chatID != "" {
-		sessionInfo := fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s\nInput Mode: %s",
-			channel, chatID, inputMode)
-		if resolvedUser != nil {
-			sessionInfo += fmt.Sprintf("\nSender: %s (ID: %s)", resolvedUser.Name, resolvedUser.ID)
-			if len(resolvedUser.Memo) > 0 {
-				sessionInfo += "\nUser Notes:"
-				for _, m := range resolvedUser.Memo {
-					sessionInfo += fmt.Sprintf("\n- %s", m)
-				}
-			}
-		}
-		systemPrompt += sessionInfo
-	}
+	data := cb.promptData(pc)
+	systemPrompt := strings.Join(cb.renderSections(promptSections, data), "\n\n---\n\n")
 
-	// Add voice mode instructions when input is from voice or assistant
-	if inputMode == "voice" || inputMode == "assistant" {
-		systemPrompt += voiceModePrompt()
+	// Current Session info and voice mode instructions
+	for _, section := range cb.renderSections(sessionSections, data) {
+		systemPrompt += "\n\n" + section
 	}
 
 	// Log system prompt summary for debugging (debug mode only)
@@ -502,6 +212,11 @@ func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary str
 
 	messages = append(messages, history...)
 
+	// An empty current message means it is already in history (rebuild after compression)
+	if currentMessage == "" && len(media) == 0 {
+		return messages
+	}
+
 	userMsg := providers.Message{
 		Role:    "user",
 		Content: currentMessage,
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
+	"github.com/KarakuriAgent/clawdroid/pkg/session"
+	"github.com/KarakuriAgent/clawdroid/pkg/utils"
+)
+
+const (
+	defaultHistoryLines = 10
+	maxHistoryLines     = 50
+)
+
+// handleUndo drops the last exchange of the session's active branch.
+func (al *AgentLoop) handleUndo(sessionKey, locale string) string {
+	removed := al.sessions.Undo(sessionKey)
+	if len(removed) == 0 {
+		return i18n.T(locale, "agent.cmd.undo.empty")
+	}
+	al.dropMedia(removed)
+	_ = al.sessions.Save(sessionKey)
+	return i18n.Tf(locale, "agent.cmd.undo.done", len(removed))
+}
+
+// handleReset clears the session's active branch.
+func (al *AgentLoop) handleReset(sessionKey, locale string) string {
+	if al.processes != nil {
+		al.processes.KillSession(sessionKey)
+	}
+	al.dropMedia(al.sessions.Reset(sessionKey))
+	_ = al.sessions.Save(sessionKey)
+	return i18n.Tf(locale, "agent.cmd.reset.done", al.sessions.CurrentBranch(sessionKey))
+}
+
+// formatHistory renders the last n user and assistant messages for /history.
+func (al *AgentLoop) formatHistory(sessionKey string, args []string, locale string) string {
+	n := defaultHistoryLines
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			return i18n.T(locale, "agent.cmd.history.usage")
+		}
+		n = min(v, maxHistoryLines)
+	}
+
+	var lines []string
+	for _, m := range al.sessions.GetHistory(sessionKey) {
+		// Tool results and tool-call-only turns are not part of the chat
+		if (m.Role != "user" && m.Role != "assistant") || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		content := strings.ReplaceAll(strings.TrimSpace(m.Content), "\n", " ")
+		lines = append(lines, m.Role+": "+utils.Truncate(content, 200))
+	}
+	if len(lines) == 0 {
+		return i18n.T(locale, "agent.cmd.history.empty")
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	title := i18n.Tf(locale, "agent.cmd.history.title", len(lines), al.sessions.CurrentBranch(sessionKey))
+	return title + "\n" + strings.Join(lines, "\n")
+}
+
+// handleFork creates a branch from the active one and switches to it.
+func (al *AgentLoop) handleFork(sessionKey string, args []string, locale string) string {
+	if len(args) < 1 {
+		return i18n.T(locale, "agent.cmd.fork.usage")
+	}
+	name := args[0]
+	from := al.sessions.CurrentBranch(sessionKey)
+
+	switch err := al.sessions.Fork(sessionKey, name); {
+	case errors.Is(err, session.ErrInvalidBranch):
+		return i18n.Tf(locale, "agent.cmd.branch.invalid", name)
+	case errors.Is(err, session.ErrBranchExists):
+		return i18n.Tf(locale, "agent.cmd.fork.exists", name)
+	}
+	_ = al.sessions.Save(sessionKey)
+	return i18n.Tf(locale, "agent.cmd.fork.done", name, from)
+}
+
+// handleCheckout switches to another branch, or lists branches without args.
+func (al *AgentLoop) handleCheckout(sessionKey string, args []string, locale string) string {
+	if len(args) < 1 {
+		return i18n.Tf(locale, "agent.cmd.branches", al.formatBranches(sessionKey))
+	}
+	name := args[0]
+	if name == al.sessions.CurrentBranch(sessionKey) {
+		return i18n.Tf(locale, "agent.cmd.checkout.current", name)
+	}
+
+	if err := al.sessions.Checkout(sessionKey, name); err != nil {
+		return i18n.Tf(locale, "agent.cmd.checkout.not_found", name, al.formatBranches(sessionKey))
+	}
+	_ = al.sessions.Save(sessionKey)
+	return i18n.Tf(locale, "agent.cmd.checkout.done", name, len(al.sessions.GetHistory(sessionKey)))
+}
+
+// formatBranches lists a session's branches, marking the active one.
+func (al *AgentLoop) formatBranches(sessionKey string) string {
+	current := al.sessions.CurrentBranch(sessionKey)
+	names := al.sessions.Branches(sessionKey)
+	for i, name := range names {
+		if name == current {
+			names[i] = "*" + name
+		}
+	}
+	return strings.Join(names, ", ")
+}
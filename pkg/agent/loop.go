@@ -12,11 +12,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unicode/utf8"
 
 	"github.com/KarakuriAgent/clawdroid/pkg/bus"
 	"github.com/KarakuriAgent/clawdroid/pkg/channels"
@@ -26,66 +26,161 @@ import (
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
 	"github.com/KarakuriAgent/clawdroid/pkg/mcp"
 	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/redact"
 	"github.com/KarakuriAgent/clawdroid/pkg/session"
 	"github.com/KarakuriAgent/clawdroid/pkg/state"
 	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/trace"
+	"github.com/KarakuriAgent/clawdroid/pkg/usage"
 	"github.com/KarakuriAgent/clawdroid/pkg/utils"
 )
 
 type AgentLoop struct {
-	bus            *bus.MessageBus
-	provider       providers.LLMProvider
-	workspace      string
-	model          string
-	maxTokens      int     // Maximum tokens for API response
-	temperature    float64 // Temperature for LLM (0 = not sent)
-	contextWindow  int     // Maximum context window size in tokens (for summarization)
-	maxIterations  int
-	sessions       *session.SessionManager
-	state          *state.Manager
-	contextBuilder *ContextBuilder
-	tools          *tools.ToolRegistry
-	userStore      *UserStore
-	running        atomic.Bool
-	migrationOnce  sync.Once
-	summarizing    sync.Map // Tracks which sessions are currently being summarized
-	channelManager *channels.Manager
-	rateLimiter    *rateLimiter
-	mcpManager     *mcp.Manager
-	activeProcs    map[string]*activeProcess
-	procsMu        sync.Mutex
-	mediaDir       string
-	queueMessages  bool
-	showErrors     bool
-	showWarnings   bool
+	bus              *bus.MessageBus
+	provider         providers.LLMProvider
+	workspace        string
+	model            string
+	maxTokens        int      // Maximum tokens for API response
+	temperature      *float64 // Temperature for LLM (nil = not sent)
+	contextWindow    int      // Maximum context window size in tokens (for summarization)
+	maxIterations    int
+	sessions         *session.SessionManager
+	state            *state.Manager
+	contextBuilder   *ContextBuilder
+	tools            *tools.ToolRegistry
+	userStore        *UserStore
+	running          atomic.Bool
+	migrationOnce    sync.Once
+	summarizing      sync.Map // Tracks which sessions are currently being summarized
+	channelManager   *channels.Manager
+	rateLimiter      *rateLimiter
+	mcpManager       *mcp.Manager
+	activeProcs      map[string]*activeProcess
+	procsMu          sync.Mutex
+	mediaDir         string
+	queueMessages    bool
+	steerMessages    bool // Inject follow-ups into the running turn (wins over queueMessages)
+	showErrors       bool
+	showWarnings     bool
+	streaming        bool
+	maxParallelTools int               // Concurrency-safe tool calls run at once
+	models           []string          // Configured models listed by /list models
+	profileModels    map[string]string // Profile name -> model, accepted by /switch model
+	summarizer       llmProfile        // Model and settings for history summarization
+	heartbeat        llmProfile        // Model and settings for heartbeat runs
+	usage            *usage.Tracker
+	budgetProfile    *llmProfile            // Used once the daily soft budget is reached (nil = keep models)
+	personas         *personaRouter         // Routes messages to named personas (nil = none configured)
+	hooks            *tools.Hooks           // Run before and after each tool call
+	outputs          *tools.OutputStore     // Tool outputs collapsed out of history, read back by read_output
+	redactor         *redact.Redactor       // Masks secrets in tool results saved to sessions
+	newExecTool      func() *tools.ExecTool // Creates configured exec tools (returns nil when exec is disabled)
+	processes        *tools.ProcessManager  // Background processes of the process tool (nil = exec disabled)
+	traces           *trace.Store           // Stores execution traces (nil = tracing disabled)
+	traceExporter    *trace.Exporter        // Exports traces over OTLP (nil = not configured)
+}
+
+// llmProfile is the model and sampling settings used for one kind of LLM call.
+type llmProfile struct {
+	Model       string
+	MaxTokens   int
+	Temperature *float64 // nil = not sent
+}
+
+// options returns the provider options for the profile.
+func (p llmProfile) options() map[string]interface{} {
+	opts := map[string]interface{}{
+		"max_tokens": p.MaxTokens,
+	}
+	if p.Temperature != nil {
+		opts["temperature"] = *p.Temperature
+	}
+	return opts
+}
+
+// defaultTemperature returns the agents.defaults temperature to send, where
+// 0 means the provider's default.
+func defaultTemperature(t float64) *float64 {
+	if t == 0 {
+		return nil
+	}
+	return &t
+}
+
+// resolveProfile looks up the profile assigned to a role, inheriting
+// MaxTokens and Temperature from base when the profile leaves them unset.
+func resolveProfile(llm config.LLMConfig, role, name string, base llmProfile) llmProfile {
+	p, ok := llm.Profile(name)
+	if !ok {
+		logger.WarnCF("agent", "Unknown model profile, using default model",
+			map[string]interface{}{
+				"role":    role,
+				"profile": name,
+			})
+	}
+	resolved := llmProfile{Model: p.Model, MaxTokens: p.MaxTokens, Temperature: p.Temperature}
+	if resolved.MaxTokens == 0 {
+		resolved.MaxTokens = base.MaxTokens
+	}
+	if resolved.Temperature == nil {
+		resolved.Temperature = base.Temperature
+	}
+	return resolved
+}
+
+// streamInterval is the minimum delay between partial response updates
+// published while the LLM is streaming.
+const streamInterval = 500 * time.Millisecond
+
+// turnStream tracks the partial replies published during one turn.
+type turnStream struct {
+	sent atomic.Bool
 }
 
 type activeProcess struct {
 	cancel context.CancelFunc
 	done   chan struct{}
+	steer  *steerQueue // Follow-ups for the running turn (steer mode)
 }
 
 // processOptions configures how a message is processed
 type processOptions struct {
-	SessionKey      string            // Session identifier for history/context
-	Channel         string            // Target channel for tool execution
-	ChatID          string            // Target chat ID for tool execution
-	UserMessage     string            // User message content (may include prefix)
-	Media           []string          // Base64 data URLs for images
-	DefaultResponse string            // Response when LLM returns empty
-	EnableSummary   bool              // Whether to trigger summarization
-	SendResponse    bool              // Whether to send response via bus
-	NoHistory       bool              // If true, don't load session history (for heartbeat)
-	InputMode       string            // "voice" or "text"
-	Metadata        map[string]string // Channel metadata (e.g. client_type)
-	ResolvedUser    *User             // Resolved user from user directory (nil if unknown)
-	Locale          string            // Normalized locale code (e.g. "en", "ja")
+	SessionKey      string              // Session identifier for history/context
+	Channel         string              // Target channel for tool execution
+	ChatID          string              // Target chat ID for tool execution
+	UserMessage     string              // User message content (may include prefix)
+	Media           []string            // Base64 data URLs for images
+	DefaultResponse string              // Response when LLM returns empty
+	EnableSummary   bool                // Whether to trigger summarization
+	SendResponse    bool                // Whether to send response via bus
+	NoHistory       bool                // If true, don't load session history (for heartbeat)
+	InputMode       string              // "voice" or "text"
+	Metadata        map[string]string   // Channel metadata (e.g. client_type)
+	ResolvedUser    *User               // Resolved user from user directory (nil if unknown)
+	Locale          string              // Normalized locale code (e.g. "en", "ja")
+	Stream          *turnStream         // Set to publish partial text while the LLM responds
+	Profile         *llmProfile         // Overrides the session's chat model and settings (e.g. for heartbeat)
+	Steer           *steerQueue         // Follow-up messages injected at iteration checkpoints (nil disables)
+	Persona         *persona            // Persona handling the message (nil = default agent)
+	Tools           *tools.ToolRegistry // Tools available to the turn (nil = all)
+}
+
+// promptContext describes the turn's conversation to the system prompt.
+func (o processOptions) promptContext() PromptContext {
+	return PromptContext{
+		Channel:   o.Channel,
+		ChatID:    o.ChatID,
+		InputMode: o.InputMode,
+		Locale:    o.Locale,
+		User:      o.ResolvedUser,
+	}
 }
 
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
-func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus, dataDir string) *tools.ToolRegistry {
+func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus, dataDir string, outputs *tools.OutputStore, newExecTool func() *tools.ExecTool) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
+	registry.SetOutputStore(outputs)
 
 	// File system tools
 	registry.Register(tools.NewReadFileTool(workspace, restrict))
@@ -93,14 +188,17 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	registry.Register(tools.NewListDirTool(workspace, restrict))
 	registry.Register(tools.NewEditFileTool(workspace, restrict))
 	registry.Register(tools.NewAppendFileTool(workspace, restrict))
+	registry.Register(tools.NewGrepTool(workspace, restrict))
+	registry.Register(tools.NewFindFilesTool(workspace, restrict))
 
 	// Copy file tool (allows copying from media dir to workspace)
 	mediaDir := filepath.Join(dataDir, "media")
 	registry.Register(tools.NewCopyFileTool(workspace, mediaDir, restrict))
 
 	// Shell execution (disabled by default for security)
-	if cfg.Tools.Exec.Enabled {
-		registry.Register(tools.NewExecTool(workspace, restrict))
+	if execTool := newExecTool(); execTool != nil {
+		execTool.SetOutputStore(outputs)
+		registry.Register(execTool)
 	}
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
@@ -112,7 +210,9 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	}); searchTool != nil {
 		registry.Register(searchTool)
 	}
-	registry.Register(tools.NewWebFetchTool(50000))
+	fetchTool := tools.NewWebFetchTool(50000)
+	fetchTool.SetOutputStore(outputs)
+	registry.Register(fetchTool)
 
 	// Android device control tool
 	sendCallbackWithType := func(channel, chatID, content, msgType string) error {
@@ -159,19 +259,83 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	mediaDir := filepath.Join(dataDir, "media")
 	_ = os.MkdirAll(mediaDir, 0755)
 
+	// OpenAI token counts use BPE files fetched into the data directory
+	providers.SetBPEDir(filepath.Join(dataDir, "tiktoken"))
+
+	// Oversized and collapsed tool outputs, read back through read_output
+	outputs := tools.NewOutputStore(filepath.Join(dataDir, "outputs"), tools.DefaultMaxOutputs)
+	// Stored outputs are redacted like saved tool results
+	redactor := redact.NewForConfig(cfg)
+	if redactor.ToolResults() {
+		outputs.SetRedact(func(text string) string {
+			redacted, _ := redactor.Redact("", text)
+			return redacted
+		})
+	}
+
+	// Every exec tool (main, subagent, process, cron) gets the same policy and sandbox
+	newExecTool := execToolFactory(cfg, workspace, restrict, newExecSandbox(cfg, workspace))
+
 	// Create tool registry for main agent
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus, dataDir)
+	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus, dataDir, outputs, newExecTool)
+
+	// Resolve the model profile assigned to each role
+	defaults := llmProfile{
+		MaxTokens:   cfg.Agents.Defaults.MaxTokens,
+		Temperature: defaultTemperature(cfg.Agents.Defaults.Temperature),
+	}
+	roles := cfg.LLM.Roles
+	chatProfile := resolveProfile(cfg.LLM, "chat", roles.Chat, defaults)
+	summarizerProfile := resolveProfile(cfg.LLM, "summarizer", roles.Summarizer, llmProfile{MaxTokens: 1024})
+	subagentProfile := resolveProfile(cfg.LLM, "subagent", roles.Subagent, llmProfile{MaxTokens: 4096})
+	heartbeatProfile := resolveProfile(cfg.LLM, "heartbeat", roles.Heartbeat, chatProfile)
+
+	profileModels := make(map[string]string, len(cfg.LLM.Profiles))
+	for name, p := range cfg.LLM.Profiles {
+		if p.Model != "" {
+			profileModels[name] = p.Model
+		}
+	}
+
+	var budgetProfile *llmProfile
+	if cfg.Usage.BudgetProfile != "" {
+		p := resolveProfile(cfg.LLM, "budget", cfg.Usage.BudgetProfile, chatProfile)
+		budgetProfile = &p
+	}
+
+	usageTracker := usage.NewTracker(usage.StorePath(dataDir), cfg.Usage)
 
 	// Create subagent manager with its own tool registry
-	subagentManager := tools.NewSubagentManager(provider, cfg.LLM.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus, dataDir)
+	subagentManager := tools.NewSubagentManager(provider, subagentProfile.Model, workspace, msgBus)
+	subagentManager.SetLLMProfile(subagentProfile.Model, subagentProfile.MaxTokens, subagentProfile.Temperature)
+	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus, dataDir, outputs, newExecTool)
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
+	// Configured hooks run around every tool call; Go hooks can be added later
+	hooks := tools.NewHooksFromConfig(cfg.Tools.Hooks, workspace)
+	toolsRegistry.SetHooks(hooks)
+	subagentTools.SetHooks(hooks)
+
+	// Sensitive tool calls wait for the user's approval
+	if cfg.Tools.Approval.Enabled {
+		approval := newApprovalPolicy(cfg.Tools.Approval, msgBus, dataDir)
+		toolsRegistry.SetApprovalPolicy(approval)
+		subagentTools.SetApprovalPolicy(approval)
+	}
+
 	// Register spawn tool (for main agent only)
 	spawnTool := tools.NewSpawnTool(subagentManager)
 	toolsRegistry.Register(spawnTool)
 
+	// Background processes (for main agent only), guarded and sandboxed like exec
+	var processes *tools.ProcessManager
+	if processExec := newExecTool(); processExec != nil {
+		processes = tools.NewProcessManager(msgBus)
+		processes.SetMaxRuntime(time.Duration(cfg.Tools.Exec.ProcessTimeout) * time.Second)
+		toolsRegistry.Register(tools.NewProcessTool(processes, processExec))
+	}
+
 	// Register exit tool (for main agent only, voice/assistant mode)
 	exitTool := tools.NewExitTool()
 	exitTool.SetSendCallback(func(channel, chatID, content, msgType string) error {
@@ -228,6 +392,9 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		subagentTools.Register(memoryTool)
 	}
 
+	toolsRegistry.Register(tools.NewReadOutputTool(outputs))
+	subagentTools.Register(tools.NewReadOutputTool(outputs))
+
 	skillTool := tools.NewSkillTool(contextBuilder.GetSkillsLoader())
 	toolsRegistry.Register(skillTool)
 	subagentTools.Register(skillTool)
@@ -242,33 +409,72 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		contextBuilder.SetMCPManager(mcpManager)
 	}
 
-	return &AgentLoop{
-		bus:            msgBus,
-		provider:       provider,
-		workspace:      workspace,
-		model:          cfg.LLM.Model,
-		maxTokens:      cfg.Agents.Defaults.MaxTokens,
-		temperature:    cfg.Agents.Defaults.Temperature,
-		contextWindow:  cfg.Agents.Defaults.ContextWindow,
-		maxIterations:  cfg.Agents.Defaults.MaxToolIterations,
-		sessions:       sessionsManager,
-		state:          stateManager,
-		contextBuilder: contextBuilder,
-		tools:          toolsRegistry,
-		userStore:      userStore,
-		summarizing:    sync.Map{},
-		rateLimiter:    newRateLimiter(cfg.RateLimits.MaxToolCallsPerMinute, cfg.RateLimits.MaxRequestsPerMinute),
-		mcpManager:     mcpManager,
-		activeProcs:    make(map[string]*activeProcess),
-		mediaDir:       mediaDir,
-		queueMessages:  cfg.Agents.Defaults.QueueMessages,
-		showErrors:     cfg.Agents.Defaults.ShowErrors,
-		showWarnings:   cfg.Agents.Defaults.ShowWarnings,
+	maxParallelTools := cfg.Agents.Defaults.MaxParallelTools
+	if maxParallelTools <= 0 {
+		maxParallelTools = defaultMaxParallelTools
+	}
+
+	al := &AgentLoop{
+		bus:              msgBus,
+		provider:         provider,
+		workspace:        workspace,
+		model:            chatProfile.Model,
+		maxTokens:        chatProfile.MaxTokens,
+		temperature:      chatProfile.Temperature,
+		contextWindow:    cfg.Agents.Defaults.ContextWindow,
+		maxIterations:    cfg.Agents.Defaults.MaxToolIterations,
+		sessions:         sessionsManager,
+		state:            stateManager,
+		contextBuilder:   contextBuilder,
+		tools:            toolsRegistry,
+		userStore:        userStore,
+		summarizing:      sync.Map{},
+		rateLimiter:      newRateLimiter(cfg.RateLimits.MaxToolCallsPerMinute, cfg.RateLimits.MaxRequestsPerMinute),
+		mcpManager:       mcpManager,
+		activeProcs:      make(map[string]*activeProcess),
+		mediaDir:         mediaDir,
+		queueMessages:    cfg.Agents.Defaults.QueueMessages,
+		steerMessages:    cfg.Agents.Defaults.SteerMessages,
+		showErrors:       cfg.Agents.Defaults.ShowErrors,
+		showWarnings:     cfg.Agents.Defaults.ShowWarnings,
+		streaming:        cfg.Agents.Defaults.Streaming,
+		maxParallelTools: maxParallelTools,
+		models:           cfg.LLM.Models(),
+		profileModels:    profileModels,
+		summarizer:       summarizerProfile,
+		heartbeat:        heartbeatProfile,
+		usage:            usageTracker,
+		budgetProfile:    budgetProfile,
+		personas:         newPersonaRouter(cfg, dataDir, chatProfile),
+		hooks:            hooks,
+		outputs:          outputs,
+		redactor:         redactor,
+		newExecTool:      newExecTool,
+		processes:        processes,
+	}
+	if cfg.Traces.Enabled {
+		al.traces = trace.NewStore(trace.Dir(dataDir), cfg.Traces.MaxTraces)
+		if cfg.Traces.OTLPEndpoint != "" {
+			al.traceExporter = trace.NewExporter(cfg.Traces.OTLPEndpoint)
+		}
 	}
+
+	// Subagent usage counts toward the session that spawned it, and stops
+	// them once the daily hard budget is spent
+	subagentManager.SetResponseCallback(func(channel, chatID, model string, resp *providers.LLMResponse) {
+		al.recordUsage(sessionUsageEntry(fmt.Sprintf("%s:%s", channel, chatID), model), resp)
+	})
+	subagentManager.SetCallCheck(al.checkBudget)
+
+	return al
 }
 
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
+	// Background processes do not outlive the loop
+	if al.processes != nil {
+		defer al.processes.StopAll()
+	}
 
 	for al.running.Load() {
 		select {
@@ -286,8 +492,30 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 			}
 
 			al.procsMu.Lock()
+			if active, exists := al.activeProcs[sessionKey]; exists && msg.Channel == "system" {
+				// System notices (background process exits) run after the
+				// session's turn instead of cancelling or steering it
+				al.procsMu.Unlock()
+				go func(m bus.InboundMessage, done chan struct{}) {
+					select {
+					case <-done:
+						al.bus.PublishInbound(m)
+					case <-ctx.Done():
+					}
+				}(msg, active.done)
+				continue
+			}
 			if active, exists := al.activeProcs[sessionKey]; exists {
-				if al.queueMessages {
+				// Steer mode: hand the follow-up to the running turn.
+				// Commands, and messages arriving after the turn stopped
+				// accepting follow-ups, wait as in queue mode.
+				if al.steerMessages && !isCommand(msg.Content) && active.steer.push(msg) {
+					al.procsMu.Unlock()
+					logger.InfoCF("agent", "Steering running turn",
+						map[string]interface{}{"session_key": sessionKey})
+					continue
+				}
+				if al.queueMessages || al.steerMessages {
 					// Queue mode: wait for completion
 					al.procsMu.Unlock()
 					select {
@@ -312,7 +540,8 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 			procCtx, procCancel := context.WithCancel(ctx)
 			done := make(chan struct{})
-			al.activeProcs[sessionKey] = &activeProcess{cancel: procCancel, done: done}
+			steer := newSteerQueue()
+			al.activeProcs[sessionKey] = &activeProcess{cancel: procCancel, done: done, steer: steer}
 			al.procsMu.Unlock()
 
 			go func(m bus.InboundMessage, sk string) {
@@ -333,24 +562,42 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 					procCancel()
 				}()
 
-				response, err := al.processMessage(procCtx, m)
+				for {
+					var stream *turnStream
+					if al.streaming {
+						stream = &turnStream{}
+					}
+					response, err := al.processInbound(procCtx, m, stream, steer)
 
-				if procCtx.Err() != nil {
-					return
-				}
-				if err != nil {
-					if al.showErrors {
+					if procCtx.Err() != nil {
+						return
+					}
+					if err != nil {
+						// The error replaces a partial reply already on screen,
+						// so one is sent after streaming even with errors hidden
+						if al.showErrors {
+							al.bus.PublishOutbound(bus.OutboundMessage{
+								Channel: m.Channel, ChatID: m.ChatID,
+								Content: fmt.Sprintf("Error: %v", err), Type: "error",
+							})
+						} else if stream != nil && stream.sent.Load() {
+							al.bus.PublishOutbound(bus.OutboundMessage{
+								Channel: m.Channel, ChatID: m.ChatID,
+								Content: i18n.T(messageLocale(m), "agent.stream_failed"), Type: "error",
+							})
+						}
+					} else if response != "" {
 						al.bus.PublishOutbound(bus.OutboundMessage{
-							Channel: m.Channel, ChatID: m.ChatID,
-							Content: fmt.Sprintf("Error: %v", err), Type: "error",
+							Channel: m.Channel, ChatID: m.ChatID, Content: response,
 						})
 					}
-					return
-				}
-				if response != "" {
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: m.Channel, ChatID: m.ChatID, Content: response,
-					})
+
+					// Follow-ups that missed the last checkpoint run as the next turn
+					next, ok := steer.next()
+					if !ok {
+						return
+					}
+					m = next
 				}
 			}(msg, sessionKey)
 		}
@@ -364,12 +611,26 @@ func (al *AgentLoop) Stop() {
 	if al.mcpManager != nil {
 		al.mcpManager.Stop()
 	}
+	if al.processes != nil {
+		al.processes.StopAll()
+	}
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// NewExecTool returns an exec tool with the configured policy and sandbox,
+// or nil when exec is disabled, for tools created outside the loop (cron).
+func (al *AgentLoop) NewExecTool() *tools.ExecTool {
+	return al.newExecTool()
+}
+
+// ToolHooks returns the hooks run around tool calls, for registering Go hooks.
+func (al *AgentLoop) ToolHooks() *tools.Hooks {
+	return al.hooks
+}
+
 func (al *AgentLoop) SetChannelManager(cm *channels.Manager) {
 	al.channelManager = cm
 
@@ -422,6 +683,9 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 // ProcessHeartbeat processes a heartbeat request without session history.
 // Each heartbeat is independent and doesn't accumulate context.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
+	if err := al.checkBudget(); err != nil {
+		return "", err
+	}
 	return al.runAgentLoop(ctx, processOptions{
 		SessionKey:      "heartbeat",
 		Channel:         channel,
@@ -431,10 +695,30 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 		EnableSummary:   false,
 		SendResponse:    false,
 		NoHistory:       true, // Don't load session history for heartbeat
+		Profile:         &al.heartbeat,
 	})
 }
 
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	return al.processInbound(ctx, msg, nil, nil)
+}
+
+// processInbound processes an inbound message. When stream is set, partial
+// text is published to the channel while the LLM responds; callers enabling
+// it must deliver the final response themselves (as Run does). Messages pushed
+// to steer are injected into the turn at its next iteration checkpoint.
+func (al *AgentLoop) processInbound(ctx context.Context, msg bus.InboundMessage, stream *turnStream, steer *steerQueue) (response string, err error) {
+	ctx, t := al.startTrace(ctx, "process_message", map[string]interface{}{
+		"channel":     msg.Channel,
+		"chat_id":     msg.ChatID,
+		"sender_id":   msg.SenderID,
+		"session_key": msg.SessionKey,
+	})
+	defer func() {
+		t.Root().SetAttr("response_chars", len(response))
+		al.finishTrace(t, err)
+	}()
+
 	// Add message preview to log (show full content for error messages)
 	var logContent string
 	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
@@ -457,15 +741,10 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 
 	// Extract input_mode and locale from metadata
 	inputMode := "text"
-	locale := "en"
-	if msg.Metadata != nil {
-		if mode, ok := msg.Metadata["input_mode"]; ok && mode != "" {
-			inputMode = mode
-		}
-		if l, ok := msg.Metadata["locale"]; ok && l != "" {
-			locale = i18n.NormalizeLocale(l)
-		}
+	if mode, ok := msg.Metadata["input_mode"]; ok && mode != "" {
+		inputMode = mode
 	}
+	locale := messageLocale(msg)
 
 	// Check request rate limit
 	if err := al.rateLimiter.checkRequest(); err != nil {
@@ -482,6 +761,16 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		return response, nil
 	}
 
+	// Refuse LLM work once the daily hard budget is spent
+	if al.overHardBudget() {
+		logger.WarnCF("agent", "Daily hard budget reached, refusing message",
+			map[string]interface{}{
+				"channel":   msg.Channel,
+				"sender_id": msg.SenderID,
+			})
+		return i18n.T(locale, "agent.budget_exceeded"), nil
+	}
+
 	// Send migration notice once on first message
 	al.migrationOnce.Do(func() {
 		if al.userStore != nil && al.userStore.NeedsMigration() {
@@ -493,19 +782,14 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		}
 	})
 
-	// Resolve sender name from user directory
-	userMessage := msg.Content
-	var resolvedUser *User
-	if al.userStore != nil {
-		resolvedUser = al.userStore.ResolveByChannelID(msg.Channel, msg.SenderID)
-		if resolvedUser != nil {
-			userMessage = fmt.Sprintf("[%s]: %s", resolvedUser.Name, msg.Content)
-		} else if msg.Channel != "websocket" {
-			// Unknown user: prefix with channel:senderID
-			userMessage = fmt.Sprintf("[%s:%s]: %s", msg.Channel, msg.SenderID, msg.Content)
-		}
-		// WebSocket with no linked user: no prefix
+	userMessage, resolvedUser := al.senderMessage(msg)
+
+	// Approval policies apply per user, and prompts use the sender's language
+	requester := tools.Requester{IDs: []string{msg.SenderID}, Locale: locale}
+	if resolvedUser != nil {
+		requester.IDs = append(requester.IDs, resolvedUser.ID)
 	}
+	ctx = tools.WithRequester(ctx, requester)
 
 	// Process as user message
 	return al.runAgentLoop(ctx, processOptions{
@@ -521,9 +805,39 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		Metadata:        msg.Metadata,
 		ResolvedUser:    resolvedUser,
 		Locale:          locale,
+		Stream:          stream,
+		Steer:           steer,
+		Persona:         al.personas.resolve(msg.Channel, msg.ChatID, msg.SenderID, resolvedUser),
 	})
 }
 
+// messageLocale returns the normalized locale from the message metadata,
+// defaulting to English.
+func messageLocale(msg bus.InboundMessage) string {
+	if l, ok := msg.Metadata["locale"]; ok && l != "" {
+		return i18n.NormalizeLocale(l)
+	}
+	return "en"
+}
+
+// senderMessage prefixes the message content with the sender's name from the
+// user directory and returns the resolved user (nil if unknown).
+func (al *AgentLoop) senderMessage(msg bus.InboundMessage) (string, *User) {
+	if al.userStore == nil {
+		return msg.Content, nil
+	}
+	resolvedUser := al.userStore.ResolveByChannelID(msg.Channel, msg.SenderID)
+	if resolvedUser != nil {
+		return fmt.Sprintf("[%s]: %s", resolvedUser.Name, msg.Content), resolvedUser
+	}
+	if msg.Channel != "websocket" {
+		// Unknown user: prefix with channel:senderID
+		return fmt.Sprintf("[%s:%s]: %s", msg.Channel, msg.SenderID, msg.Content), nil
+	}
+	// WebSocket with no linked user: no prefix
+	return msg.Content, nil
+}
+
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Verify this is a system message
 	if msg.Channel != "system" {
@@ -545,6 +859,11 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		originChannel = "cli"
 	}
 
+	// Background process exits are handed to the agent in the origin session
+	if strings.HasPrefix(msg.SenderID, "process:") {
+		return al.processExitNotice(ctx, msg, originChannel)
+	}
+
 	// Extract subagent result from message content
 	// Format: "Task 'label' completed.\n\nResult:\n<actual content>"
 	content := msg.Content
@@ -576,9 +895,62 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 	return "", nil
 }
 
+// processExitNotice runs a turn in the session that started a background
+// process, so the agent can follow up on its exit (report a finished build,
+// restart a crashed server) or stay silent.
+func (al *AgentLoop) processExitNotice(ctx context.Context, msg bus.InboundMessage, originChannel string) (string, error) {
+	if constants.IsInternalChannel(originChannel) {
+		logger.InfoCF("agent", "Background process exited (internal channel)",
+			map[string]interface{}{
+				"sender_id": msg.SenderID,
+				"channel":   originChannel,
+			})
+		return "", nil
+	}
+
+	sessionKey := msg.SessionKey
+	if sessionKey == "" {
+		sessionKey = msg.ChatID
+	}
+	originChatID := strings.TrimPrefix(msg.ChatID, originChannel+":")
+
+	// The turn runs as the persona that handled the starting sender, with
+	// its tools, prompt and model
+	senderID := msg.Metadata["origin_sender"]
+	var user *User
+	if al.userStore != nil && senderID != "" {
+		user = al.userStore.ResolveByChannelID(originChannel, senderID)
+	}
+	response, err := al.runAgentLoop(ctx, processOptions{
+		SessionKey: sessionKey,
+		Channel:    originChannel,
+		ChatID:     originChatID,
+		UserMessage: fmt.Sprintf("[System: %s\nTell the user if the result matters to them; otherwise reply %s.]",
+			msg.Content, SilentReplyToken),
+		EnableSummary: true,
+		SendResponse:  false,
+		ResolvedUser:  user,
+		Persona:       al.personas.resolve(originChannel, originChatID, senderID, user),
+	})
+	if err != nil {
+		return "", err
+	}
+	if response != "" {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: originChannel,
+			ChatID:  originChatID,
+			Content: response,
+		})
+	}
+	return "", nil
+}
+
 // runAgentLoop is the core message processing logic.
 // It handles context building, LLM calls, tool execution, and response handling.
 func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (string, error) {
+	// Session-scoped tools (read_output) find their data through ctx
+	ctx = tools.WithSession(ctx, opts.SessionKey)
+
 	// 0. Record last channel for heartbeat notifications (skip internal channels)
 	if opts.Channel != "" && opts.ChatID != "" {
 		// Don't record internal channels (cli, system, subagent)
@@ -612,6 +984,15 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 
 	// 1. Update tool contexts
 	al.updateToolContexts(opts.Channel, opts.ChatID, opts.Metadata)
+	if opts.Persona != nil {
+		opts.Tools = al.personaTools(opts.Persona)
+		ctx = tools.WithAllowedTools(ctx, opts.Persona.tools)
+		logger.DebugCF("agent", "Routed to persona",
+			map[string]interface{}{
+				"session_key": opts.SessionKey,
+				"persona":     opts.Persona.name,
+			})
+	}
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
@@ -620,30 +1001,16 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 		history = al.sessions.GetHistory(opts.SessionKey)
 		summary = al.sessions.GetSummary(opts.SessionKey)
 	}
-	messages := al.contextBuilder.BuildMessages(
+	messages := al.personaContext(opts).BuildMessages(
 		history,
 		summary,
 		opts.UserMessage,
 		opts.Media,
-		opts.Channel,
-		opts.ChatID,
-		opts.InputMode,
-		opts.ResolvedUser,
+		opts.promptContext(),
 	)
 
 	// 3. Save user message to session (with media if present)
-	userContent := opts.UserMessage
-	if len(opts.Media) > 0 {
-		paths := PersistMedia(opts.Media, al.mediaDir)
-		for _, p := range paths {
-			userContent += fmt.Sprintf("\n[Image: %s]", p)
-		}
-	}
-	al.sessions.AddFullMessage(opts.SessionKey, providers.Message{
-		Role:    "user",
-		Content: userContent,
-		Media:   opts.Media,
-	})
+	al.saveUserMessage(opts.SessionKey, opts.UserMessage, opts.Media)
 
 	// 4. Emit thinking status
 	thinkingLabel := i18n.T(locale, "status.thinking")
@@ -744,18 +1111,53 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	return finalContent, nil
 }
 
+// saveUserMessage adds a user message to the session, noting the persisted
+// path of each attached image.
+func (al *AgentLoop) saveUserMessage(sessionKey, content string, media []string) {
+	if len(media) > 0 {
+		paths := PersistMedia(media, al.mediaDir)
+		for _, p := range paths {
+			content += fmt.Sprintf("\n[Image: %s]", p)
+		}
+	}
+	al.sessions.AddFullMessage(sessionKey, providers.Message{
+		Role:    "user",
+		Content: content,
+		Media:   media,
+	})
+}
+
 // runLLMIteration executes the LLM call loop with tool handling.
 // Returns the final content, iteration count, and any error.
 func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions, currentStatus *atomic.Value) (string, int, error) {
 	iteration := 0
 	var finalContent string
 
+	profile := llmProfile{
+		Model:       al.sessionModel(opts.SessionKey),
+		MaxTokens:   al.maxTokens,
+		Temperature: al.temperature,
+	}
+	if p := opts.Persona; p != nil && p.profile != nil && al.sessions.GetModel(opts.SessionKey) == "" {
+		// A model chosen with /switch still wins over the persona's
+		profile = *p.profile
+	}
+	if opts.Profile != nil {
+		profile = *opts.Profile
+	}
+	profile = al.budgetProfileFor(profile)
+	model := profile.Model
+
 	// Use locale from opts (set by processMessage), default to "en"
 	locale := opts.Locale
 	if locale == "" {
 		locale = "en"
 	}
 
+	loops := newLoopDetector()
+	answered := false // The model gave a final answer
+	wrapUpNote := finalAnswerLimitNote
+
 	for iteration < al.maxIterations {
 		iteration++
 
@@ -766,6 +1168,33 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		default:
 		}
 
+		// A running turn stops once the daily hard budget is spent
+		if al.overHardBudget() {
+			logger.WarnCF("agent", "Daily hard budget reached, stopping turn",
+				map[string]interface{}{
+					"session_key": opts.SessionKey,
+					"iteration":   iteration,
+				})
+			return i18n.T(locale, "agent.budget_exceeded"), iteration, nil
+		}
+
+		// Steer mode: follow-ups sent since the last checkpoint join the
+		// conversation after the tool results gathered so far
+		for _, m := range opts.Steer.take() {
+			content, _ := al.senderMessage(m)
+			logger.InfoCF("agent", "Injecting follow-up into running turn",
+				map[string]interface{}{
+					"session_key": opts.SessionKey,
+					"iteration":   iteration,
+				})
+			messages = append(messages, providers.Message{
+				Role:    "user",
+				Content: content,
+				Media:   m.Media,
+			})
+			al.saveUserMessage(opts.SessionKey, content, m.Media)
+		}
+
 		logger.DebugCF("agent", "LLM iteration",
 			map[string]interface{}{
 				"iteration": iteration,
@@ -773,17 +1202,17 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			})
 
 		// Build tool definitions
-		providerToolDefs := al.tools.ToProviderDefs()
+		providerToolDefs := al.turnTools(opts).ToProviderDefs()
 
 		// Log LLM request details
 		logger.DebugCF("agent", "LLM request",
 			map[string]interface{}{
 				"iteration":         iteration,
-				"model":             al.model,
+				"model":             model,
 				"messages_count":    len(messages),
 				"tools_count":       len(providerToolDefs),
-				"max_tokens":        al.maxTokens,
-				"temperature":       al.temperature,
+				"max_tokens":        profile.MaxTokens,
+				"temperature":       profile.options()["temperature"],
 				"system_prompt_len": len(messages[0].Content),
 			})
 
@@ -795,19 +1224,34 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				"tools_json":    formatToolsForLog(providerToolDefs),
 			})
 
-		var response *providers.LLMResponse
-		var err error
-
 		// Retry loop for context/token errors
 		maxRetries := 2
-		for retry := 0; retry <= maxRetries; retry++ {
-			llmOpts := map[string]interface{}{
-				"max_tokens": al.maxTokens,
+
+		// Pre-flight: trim history before sending a request that cannot fit
+		// the window with room left for the reply. Stops once there is
+		// nothing left to compress.
+		budget := al.contextWindow - profile.MaxTokens
+		for budget > 0 && !opts.NoHistory {
+			tokens := al.estimateTokens(model, messages, providerToolDefs)
+			if tokens <= budget {
+				break
 			}
-			if al.temperature > 0 {
-				llmOpts["temperature"] = al.temperature
+			logger.WarnCF("agent", "Request exceeds context window, compressing before sending",
+				map[string]interface{}{
+					"tokens": tokens,
+					"budget": budget,
+				})
+			if !al.compress(ctx, opts.SessionKey, "preflight") {
+				break
 			}
-			response, err = al.provider.Chat(ctx, messages, providerToolDefs, al.model, llmOpts)
+			messages = al.rebuildMessages(opts)
+		}
+
+		var response *providers.LLMResponse
+		var err error
+		for retry := 0; retry <= maxRetries; retry++ {
+			llmOpts := profile.options()
+			response, err = al.chatLLM(ctx, model, messages, providerToolDefs, llmOpts, opts)
 
 			if err == nil {
 				break // Success
@@ -823,7 +1267,8 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			}
 
 			// Check for context window errors (provider specific, but usually contain "token" or "invalid")
-			isContextError := strings.Contains(errMsg, "token") ||
+			isContextError := providers.ClassifyError(err) == providers.ErrorClassContextLength ||
+				strings.Contains(errMsg, "token") ||
 				strings.Contains(errMsg, "context") ||
 				strings.Contains(errMsg, "invalidparameter") ||
 				strings.Contains(errMsg, "length")
@@ -844,27 +1289,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 					})
 				}
 
-				// Force compression
-				al.forceCompression(opts.SessionKey)
-
-				// Rebuild messages with compressed history
-				// Note: We need to reload history from session manager because forceCompression changed it
-				newHistory := al.sessions.GetHistory(opts.SessionKey)
-				newSummary := al.sessions.GetSummary(opts.SessionKey)
-
-				// Rebuild from Session History ONLY.
-				// Pass empty string as "currentMessage" to BuildMessages
-				// because the "current message" is already saved in history (step 3 of runAgentLoop).
-				messages = al.contextBuilder.BuildMessages(
-					newHistory,
-					newSummary,
-					"", // Empty because history already contains the relevant messages
-					nil,
-					opts.Channel,
-					opts.ChatID,
-					opts.InputMode,
-					opts.ResolvedUser,
-				)
+				// Force compression and rebuild messages with compressed history
+				al.compress(ctx, opts.SessionKey, "context_error")
+				messages = al.rebuildMessages(opts)
 
 				continue
 			}
@@ -882,9 +1309,21 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
+		al.recordUsage(usageEntry(opts, model), response)
+
+		if response.Model != "" && response.Model != model {
+			logger.InfoCF("agent", "Response served by fallback model",
+				map[string]interface{}{
+					"model":     response.Model,
+					"primary":   model,
+					"iteration": iteration,
+				})
+		}
+
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
+			answered = true
 			logger.InfoCF("agent", "LLM response without tool calls (direct answer)",
 				map[string]interface{}{
 					"iteration":     iteration,
@@ -926,114 +1365,139 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		// Save assistant message with tool calls to session
 		al.sessions.AddFullMessage(opts.SessionKey, assistantMsg)
 
-		// Execute tool calls
-		for _, tc := range response.ToolCalls {
-			// Check tool call rate limit
-			if err := al.rateLimiter.checkToolCall(); err != nil {
-				logger.WarnCF("agent", "Tool call rate limited",
-					map[string]interface{}{
-						"tool":      tc.Name,
-						"iteration": iteration,
-					})
-				toolResultMsg := providers.Message{
-					Role:       "tool",
-					Content:    i18n.Tf(locale, "agent.rate_limited_tool", err),
-					ToolCallID: tc.ID,
-				}
+		// Execute tool calls; runs of concurrency-safe calls execute together
+		var executed []providers.ToolCall
+		var resultTexts, images []string
+		for _, batch := range al.toolBatches(response.ToolCalls) {
+			results := al.runToolBatch(ctx, batch, opts, locale, iteration, currentStatus)
+			for i, tc := range batch {
+				toolResultMsg := al.toolResultMessage(tc, results[i], opts)
 				messages = append(messages, toolResultMsg)
-				al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
-				continue
+				executed = append(executed, tc)
+				resultTexts = append(resultTexts, toolResultMsg.Content)
+				images = append(images, results[i].Media...)
+
+				// Save tool result message to session
+				al.sessions.AddFullMessage(opts.SessionKey, al.redactToolResult(toolResultMsg, opts))
 			}
 
-			// Log tool call with arguments preview
-			argsJSON, _ := json.Marshal(tc.Arguments)
-			argsPreview := utils.Truncate(string(argsJSON), 200)
-			logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+			// Cancellation checkpoint after each batch
+			select {
+			case <-ctx.Done():
+				return finalContent, iteration, ctx.Err()
+			default:
+			}
+		}
+
+		// Tool messages are text-only for most providers, so images from
+		// tool results follow them in a user message. History keeps only
+		// the saved paths noted in the tool results.
+		if len(images) > 0 {
+			messages = append(messages, providers.Message{Role: "user", Content: toolImagesNote, Media: images})
+		}
+
+		// Break tool-call loops: warn the model first, then withdraw tools
+		if note := loops.observe(executed, resultTexts); note != "" {
+			logger.WarnCF("agent", "Tool-call loop detected",
 				map[string]interface{}{
-					"tool":      tc.Name,
+					"tools":     toolNames,
 					"iteration": iteration,
+					"exhausted": loops.exhausted(),
 				})
-
-			// Create async callback for tools that implement AsyncTool
-			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
-			// Instead, they notify the agent via PublishInbound, and the agent decides
-			// whether to forward the result to the user (in processSystemMessage).
-			asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
-				// Log the async completion but don't send directly to user
-				// The agent will handle user notification via processSystemMessage
-				if !result.Silent && result.ForUser != "" {
-					logger.InfoCF("agent", "Async tool completed, agent will handle notification",
-						map[string]interface{}{
-							"tool":        tc.Name,
-							"content_len": len(result.ForUser),
-						})
-				}
+			if loops.exhausted() {
+				wrapUpNote = finalAnswerLoopNote
+				break
 			}
+			// Saved too, so the history matches what the model saw
+			noteMsg := providers.Message{Role: "user", Content: note}
+			messages = append(messages, noteMsg)
+			al.sessions.AddFullMessage(opts.SessionKey, noteMsg)
+		}
+	}
 
-			// Emit tool use status indicator
-			if !constants.IsInternalChannel(opts.Channel) {
-				if label := statusLabel(tc.Name, tc.Arguments, locale); label != "" {
-					currentStatus.Store(label)
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: opts.Channel,
-						ChatID:  opts.ChatID,
-						Content: label,
-						Type:    "status",
-					})
-				}
-			}
+	// The model was still calling tools: ask for a closing answer so the
+	// user always hears what was done
+	if !answered {
+		finalContent = al.finalAnswer(ctx, messages, al.turnTools(opts).ToProviderDefs(), profile, opts, wrapUpNote, iteration)
+	}
 
-			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+	return finalContent, iteration, nil
+}
 
-			// Send ForUser content to user immediately if not Silent
-			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
-				al.bus.PublishOutbound(bus.OutboundMessage{
-					Channel: opts.Channel,
-					ChatID:  opts.ChatID,
-					Content: toolResult.ForUser,
-				})
-				logger.DebugCF("agent", "Sent tool result to user",
-					map[string]interface{}{
-						"tool":        tc.Name,
-						"content_len": len(toolResult.ForUser),
-					})
-			}
+// finalAnswer makes one last LLM call with tool use disabled, after a turn hit
+// the iteration cap or kept looping. It returns "" when the call fails, so the
+// caller's default response applies.
+func (al *AgentLoop) finalAnswer(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, profile llmProfile, opts processOptions, note string, iteration int) string {
+	logger.InfoCF("agent", "Forcing final answer without tools",
+		map[string]interface{}{
+			"iteration": iteration,
+			"max":       al.maxIterations,
+		})
 
-			// Determine content for LLM based on tool result
-			contentForLLM := toolResult.ForLLM
-			if contentForLLM == "" && toolResult.Err != nil {
-				contentForLLM = toolResult.Err.Error()
-			}
+	messages = append(messages, providers.Message{Role: "user", Content: note})
+	// Tool definitions stay in the request because some providers reject
+	// histories with tool calls otherwise; tool_choice keeps them unused.
+	llmOpts := profile.options()
+	llmOpts["tool_choice"] = "none"
+	response, err := al.chatLLM(ctx, profile.Model, messages, toolDefs, llmOpts, opts)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.WarnCF("agent", "Final answer call failed",
+				map[string]interface{}{"error": err.Error()})
+		}
+		return ""
+	}
+	al.recordUsage(usageEntry(opts, profile.Model), response)
+	return response.Content
+}
 
-			// Persist media files from tool results (e.g. screenshots)
-			if len(toolResult.Media) > 0 {
-				paths := PersistMedia(toolResult.Media, al.mediaDir)
-				for _, p := range paths {
-					contentForLLM += fmt.Sprintf("\n[Image: %s]", p)
-				}
-			}
+// chatLLM calls the provider, publishing partial text as "stream" messages
+// when opts.Stream is set and the provider supports streaming.
+func (al *AgentLoop) chatLLM(ctx context.Context, model string, messages []providers.Message, toolDefs []providers.ToolDefinition, llmOpts map[string]interface{}, opts processOptions) (resp *providers.LLMResponse, err error) {
+	ctx, span := trace.Start(ctx, "llm.chat", map[string]interface{}{
+		"model":    model,
+		"messages": len(messages),
+		"tools":    len(toolDefs),
+	})
+	defer func() { endLLMSpan(span, resp, err) }()
 
-			toolResultMsg := providers.Message{
-				Role:       "tool",
-				Content:    contentForLLM,
-				Media:      toolResult.Media,
-				ToolCallID: tc.ID,
-			}
-			messages = append(messages, toolResultMsg)
+	sp, ok := al.provider.(providers.StreamingProvider)
+	if !ok || opts.Stream == nil || constants.IsInternalChannel(opts.Channel) {
+		return al.provider.Chat(ctx, messages, toolDefs, model, llmOpts)
+	}
 
-			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+	var text strings.Builder
+	var lastSent time.Time
+	return sp.ChatStream(ctx, messages, toolDefs, model, llmOpts, func(delta string) {
+		text.WriteString(delta)
+		partial := text.String()
 
-			// Cancellation checkpoint after each tool execution
-			select {
-			case <-ctx.Done():
-				return finalContent, iteration, ctx.Err()
-			default:
-			}
+		// Hold back output that may still turn out to be the silent reply token
+		if strings.HasPrefix(SilentReplyToken, strings.TrimSpace(partial)) {
+			return
 		}
-	}
+		if time.Since(lastSent) < streamInterval {
+			return
+		}
+		lastSent = time.Now()
 
-	return finalContent, iteration, nil
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: partial,
+			Type:    "stream",
+		})
+		opts.Stream.sent.Store(true)
+	})
+}
+
+// sessionModel returns the model used for a session: its override set with
+// /switch model, or the configured model.
+func (al *AgentLoop) sessionModel(sessionKey string) string {
+	if model := al.sessions.GetModel(sessionKey); model != "" {
+		return model
+	}
+	return al.model
 }
 
 // updateToolContexts updates the context for tools that need channel/chatID info.
@@ -1081,7 +1545,9 @@ func (al *AgentLoop) updateToolContexts(channel, chatID string, metadata map[str
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
 func (al *AgentLoop) maybeSummarize(sessionKey, channel, chatID, locale string) {
 	newHistory := al.sessions.GetHistory(sessionKey)
-	tokenEstimate := al.estimateTokens(newHistory)
+	// The system prompt and tool definitions share the window with history
+	request := append([]providers.Message{{Role: "system", Content: al.contextBuilder.BuildSystemPrompt(PromptContext{Channel: channel, ChatID: chatID, Locale: locale})}}, newHistory...)
+	tokenEstimate := al.estimateTokens(al.sessionModel(sessionKey), request, al.tools.ToProviderDefs())
 	threshold := al.contextWindow * 75 / 100
 
 	if len(newHistory) > 20 || tokenEstimate > threshold {
@@ -1103,86 +1569,89 @@ func (al *AgentLoop) maybeSummarize(sessionKey, channel, chatID, locale string)
 	}
 }
 
-// forceCompression aggressively reduces context when the limit is hit.
-// It drops the oldest 50% of messages (keeping system prompt and last user message).
-func (al *AgentLoop) forceCompression(sessionKey string) {
+// compress runs forceCompression in a span of the turn's trace.
+func (al *AgentLoop) compress(ctx context.Context, sessionKey, reason string) bool {
+	_, span := trace.Start(ctx, "compress", map[string]interface{}{
+		"reason":          reason,
+		"messages_before": len(al.sessions.GetHistory(sessionKey)),
+	})
+	dropped := al.forceCompression(sessionKey)
+	span.SetAttr("messages_after", len(al.sessions.GetHistory(sessionKey)))
+	span.End()
+	return dropped
+}
+
+// forceCompression reduces context when the limit is hit. The conversation
+// is compacted by relevance (see compaction) to half the context window, or to
+// half its current size when the provider rejected a request that should fit.
+// Returns false when there is nothing left to compact.
+func (al *AgentLoop) forceCompression(sessionKey string) bool {
 	history := al.sessions.GetHistory(sessionKey)
 	if len(history) <= 4 {
-		return
+		return false
 	}
 
-	// Keep system prompt (usually [0]) and the very last message (user's trigger)
-	// We want to drop the oldest half of the *conversation*
-	// Assuming [0] is system, [1:] is conversation
-	conversation := history[1 : len(history)-1]
-	if len(conversation) == 0 {
-		return
-	}
-
-	// Helper to find the mid-point of the conversation
-	mid := len(conversation) / 2
-
-	// Adjust mid so we don't split in the middle of a tool_calls/tool group.
-	// Both cases move mid forward to include the entire group in the dropped
-	// portion, which maximises context freed by forceCompression.
-	//
-	// Case 1: mid lands on a "tool" message — advance past all consecutive
-	//         tool messages so the group is dropped together with its
-	//         preceding assistant (already in the dropped half).
-	// Case 2: mid lands on an "assistant" with tool_calls — advance past
-	//         the subsequent tool responses so the whole group is dropped.
-	if mid < len(conversation) && conversation[mid].Role == "tool" {
-		for mid < len(conversation) && conversation[mid].Role == "tool" {
-			mid++
-		}
-	} else if mid < len(conversation) && conversation[mid].Role == "assistant" && len(conversation[mid].ToolCalls) > 0 {
-		mid++
-		for mid < len(conversation) && conversation[mid].Role == "tool" {
-			mid++
+	// Keep the first message (usually the system prompt) and the very last
+	// message (user's trigger); compact what is between. Notes from earlier
+	// compressions are replaced by a new one.
+	var conversation []providers.Message
+	for _, m := range history[1 : len(history)-1] {
+		if m.Role != "user" || !strings.HasPrefix(m.Content, compressionNotePrefix) {
+			conversation = append(conversation, m)
 		}
 	}
-
-	// Clamp: ensure we keep at least something and drop at least something
-	if mid <= 0 {
-		mid = 1
+	c := newCompaction(conversation, providers.NewTokenCounter(al.sessionModel(sessionKey)), al.outputs, sessionKey)
+	target := al.contextWindow / 2
+	if c.total/2 < target {
+		target = c.total / 2
 	}
-	if mid >= len(conversation) {
-		mid = len(conversation) - 1
+	kept := c.run(target)
+	if c.collapsed == 0 && len(c.dropped) == 0 {
+		return false
 	}
 
-	droppedCount := mid
-	// Clean up media files from dropped messages
-	CleanupMediaFiles(conversation[:mid])
-	keptConversation := conversation[mid:]
-
-	newHistory := make([]providers.Message, 0)
+	newHistory := make([]providers.Message, 0, len(kept)+3)
 	newHistory = append(newHistory, history[0]) // System prompt
 
-	// Add a note about compression
-	compressionNote := fmt.Sprintf("[System: Emergency compression dropped %d oldest messages due to context limit]", droppedCount)
-	// If there was an existing summary, we might lose it if it was in the dropped part (which is just messages).
-	// The summary is stored separately in session.Summary, so it persists!
-	// We just need to ensure the user knows there's a gap.
-
-	// Use "user" role for the compression note because some providers reject
-	// "system" messages that appear after the initial system prompt.
+	// Add a note about compression. The summary is stored separately in
+	// session.Summary, so it persists; the note tells the LLM there is a gap.
+	// Use "user" role because some providers reject "system" messages that
+	// appear after the initial system prompt.
 	newHistory = append(newHistory, providers.Message{
-		Role:    "user",
-		Content: compressionNote,
+		Role: "user",
+		Content: fmt.Sprintf("%s collapsed %d tool outputs and dropped %d messages due to context limit]",
+			compressionNotePrefix, c.collapsed, len(c.dropped)),
 	})
-
-	newHistory = append(newHistory, keptConversation...)
+	newHistory = append(newHistory, kept...)
 	newHistory = append(newHistory, history[len(history)-1]) // Last message
 
 	// Update session
 	al.sessions.SetHistory(sessionKey, newHistory)
 	_ = al.sessions.Save(sessionKey)
 
+	// Clean up media files from dropped messages
+	al.dropMedia(c.dropped)
+
 	logger.WarnCF("agent", "Forced compression executed", map[string]interface{}{
-		"session_key":  sessionKey,
-		"dropped_msgs": droppedCount,
-		"new_count":    len(newHistory),
+		"session_key":    sessionKey,
+		"collapsed_msgs": c.collapsed,
+		"dropped_msgs":   len(c.dropped),
+		"new_count":      len(newHistory),
 	})
+	return true
+}
+
+// rebuildMessages rebuilds the request from session history after
+// compression. The current message is already saved in history (step 3 of
+// runAgentLoop), so it is not passed again.
+func (al *AgentLoop) rebuildMessages(opts processOptions) []providers.Message {
+	return al.personaContext(opts).BuildMessages(
+		al.sessions.GetHistory(opts.SessionKey),
+		al.sessions.GetSummary(opts.SessionKey),
+		"", // Empty because history already contains the relevant messages
+		nil,
+		opts.promptContext(),
+	)
 }
 
 // GetStartupInfo returns information about loaded tools and skills for logging.
@@ -1257,6 +1726,13 @@ func formatToolsForLog(tools []providers.ToolDefinition) string {
 func (al *AgentLoop) summarizeSession(sessionKey string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
 	defer cancel()
+	// Runs after the turn that triggered it, so it gets its own trace
+	ctx, t := al.startTrace(ctx, "summarize", map[string]interface{}{"session_key": sessionKey})
+	defer al.finishTrace(t, nil)
+
+	if al.overHardBudget() {
+		return
+	}
 
 	history := al.sessions.GetHistory(sessionKey)
 	summary := al.sessions.GetSummary(sessionKey)
@@ -1279,7 +1755,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 			continue
 		}
 		// Estimate tokens for this message
-		msgTokens := len(m.Content) / 2 // Use safer estimate here too (2.5 -> 2 for integer division safety)
+		msgTokens := al.estimateTokens(al.summarizer.Model, []providers.Message{m}, nil)
 		if msgTokens > maxMessageTokens {
 			omitted = true
 			continue
@@ -1299,21 +1775,19 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 		part1 := validMessages[:mid]
 		part2 := validMessages[mid:]
 
-		s1, _ := al.summarizeBatch(ctx, part1, "")
-		s2, _ := al.summarizeBatch(ctx, part2, "")
+		s1, _ := al.summarizeBatch(ctx, sessionKey, part1, "")
+		s2, _ := al.summarizeBatch(ctx, sessionKey, part2, "")
 
 		// Merge them
 		mergePrompt := fmt.Sprintf("Merge these two conversation summaries into one cohesive summary:\n\n1: %s\n\n2: %s", s1, s2)
-		resp, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: mergePrompt}}, nil, al.model, map[string]interface{}{
-			"max_tokens": 1024,
-		})
+		resp, err := al.chatSummarizer(ctx, sessionKey, mergePrompt)
 		if err == nil {
 			finalSummary = resp.Content
 		} else {
 			finalSummary = s1 + " " + s2
 		}
 	} else {
-		finalSummary, _ = al.summarizeBatch(ctx, validMessages, summary)
+		finalSummary, _ = al.summarizeBatch(ctx, sessionKey, validMessages, summary)
 	}
 
 	if omitted && finalSummary != "" {
@@ -1321,16 +1795,16 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 	}
 
 	if finalSummary != "" {
-		// Clean up media files from messages being summarized
-		CleanupMediaFiles(toSummarize)
 		al.sessions.SetSummary(sessionKey, finalSummary)
 		al.sessions.TruncateHistory(sessionKey, 4)
 		_ = al.sessions.Save(sessionKey)
+		// Clean up media files from messages being summarized
+		al.dropMedia(toSummarize)
 	}
 }
 
 // summarizeBatch summarizes a batch of messages.
-func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Message, existingSummary string) (string, error) {
+func (al *AgentLoop) summarizeBatch(ctx context.Context, sessionKey string, batch []providers.Message, existingSummary string) (string, error) {
 	prompt := "Provide a concise summary of this conversation segment, preserving core context and key points.\n"
 	if existingSummary != "" {
 		prompt += "Existing context: " + existingSummary + "\n"
@@ -1340,25 +1814,37 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 		prompt += fmt.Sprintf("%s: %s\n", m.Role, m.Content)
 	}
 
-	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
-		"max_tokens": 1024,
-	})
+	response, err := al.chatSummarizer(ctx, sessionKey, prompt)
 	if err != nil {
 		return "", err
 	}
 	return response.Content, nil
 }
 
-// estimateTokens estimates the number of tokens in a message list.
-// Uses a safe heuristic of 2.5 characters per token to account for CJK and other
-// overheads better than the previous 3 chars/token.
-func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	totalChars := 0
-	for _, m := range messages {
-		totalChars += utf8.RuneCountInString(m.Content)
+// chatSummarizer sends a single prompt to the summarizer model and records
+// its usage.
+func (al *AgentLoop) chatSummarizer(ctx context.Context, sessionKey, prompt string) (resp *providers.LLMResponse, err error) {
+	ctx, span := trace.Start(ctx, "llm.summarize", map[string]interface{}{
+		"model":        al.summarizer.Model,
+		"prompt_chars": len(prompt),
+	})
+	defer func() { endLLMSpan(span, resp, err) }()
+
+	if err := al.checkBudget(); err != nil {
+		return nil, err
 	}
-	// 2.5 chars per token = totalChars * 2 / 5
-	return totalChars * 2 / 5
+	resp, err = al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.summarizer.Model, al.summarizer.options())
+	if err != nil {
+		return nil, err
+	}
+	al.recordUsage(sessionUsageEntry(sessionKey, al.summarizer.Model), resp)
+	return resp, nil
+}
+
+// estimateTokens counts the tokens of a request to model with the counter of
+// its provider family, including tool calls, images and tool definitions.
+func (al *AgentLoop) estimateTokens(model string, messages []providers.Message, toolDefs []providers.ToolDefinition) int {
+	return providers.CountMessages(providers.NewTokenCounter(model), messages, toolDefs)
 }
 
 func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage, locale string) (string, bool) {
@@ -1375,6 +1861,11 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 	cmd := parts[0]
 	args := parts[1:]
 
+	sessionKey := msg.SessionKey
+	if sessionKey == "" {
+		sessionKey = fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID)
+	}
+
 	switch cmd {
 	case "/show":
 		if len(args) < 1 {
@@ -1382,7 +1873,7 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 		}
 		switch args[0] {
 		case "model":
-			return i18n.Tf(locale, "agent.cmd.show.model", al.model), true
+			return i18n.Tf(locale, "agent.cmd.show.model", al.sessionModel(sessionKey)), true
 		case "channel":
 			return i18n.Tf(locale, "agent.cmd.show.channel", msg.Channel), true
 		default:
@@ -1395,7 +1886,7 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 		}
 		switch args[0] {
 		case "models":
-			return i18n.T(locale, "agent.cmd.list.models"), true
+			return al.listModels(sessionKey, locale), true
 		case "channels":
 			if al.channelManager == nil {
 				return i18n.T(locale, "agent.cmd.channel_mgr_error"), true
@@ -1409,6 +1900,28 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 			return i18n.Tf(locale, "agent.cmd.list.unknown", args[0]), true
 		}
 
+	case "/usage":
+		var user *User
+		if al.userStore != nil {
+			user = al.userStore.ResolveByChannelID(msg.Channel, msg.SenderID)
+		}
+		return al.formatUsage(sessionKey, msg.Channel, user, locale), true
+
+	case "/undo":
+		return al.handleUndo(sessionKey, locale), true
+
+	case "/reset":
+		return al.handleReset(sessionKey, locale), true
+
+	case "/history":
+		return al.formatHistory(sessionKey, args, locale), true
+
+	case "/fork":
+		return al.handleFork(sessionKey, args, locale), true
+
+	case "/checkout":
+		return al.handleCheckout(sessionKey, args, locale), true
+
 	case "/switch":
 		if len(args) < 3 || args[1] != "to" {
 			return i18n.T(locale, "agent.cmd.switch.usage"), true
@@ -1418,8 +1931,19 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 
 		switch target {
 		case "model":
-			oldModel := al.model
-			al.model = value
+			if m, ok := al.profileModels[value]; ok {
+				value = m
+			}
+			if err := providers.ValidateModel(value); err != nil {
+				return i18n.Tf(locale, "agent.cmd.switch.model_invalid", value), true
+			}
+			oldModel := al.sessionModel(sessionKey)
+			override := value
+			if value == al.model {
+				override = ""
+			}
+			al.sessions.SetModel(sessionKey, override)
+			_ = al.sessions.Save(sessionKey)
 			return i18n.Tf(locale, "agent.cmd.switch.model", oldModel, value), true
 		case "channel":
 			if al.channelManager == nil {
@@ -1436,3 +1960,33 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage,
 
 	return "", false
 }
+
+// listModels formats the configured models, marking the one the session uses.
+func (al *AgentLoop) listModels(sessionKey, locale string) string {
+	current := al.sessionModel(sessionKey)
+	models := al.models
+	if !slices.Contains(models, current) {
+		models = append(slices.Clone(models), current)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(locale, "agent.cmd.list.models"))
+	for _, m := range models {
+		if m == current {
+			sb.WriteString("\n* " + m)
+		} else {
+			sb.WriteString("\n- " + m)
+		}
+		var names []string
+		for name, pm := range al.profileModels {
+			if pm == m {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			slices.Sort(names)
+			sb.WriteString(" (" + strings.Join(names, ", ") + ")")
+		}
+	}
+	return sb.String()
+}
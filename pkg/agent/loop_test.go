@@ -2,17 +2,24 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/KarakuriAgent/clawdroid/pkg/bus"
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
 	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/redact"
 	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/trace"
+	"github.com/KarakuriAgent/clawdroid/pkg/usage"
 )
 
 // mockProvider is a simple mock LLM provider for testing
@@ -1198,3 +1205,1213 @@ func TestSanitizeToolMessages_AllToolCallsMissing(t *testing.T) {
 		}
 	}
 }
+
+// streamingMockProvider streams its response as fixed deltas, then fails
+// with err when it is set
+type streamingMockProvider struct {
+	deltas     []string
+	err        error
+	chatCalled bool
+}
+
+func (m *streamingMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.chatCalled = true
+	return &providers.LLMResponse{Content: strings.Join(m.deltas, "")}, nil
+}
+
+func (m *streamingMockProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}, onDelta providers.StreamCallback) (*providers.LLMResponse, error) {
+	for _, d := range m.deltas {
+		onDelta(d)
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &providers.LLMResponse{Content: strings.Join(m.deltas, "")}, nil
+}
+
+func (m *streamingMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// drainStreamMessages collects the content of all queued "stream" outbound messages.
+func drainStreamMessages(msgBus *bus.MessageBus) []string {
+	var partials []string
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		msg, ok := msgBus.SubscribeOutbound(ctx)
+		cancel()
+		if !ok {
+			return partials
+		}
+		if msg.Type == "stream" {
+			partials = append(partials, msg.Content)
+		}
+	}
+}
+
+func newStreamingTestLoop(t *testing.T, provider providers.LLMProvider) (*AgentLoop, *bus.MessageBus) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Model: "test-model",
+		},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+				Streaming:         true,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	return NewAgentLoop(cfg, msgBus, provider), msgBus
+}
+
+func TestStreaming_PublishesPartialText(t *testing.T) {
+	provider := &streamingMockProvider{deltas: []string{"Hello", ", world"}}
+	al, msgBus := newStreamingTestLoop(t, provider)
+
+	msg := bus.InboundMessage{
+		Channel:    "telegram",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "hi",
+		SessionKey: "test-session",
+	}
+	response, err := al.processInbound(context.Background(), msg, &turnStream{}, nil)
+	if err != nil {
+		t.Fatalf("processInbound failed: %v", err)
+	}
+	if response != "Hello, world" {
+		t.Errorf("response = %q, want %q", response, "Hello, world")
+	}
+	if provider.chatCalled {
+		t.Error("Chat should not be called when streaming is available")
+	}
+
+	partials := drainStreamMessages(msgBus)
+	if len(partials) == 0 {
+		t.Fatal("expected at least one stream message")
+	}
+	if partials[0] != "Hello" {
+		t.Errorf("first partial = %q, want %q", partials[0], "Hello")
+	}
+}
+
+func TestStreaming_FailedTurnReplacesPartialText(t *testing.T) {
+	provider := &streamingMockProvider{deltas: []string{"Hello"}, err: errors.New("connection reset")}
+	al, msgBus := newStreamingTestLoop(t, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+	msgBus.PublishInbound(bus.InboundMessage{
+		Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "hi", SessionKey: "test-session",
+	})
+
+	var streamed bool
+	for {
+		waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+		msg, ok := msgBus.SubscribeOutbound(waitCtx)
+		waitCancel()
+		if !ok {
+			t.Fatal("no error message after the partial reply")
+		}
+		switch msg.Type {
+		case "stream":
+			streamed = true
+		case "error":
+			if !streamed {
+				t.Fatal("error sent before the partial reply")
+			}
+			// ShowErrors is off in this config, so the generic notice is sent
+			if msg.Content != i18n.T("en", "agent.stream_failed") {
+				t.Errorf("error content = %q", msg.Content)
+			}
+			return
+		}
+	}
+}
+
+// gatedProvider holds its first call until gate is closed and records the
+// last message of each call
+type gatedProvider struct {
+	mu       sync.Mutex
+	gate     chan struct{}
+	received []string
+}
+
+func (p *gatedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	p.mu.Lock()
+	p.received = append(p.received, messages[len(messages)-1].Content)
+	first := len(p.received) == 1
+	p.mu.Unlock()
+	if first {
+		<-p.gate
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (p *gatedProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func (p *gatedProvider) calls() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.received...)
+}
+
+func TestRun_ProcessExitNoticeWaitsForRunningTurn(t *testing.T) {
+	provider := &gatedProvider{gate: make(chan struct{})}
+	al, msgBus := newStreamingTestLoop(t, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go al.Run(ctx)
+	msgBus.PublishInbound(bus.InboundMessage{
+		Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "build it", SessionKey: "telegram:chat1",
+	})
+	waitFor := func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for len(provider.calls()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("LLM calls = %d, want %d", len(provider.calls()), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return provider.calls()
+	}
+	waitFor(1)
+
+	// The process exits while the user's turn is still running
+	msgBus.PublishInbound(bus.InboundMessage{
+		Channel: "system", SenderID: "process:proc-1", ChatID: "telegram:chat1",
+		Content: "Background process proc-1 (make) exited with code 0.", SessionKey: "telegram:chat1",
+	})
+	time.Sleep(100 * time.Millisecond)
+	if calls := provider.calls(); len(calls) != 1 {
+		t.Fatalf("notice ran during the user's turn: %v", calls)
+	}
+
+	close(provider.gate)
+	waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer waitCancel()
+	for {
+		msg, ok := msgBus.SubscribeOutbound(waitCtx)
+		if !ok {
+			t.Fatal("the user's turn was cancelled by the notice")
+		}
+		if msg.Type == "" && msg.Content == "done" {
+			break
+		}
+	}
+	calls := waitFor(2)
+	if !strings.Contains(calls[1], "proc-1") {
+		t.Errorf("second LLM call = %q, want the exit notice", calls[1])
+	}
+	if history := al.sessions.GetHistory("telegram:chat1"); len(history) < 4 {
+		t.Errorf("session history has %d messages, want both turns", len(history))
+	}
+}
+
+func TestStreaming_SilentReplyIsHeldBack(t *testing.T) {
+	provider := &streamingMockProvider{deltas: []string{"NO_", "REPLY"}}
+	al, msgBus := newStreamingTestLoop(t, provider)
+
+	msg := bus.InboundMessage{
+		Channel:    "telegram",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "hi",
+		SessionKey: "test-session",
+	}
+	response, err := al.processInbound(context.Background(), msg, &turnStream{}, nil)
+	if err != nil {
+		t.Fatalf("processInbound failed: %v", err)
+	}
+	if response != "" {
+		t.Errorf("response = %q, want empty for silent reply", response)
+	}
+	if partials := drainStreamMessages(msgBus); len(partials) != 0 {
+		t.Errorf("silent reply should not be streamed, got %v", partials)
+	}
+}
+
+func TestStreaming_DisabledForDirectProcessing(t *testing.T) {
+	provider := &streamingMockProvider{deltas: []string{"Done"}}
+	al, msgBus := newStreamingTestLoop(t, provider)
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "run job", "cron-1", "telegram", "chat1"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel failed: %v", err)
+	}
+	if !provider.chatCalled {
+		t.Error("Chat should be used when streaming is not requested")
+	}
+	if partials := drainStreamMessages(msgBus); len(partials) != 0 {
+		t.Errorf("expected no stream messages, got %v", partials)
+	}
+}
+
+// modelRecordingProvider records the model requested on each call.
+type modelRecordingProvider struct {
+	models []string
+}
+
+func (m *modelRecordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.models = append(m.models, model)
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (m *modelRecordingProvider) GetDefaultModel() string {
+	return "openai/gpt-test"
+}
+
+func TestSwitchModel_AppliesPerSession(t *testing.T) {
+	provider := &modelRecordingProvider{}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.model = "openai/gpt-test"
+
+	send := func(sessionKey, content string) string {
+		t.Helper()
+		resp, err := al.processMessage(context.Background(), bus.InboundMessage{
+			Channel:    "telegram",
+			SenderID:   "user1",
+			ChatID:     sessionKey,
+			Content:    content,
+			SessionKey: sessionKey,
+		})
+		if err != nil {
+			t.Fatalf("processMessage(%q) failed: %v", content, err)
+		}
+		return resp
+	}
+
+	send("s1", "/switch model to gemini/gemini-test")
+	send("s1", "hello")
+	send("s2", "hello")
+
+	want := []string{"gemini/gemini-test", "openai/gpt-test"}
+	if len(provider.models) != len(want) {
+		t.Fatalf("models = %v, want %v", provider.models, want)
+	}
+	for i := range want {
+		if provider.models[i] != want[i] {
+			t.Errorf("call %d model = %q, want %q", i, provider.models[i], want[i])
+		}
+	}
+
+	if got := send("s1", "/list models"); !strings.Contains(got, "* gemini/gemini-test") {
+		t.Errorf("/list models should mark the session model, got %q", got)
+	}
+	if got := send("s1", "/switch model to not-a-model"); al.sessions.GetModel("s1") != "gemini/gemini-test" {
+		t.Errorf("invalid model should be rejected, got %q", got)
+	}
+
+	// Switching back to the configured model clears the override
+	send("s1", "/switch model to openai/gpt-test")
+	if got := al.sessions.GetModel("s1"); got != "" {
+		t.Errorf("override = %q, want cleared", got)
+	}
+}
+
+func TestModelProfiles_AssignedPerRole(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Model: "anthropic/claude-strong",
+			Profiles: map[string]config.LLMProfileConfig{
+				"cheap": {Model: "gemini/gemini-flash", MaxTokens: 256},
+			},
+			Roles: config.LLMRolesConfig{Summarizer: "cheap", Heartbeat: "cheap"},
+		},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &modelRecordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	if al.model != "anthropic/claude-strong" || al.maxTokens != 4096 {
+		t.Errorf("chat profile = (%q, %d), want the default model", al.model, al.maxTokens)
+	}
+	if al.summarizer.Model != "gemini/gemini-flash" || al.summarizer.MaxTokens != 256 {
+		t.Errorf("summarizer = %+v", al.summarizer)
+	}
+
+	if _, err := al.ProcessHeartbeat(context.Background(), "check", "telegram", "chat1"); err != nil {
+		t.Fatalf("ProcessHeartbeat failed: %v", err)
+	}
+	if _, err := al.summarizeBatch(context.Background(), "s1", []providers.Message{{Role: "user", Content: "hi"}}, ""); err != nil {
+		t.Fatalf("summarizeBatch failed: %v", err)
+	}
+	for i, m := range provider.models {
+		if m != "gemini/gemini-flash" {
+			t.Errorf("call %d model = %q, want the cheap profile", i, m)
+		}
+	}
+}
+
+func TestResolveProfile_ZeroTemperatureIsSent(t *testing.T) {
+	zero := 0.0
+	llm := config.LLMConfig{
+		Model: "anthropic/claude-strong",
+		Profiles: map[string]config.LLMProfileConfig{
+			"exact":   {Model: "openai/gpt-test", Temperature: &zero},
+			"inherit": {Model: "openai/gpt-test"},
+		},
+	}
+	base := llmProfile{MaxTokens: 4096, Temperature: defaultTemperature(0.7)}
+
+	if opts := resolveProfile(llm, "chat", "exact", base).options(); opts["temperature"] != 0.0 {
+		t.Errorf("exact options = %v, want temperature 0", opts)
+	}
+	if opts := resolveProfile(llm, "chat", "inherit", base).options(); opts["temperature"] != 0.7 {
+		t.Errorf("inherit options = %v, want the base temperature", opts)
+	}
+	if opts := resolveProfile(llm, "chat", "inherit", llmProfile{Temperature: defaultTemperature(0)}).options(); opts["temperature"] != nil {
+		t.Errorf("options = %v, want no temperature", opts)
+	}
+}
+
+// usageMockProvider returns a fixed token usage with each response.
+type usageMockProvider struct {
+	calls int
+}
+
+func (m *usageMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	return &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 1_000_000, CompletionTokens: 0},
+	}, nil
+}
+
+func (m *usageMockProvider) GetDefaultModel() string {
+	return "openai/gpt-test"
+}
+
+func TestUsage_RecordedAndHardBudgetRefuses(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		LLM: config.LLMConfig{Model: "openai/gpt-test"},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+			},
+		},
+		Usage: config.UsageConfig{
+			Prices:          map[string]config.ModelPrice{"openai/gpt-test": {Input: 1}},
+			DailyHardBudget: 1,
+		},
+	}
+	provider := &usageMockProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	msg := bus.InboundMessage{
+		Channel:    "telegram",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "hello",
+		SessionKey: "telegram:chat1",
+	}
+	if _, err := al.processMessage(context.Background(), msg); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if got := al.usage.Today().Sessions["telegram:chat1"]; got == nil || got.PromptTokens != 1_000_000 {
+		t.Fatalf("session usage = %+v", got)
+	}
+
+	// The hard budget is now spent: the next message is refused without an LLM call
+	resp, err := al.processMessage(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+	if !strings.Contains(resp, "budget") {
+		t.Errorf("expected budget refusal, got %q", resp)
+	}
+
+	// Commands still work
+	msg.Content = "/usage"
+	resp, _ = al.processMessage(context.Background(), msg)
+	if !strings.Contains(resp, "1000000 in") {
+		t.Errorf("/usage = %q", resp)
+	}
+}
+
+// toolCallingUsageProvider calls mock_custom on its first call; every call
+// uses a million prompt tokens
+type toolCallingUsageProvider struct {
+	calls int
+}
+
+func (m *toolCallingUsageProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	resp := &providers.LLMResponse{
+		Content: "ok",
+		Usage:   &providers.UsageInfo{PromptTokens: 1_000_000},
+	}
+	if m.calls == 1 {
+		resp.ToolCalls = []providers.ToolCall{{ID: "call-1", Name: "mock_custom", Arguments: map[string]interface{}{}}}
+	}
+	return resp, nil
+}
+
+func (m *toolCallingUsageProvider) GetDefaultModel() string {
+	return "openai/gpt-test"
+}
+
+func TestUsage_HardBudgetStopsRunningTurn(t *testing.T) {
+	provider := &toolCallingUsageProvider{}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.usage = usage.NewTracker("", config.UsageConfig{
+		Prices:          map[string]config.ModelPrice{"test-model": {Input: 1}},
+		DailyHardBudget: 1,
+	})
+	al.RegisterTool(&mockCustomTool{})
+
+	resp, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "telegram", SenderID: "user1", ChatID: "chat1", Content: "hello", SessionKey: "telegram:chat1",
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	// The first call spends the budget, so the turn ends after its tool call
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times, want 1", provider.calls)
+	}
+	if resp != i18n.T("en", "agent.budget_exceeded") {
+		t.Errorf("response = %q, want the budget notice", resp)
+	}
+}
+
+func TestUsage_SoftBudgetDowngradesModel(t *testing.T) {
+	al, _ := newStreamingTestLoop(t, &modelRecordingProvider{})
+	al.usage = usage.NewTracker("", config.UsageConfig{
+		Prices:          map[string]config.ModelPrice{"openai/gpt-test": {Input: 1}},
+		DailySoftBudget: 1,
+	})
+	al.budgetProfile = &llmProfile{Model: "openai/gpt-cheap", MaxTokens: 512}
+
+	profile := llmProfile{Model: "openai/gpt-test", MaxTokens: 4096}
+	if got := al.budgetProfileFor(profile); got.Model != "openai/gpt-test" {
+		t.Errorf("under budget: model = %q, want unchanged", got.Model)
+	}
+	_, _ = al.usage.Record(usage.Entry{Model: "openai/gpt-test", PromptTokens: 1_000_000})
+	if got := al.budgetProfileFor(profile); got.Model != "openai/gpt-cheap" {
+		t.Errorf("over soft budget: model = %q, want the budget profile", got.Model)
+	}
+}
+
+// toolThenAnswerProvider calls mock_custom once, then answers.
+type toolThenAnswerProvider struct {
+	calls int
+}
+
+func (m *toolThenAnswerProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{
+				ID:        "call_1",
+				Type:      "function",
+				Name:      "mock_custom",
+				Arguments: map[string]interface{}{},
+				Function:  &providers.FunctionCall{Name: "mock_custom", Arguments: "{}"},
+			}},
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "done after tool"}, nil
+}
+
+func (m *toolThenAnswerProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestCassette_ReplaysConversationWithToolCalls(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "conv.jsonl")
+	msg := bus.InboundMessage{
+		Channel:    "test",
+		SenderID:   "user1",
+		ChatID:     "chat1",
+		Content:    "use the tool",
+		SessionKey: "test-session",
+	}
+
+	run := func(provider providers.LLMProvider) string {
+		t.Helper()
+		al, _ := newStreamingTestLoop(t, provider)
+		al.RegisterTool(&mockCustomTool{})
+		resp, err := al.processMessage(context.Background(), msg)
+		if err != nil {
+			t.Fatalf("processMessage failed: %v", err)
+		}
+		return resp
+	}
+
+	live := &toolThenAnswerProvider{}
+	recorded := run(providers.NewRecordingProvider(live, cassette))
+	if live.calls != 2 {
+		t.Fatalf("live provider called %d times, want 2", live.calls)
+	}
+
+	replay, err := providers.NewReplayProvider(cassette, "test-model")
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	if got := run(replay); got != recorded || got != "done after tool" {
+		t.Errorf("replayed response = %q, recorded %q", got, recorded)
+	}
+}
+
+// slowTool records how many of its calls overlap.
+type slowTool struct {
+	name     string
+	safe     bool
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (s *slowTool) Name() string        { return s.name }
+func (s *slowTool) Description() string { return "slow tool for testing" }
+func (s *slowTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (s *slowTool) ConcurrencySafe() bool { return s.safe }
+
+func (s *slowTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	s.mu.Lock()
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return tools.SilentResult(fmt.Sprintf("%s %v", s.name, args["n"]))
+}
+
+// batchCallProvider requests the given tool calls once, then answers and
+// keeps the messages it was sent.
+type batchCallProvider struct {
+	calls    []providers.ToolCall
+	received []providers.Message
+}
+
+func (m *batchCallProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if m.received == nil {
+		m.received = messages
+		return &providers.LLMResponse{ToolCalls: m.calls}, nil
+	}
+	m.received = messages
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (m *batchCallProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestParallelTools_RunConcurrentlyInOrder(t *testing.T) {
+	call := func(id, name string, n int) providers.ToolCall {
+		return providers.ToolCall{ID: id, Name: name, Arguments: map[string]interface{}{"n": n}}
+	}
+	provider := &batchCallProvider{calls: []providers.ToolCall{
+		call("c1", "fetch", 1),
+		call("c2", "fetch", 2),
+		call("c3", "fetch", 3),
+		call("c4", "write", 4),
+		call("c5", "fetch", 5),
+	}}
+	al, _ := newStreamingTestLoop(t, provider)
+	fetch := &slowTool{name: "fetch", safe: true}
+	write := &slowTool{name: "write"}
+	al.RegisterTool(fetch)
+	al.RegisterTool(write)
+
+	batches := al.toolBatches(provider.calls)
+	if len(batches) != 3 || len(batches[0]) != 3 || len(batches[1]) != 1 || len(batches[2]) != 1 {
+		t.Fatalf("batch sizes = %v, want [3 1 1]", batches)
+	}
+
+	_, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "go", SessionKey: "test-session",
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if fetch.peak != 3 {
+		t.Errorf("fetch peak concurrency = %d, want 3", fetch.peak)
+	}
+
+	var got []string
+	for _, m := range provider.received {
+		if m.Role == "tool" {
+			got = append(got, m.ToolCallID+"="+m.Content)
+		}
+	}
+	want := []string{"c1=fetch 1", "c2=fetch 2", "c3=fetch 3", "c4=write 4", "c5=fetch 5"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("tool results = %v, want %v", got, want)
+	}
+}
+
+// steeringTool pushes a follow-up onto the turn's steer queue while it runs,
+// as if the user sent a correction during tool work.
+type steeringTool struct {
+	steer *steerQueue
+	msg   bus.InboundMessage
+}
+
+func (s *steeringTool) Name() string        { return "work" }
+func (s *steeringTool) Description() string { return "steering tool for testing" }
+func (s *steeringTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+
+func (s *steeringTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	s.steer.push(s.msg)
+	return tools.SilentResult("worked")
+}
+
+func TestSteer_FollowUpInjectedAtNextCheckpoint(t *testing.T) {
+	provider := &batchCallProvider{calls: []providers.ToolCall{
+		{ID: "c1", Name: "work", Arguments: map[string]interface{}{}},
+	}}
+	al, _ := newStreamingTestLoop(t, provider)
+	steer := newSteerQueue()
+	followUp := bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "actually use plan B", SessionKey: "test-session",
+	}
+	al.RegisterTool(&steeringTool{steer: steer, msg: followUp})
+
+	response, err := al.processInbound(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "do plan A", SessionKey: "test-session",
+	}, nil, steer)
+	if err != nil {
+		t.Fatalf("processInbound failed: %v", err)
+	}
+	if response != "done" {
+		t.Errorf("response = %q, want %q", response, "done")
+	}
+
+	// The follow-up comes after the tool result and is kept in the session
+	n := len(provider.received)
+	if n < 2 || provider.received[n-2].Role != "tool" ||
+		provider.received[n-1].Role != "user" || !strings.Contains(provider.received[n-1].Content, "actually use plan B") {
+		t.Fatalf("last messages sent to LLM = %+v, want tool result then follow-up", provider.received[max(0, n-2):])
+	}
+	found := false
+	for _, m := range al.sessions.GetHistory("test-session") {
+		if m.Role == "user" && strings.Contains(m.Content, "actually use plan B") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("follow-up not saved to session history")
+	}
+
+	// Taken follow-ups are not replayed as a new turn, and the queue closes
+	if _, ok := steer.next(); ok {
+		t.Error("steer.next() returned an already injected follow-up")
+	}
+	if steer.push(followUp) {
+		t.Error("push accepted after the queue closed")
+	}
+}
+
+func TestPreflight_TrimsHistoryToFitWindow(t *testing.T) {
+	provider := &batchCallProvider{}
+	al, _ := newStreamingTestLoop(t, provider)
+	sessionKey := "test-session"
+	for i := 0; i < 20; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		al.sessions.AddMessage(sessionKey, role, strings.Repeat("x", 1000))
+	}
+
+	// Leave room for the system prompt, tool definitions and the reply,
+	// plus about a quarter of the seeded history
+	toolDefs := al.tools.ToProviderDefs()
+	base := al.estimateTokens("test-model", al.contextBuilder.BuildMessages(nil, "", "hi", nil, PromptContext{Channel: "test", ChatID: "chat1", InputMode: "text"}), toolDefs)
+	al.contextWindow = base + al.maxTokens + 2000
+
+	_, err := al.runAgentLoop(context.Background(), processOptions{
+		SessionKey:      sessionKey,
+		Channel:         "test",
+		ChatID:          "chat1",
+		UserMessage:     "hi",
+		DefaultResponse: "done",
+	})
+	if err != nil {
+		t.Fatalf("runAgentLoop failed: %v", err)
+	}
+
+	if len(provider.received) >= 22 {
+		t.Fatalf("request kept all %d messages, want history trimmed", len(provider.received))
+	}
+	if got, budget := al.estimateTokens("test-model", provider.received, toolDefs), al.contextWindow-al.maxTokens; got > budget {
+		t.Errorf("request uses %d tokens, want <= %d", got, budget)
+	}
+	if last := provider.received[len(provider.received)-1]; last.Role != "user" || last.Content != "hi" {
+		t.Errorf("last message = %+v, want the current user message", last)
+	}
+}
+
+// toolLoopProvider keeps requesting tool calls until tool use is disabled,
+// then answers with a summary.
+type toolLoopProvider struct {
+	vary     bool // Use new arguments on every call
+	calls    int
+	received []providers.Message
+}
+
+func (m *toolLoopProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	m.received = messages
+	if opts["tool_choice"] == "none" {
+		return &providers.LLMResponse{Content: "summary of work"}, nil
+	}
+	args := map[string]interface{}{"path": "same.txt"}
+	if m.vary {
+		args["n"] = m.calls
+	}
+	return &providers.LLMResponse{ToolCalls: []providers.ToolCall{
+		{ID: fmt.Sprintf("c%d", m.calls), Name: "mock_custom", Arguments: args},
+	}}, nil
+}
+
+func (m *toolLoopProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestToolLoop_WarnsThenForcesFinalAnswer(t *testing.T) {
+	provider := &toolLoopProvider{}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.RegisterTool(&mockCustomTool{})
+
+	response, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "go", SessionKey: "test-session",
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if response != "summary of work" {
+		t.Errorf("response = %q, want forced final answer", response)
+	}
+	// Warned after the third identical call, cut off after the fourth
+	if provider.calls != repeatCallLimit+2 {
+		t.Errorf("LLM calls = %d, want %d", provider.calls, repeatCallLimit+2)
+	}
+
+	var notes []string
+	for _, m := range provider.received {
+		if m.Role == "user" && strings.HasPrefix(m.Content, "[System:") {
+			notes = append(notes, m.Content)
+		}
+	}
+	if len(notes) != 2 || !strings.Contains(notes[0], "identical arguments") || notes[1] != finalAnswerLoopNote {
+		t.Errorf("notes sent to LLM = %q, want repeat warning then final-answer note", notes)
+	}
+
+	// The warning is in the saved history, so a replay matches what the model saw
+	saved := false
+	for _, m := range al.sessions.GetHistory("test-session") {
+		if m.Role == "user" && m.Content == notes[0] {
+			saved = true
+		}
+	}
+	if !saved {
+		t.Error("repeat warning was not saved to the session")
+	}
+}
+
+func TestToolLoop_IterationCapForcesFinalAnswer(t *testing.T) {
+	provider := &toolLoopProvider{vary: true}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.RegisterTool(&mockCustomTool{})
+
+	response, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "go", SessionKey: "test-session",
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if response != "summary of work" {
+		t.Errorf("response = %q, want forced final answer", response)
+	}
+	if provider.calls != al.maxIterations+1 {
+		t.Errorf("LLM calls = %d, want %d", provider.calls, al.maxIterations+1)
+	}
+	if last := provider.received[len(provider.received)-1]; last.Content != finalAnswerLimitNote {
+		t.Errorf("last message = %q, want iteration limit note", last.Content)
+	}
+}
+
+func TestBranchCommands(t *testing.T) {
+	al, _ := newStreamingTestLoop(t, &simpleMockProvider{response: "ok"})
+	send := func(content string) string {
+		t.Helper()
+		response, err := al.processMessage(context.Background(), bus.InboundMessage{
+			Channel: "test", SenderID: "user1", ChatID: "chat1", Content: content, SessionKey: "test-session",
+		})
+		if err != nil {
+			t.Fatalf("processMessage(%q) failed: %v", content, err)
+		}
+		return response
+	}
+
+	send("first question")
+	if got := send("/fork alt"); !strings.Contains(got, "alt") {
+		t.Errorf("/fork response = %q", got)
+	}
+	send("question on alt")
+	if got := send("/history 1"); !strings.Contains(got, "assistant: ok") || !strings.Contains(got, "branch alt") {
+		t.Errorf("/history response = %q", got)
+	}
+	if got := send("/undo"); !strings.Contains(got, "2 messages") {
+		t.Errorf("/undo response = %q", got)
+	}
+	if got := send("/checkout"); !strings.Contains(got, "*alt, main") {
+		t.Errorf("/checkout response = %q", got)
+	}
+	if got := send("/checkout main"); !strings.Contains(got, "main (2 messages)") {
+		t.Errorf("/checkout main response = %q", got)
+	}
+	send("/reset")
+	if got := send("/history"); got != "No messages in this conversation yet" {
+		t.Errorf("/history after reset = %q", got)
+	}
+}
+
+// promptRecordingProvider records the system prompt, tools and model of each call.
+type promptRecordingProvider struct {
+	prompts []string
+	tools   [][]string
+	models  []string
+}
+
+func (m *promptRecordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.prompts = append(m.prompts, messages[0].Content)
+	var names []string
+	for _, td := range tools {
+		names = append(names, td.Function.Name)
+	}
+	m.tools = append(m.tools, names)
+	m.models = append(m.models, model)
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (m *promptRecordingProvider) GetDefaultModel() string {
+	return "openai/gpt-test"
+}
+
+func TestPersonas_RoutedByChannelAndChat(t *testing.T) {
+	tmpDir := t.TempDir()
+	familyDir := filepath.Join(tmpDir, "personas", "family")
+	if err := os.MkdirAll(familyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(familyDir, "SOUL.md"), []byte("You are the family helper."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Model: "anthropic/claude-strong",
+			Profiles: map[string]config.LLMProfileConfig{
+				"cheap": {Model: "gemini/gemini-flash"},
+			},
+		},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+			},
+			Personas: map[string]config.PersonaConfig{
+				"family": {Profile: "cheap", Tools: []string{"read_file", "message"}},
+			},
+			Routes: []config.PersonaRouteConfig{
+				{Persona: "family", Channel: "telegram", ChatID: "family-group"},
+			},
+		},
+	}
+	provider := &promptRecordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	for _, chatID := range []string{"family-group", "work"} {
+		if _, err := al.processMessage(context.Background(), bus.InboundMessage{
+			Channel: "telegram", SenderID: "user1", ChatID: chatID, Content: "hello", SessionKey: "telegram:" + chatID,
+		}); err != nil {
+			t.Fatalf("processMessage(%s) failed: %v", chatID, err)
+		}
+	}
+	if len(provider.prompts) != 2 {
+		t.Fatalf("got %d LLM calls, want 2", len(provider.prompts))
+	}
+
+	if !strings.Contains(provider.prompts[0], "You are the family helper.") {
+		t.Error("family prompt does not include the persona's SOUL.md")
+	}
+	sort.Strings(provider.tools[0])
+	if got := strings.Join(provider.tools[0], ","); got != "message,read_file" {
+		t.Errorf("family tools = %s, want message,read_file", got)
+	}
+	if provider.models[0] != "gemini/gemini-flash" {
+		t.Errorf("family model = %q, want the persona's profile", provider.models[0])
+	}
+
+	if strings.Contains(provider.prompts[1], "You are the family helper.") {
+		t.Error("default agent prompt includes the persona's SOUL.md")
+	}
+	if len(provider.tools[1]) <= 2 {
+		t.Errorf("default agent tools = %v, want all tools", provider.tools[1])
+	}
+	if provider.models[1] != "anthropic/claude-strong" {
+		t.Errorf("default model = %q", provider.models[1])
+	}
+}
+
+func TestPersonas_ProcessExitNoticeUsesPersona(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		LLM: config.LLMConfig{
+			Model: "anthropic/claude-strong",
+			Profiles: map[string]config.LLMProfileConfig{
+				"cheap": {Model: "gemini/gemini-flash"},
+			},
+		},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+			},
+			Personas: map[string]config.PersonaConfig{
+				"family": {Profile: "cheap", Tools: []string{"read_file", "message"}},
+			},
+			Routes: []config.PersonaRouteConfig{
+				{Persona: "family", Channel: "telegram", User: "123"},
+			},
+		},
+	}
+	provider := &promptRecordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	// The notice of a process started by sender 123
+	if _, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "system", SenderID: "process:proc-1", ChatID: "telegram:chat1", SessionKey: "telegram:chat1",
+		Content:  "Background process proc-1 (make) exited with code 0.",
+		Metadata: map[string]string{"origin_sender": "123"},
+	}); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if len(provider.tools) != 1 {
+		t.Fatalf("got %d LLM calls, want 1", len(provider.tools))
+	}
+	sort.Strings(provider.tools[0])
+	if got := strings.Join(provider.tools[0], ","); got != "message,read_file" {
+		t.Errorf("notice tools = %s, want the persona's message,read_file", got)
+	}
+	if provider.models[0] != "gemini/gemini-flash" {
+		t.Errorf("notice model = %q, want the persona's profile", provider.models[0])
+	}
+}
+
+func TestPersonaRouter_MatchesUser(t *testing.T) {
+	cfg := &config.Config{Agents: config.AgentsConfig{
+		Personas: map[string]config.PersonaConfig{"work": {}, "family": {}},
+		Routes: []config.PersonaRouteConfig{
+			{Persona: "missing", Channel: "slack"},
+			{Persona: "family", User: "Alice"},
+			{Persona: "work", Channel: "slack"},
+		},
+	}}
+	r := newPersonaRouter(cfg, t.TempDir(), llmProfile{})
+
+	tests := []struct {
+		channel, senderID string
+		user              *User
+		want              string
+	}{
+		{"slack", "U1", nil, "work"},
+		{"slack", "U1", &User{ID: "u-1", Name: "alice"}, "family"},
+		{"telegram", "123|bob", nil, ""},
+		{"discord", "Alice", nil, "family"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if p := r.resolve(tt.channel, "chat", tt.senderID, tt.user); p != nil {
+			got = p.name
+		}
+		if got != tt.want {
+			t.Errorf("resolve(%s, %s) = %q, want %q", tt.channel, tt.senderID, got, tt.want)
+		}
+	}
+}
+
+func TestTrace_RecordsTurnSpans(t *testing.T) {
+	al, _ := newStreamingTestLoop(t, &toolThenAnswerProvider{})
+	al.RegisterTool(&mockCustomTool{})
+	al.traces = trace.NewStore(t.TempDir(), 10)
+
+	if _, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "use the tool", SessionKey: "test-session",
+	}); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+
+	list := al.traces.List()
+	if len(list) != 1 {
+		t.Fatalf("got %d traces, want 1", len(list))
+	}
+	spans, err := al.traces.Load(list[0].ID)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "process_message,llm.chat,tool.mock_custom,llm.chat" {
+		t.Fatalf("spans = %s", got)
+	}
+	if spans[2].Attributes["tool"] != "mock_custom" || spans[2].ParentID != spans[0].SpanID {
+		t.Errorf("tool span = %+v", spans[2])
+	}
+	if spans[1].Attributes["tool_calls"] != float64(1) {
+		t.Errorf("llm span attributes = %v", spans[1].Attributes)
+	}
+}
+
+func TestRedaction_ToolResultMaskedInSessionOnly(t *testing.T) {
+	provider := &batchCallProvider{calls: []providers.ToolCall{
+		{ID: "c1", Name: "contacts", Arguments: map[string]interface{}{"n": "Ann 090-1234-5678"}},
+	}}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.RegisterTool(&slowTool{name: "contacts"})
+	al.redactor = redact.New(config.RedactionConfig{
+		Enabled:     true,
+		Detectors:   config.FlexibleStringSlice{redact.Phone},
+		ToolResults: true,
+	}, nil)
+
+	_, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "find Ann", SessionKey: "test-session",
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+
+	// The current turn works with the real result
+	if last := provider.received[len(provider.received)-1]; last.Content != "contacts Ann 090-1234-5678" {
+		t.Errorf("LLM saw tool result %q", last.Content)
+	}
+	var saved string
+	for _, m := range al.sessions.GetHistory("test-session") {
+		if m.Role == "tool" {
+			saved = m.Content
+		}
+	}
+	if saved != "contacts Ann [REDACTED:phone]" {
+		t.Errorf("saved tool result = %q", saved)
+	}
+}
+
+// mockImageTool returns an image, like read_file on a PNG.
+type mockImageTool struct{}
+
+func (m *mockImageTool) Name() string {
+	return "mock_image"
+}
+
+func (m *mockImageTool) Description() string {
+	return "Mock tool returning an image"
+}
+
+func (m *mockImageTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{},
+	}
+}
+
+func (m *mockImageTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	result := tools.NewToolResult("Read image a.png")
+	result.Media = []string{"data:image/png;base64,iVBORw0KGgo="}
+	return result
+}
+
+// imageToolProvider calls mock_image once, then answers.
+type imageToolProvider struct {
+	received []providers.Message
+}
+
+func (m *imageToolProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if messages[len(messages)-1].Role == "user" && strings.HasSuffix(messages[len(messages)-1].Content, "look") {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "c1", Name: "mock_image", Arguments: map[string]interface{}{}}}}, nil
+	}
+	m.received = messages
+	return &providers.LLMResponse{Content: "a cat"}, nil
+}
+
+func (m *imageToolProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestToolImages_SentAfterToolResultsNotSaved(t *testing.T) {
+	provider := &imageToolProvider{}
+	al, _ := newStreamingTestLoop(t, provider)
+	al.RegisterTool(&mockImageTool{})
+
+	if _, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel: "test", SenderID: "user1", ChatID: "chat1", Content: "look", SessionKey: "test-session",
+	}); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+
+	n := len(provider.received)
+	if n < 2 {
+		t.Fatalf("received %d messages", n)
+	}
+	toolMsg, imageMsg := provider.received[n-2], provider.received[n-1]
+	if toolMsg.Role != "tool" || len(toolMsg.Media) != 0 || !strings.Contains(toolMsg.Content, "[Image: ") {
+		t.Errorf("tool message = %+v, want text with the saved path", toolMsg)
+	}
+	if imageMsg.Role != "user" || len(imageMsg.Media) != 1 {
+		t.Errorf("image message = %+v, want a user message with the image", imageMsg)
+	}
+
+	for _, m := range al.sessions.GetHistory("test-session") {
+		if m.Content == toolImagesNote || (m.Role == "tool" && len(m.Media) > 0) {
+			t.Errorf("history keeps tool image data: %+v", m)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+)
+
+const (
+	// repeatCallLimit is how many identical calls (same tool, arguments and
+	// result) a turn may make before they count as a loop.
+	repeatCallLimit = 3
+	// maxLoopWarnings is how many loops are answered with a corrective note;
+	// the next one ends tool use and forces a final answer.
+	maxLoopWarnings = 1
+)
+
+// Notes sent to the model. They use the "user" role, like the compression
+// note, because some providers reject mid-conversation system messages.
+const (
+	loopRepeatNote = "[System: You have called %s with identical arguments %d times in this turn and got the same result each time. " +
+		"Do not repeat it. Use the results you already have, try a different approach, or answer the user.]"
+	loopOscillationNote = "[System: Your last %d tool calls repeat the same alternating pattern without progress. " +
+		"Stop cycling. Use the results you already have, try a different approach, or answer the user.]"
+	finalAnswerLimitNote = "[System: The tool-call limit for this turn has been reached. Do not call any more tools. " +
+		"Answer the user now: summarize what you did, what you found, and what is left to do.]"
+	finalAnswerLoopNote = "[System: You kept repeating tool calls after being warned, so tools are no longer available in this turn. " +
+		"Answer the user now: summarize what you did, what you found, and what is left to do.]"
+)
+
+// loopDetector watches a turn's tool calls for loops: the same call made over
+// and over, or iterations cycling through the same few call sets. Calls only
+// match when their results match too, so re-reading a file after editing it
+// is progress, not a loop.
+type loopDetector struct {
+	callCounts map[string]int // Call signature -> times made
+	rounds     []string       // Signature of each iteration's call set
+	loops      int            // Loops detected so far
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{callCounts: make(map[string]int)}
+}
+
+// observe records an iteration's tool calls and their results (in the same
+// order) and returns a corrective note for the model when they complete a
+// loop, or "" otherwise.
+func (d *loopDetector) observe(calls []providers.ToolCall, results []string) string {
+	sigs := make([]string, 0, len(calls))
+	repeated, repeats := "", 0
+	for i, tc := range calls {
+		result := ""
+		if i < len(results) {
+			result = results[i]
+		}
+		sig := callSignature(tc, result)
+		sigs = append(sigs, sig)
+		d.callCounts[sig]++
+		if n := d.callCounts[sig]; n >= repeatCallLimit && n > repeats {
+			repeated, repeats = tc.Name, n
+		}
+	}
+	sort.Strings(sigs)
+	d.rounds = append(d.rounds, strings.Join(sigs, "\n"))
+
+	if repeated != "" {
+		d.loops++
+		return fmt.Sprintf(loopRepeatNote, repeated, repeats)
+	}
+	if period := d.oscillation(); period > 0 {
+		d.loops++
+		// The pattern must form again from scratch to count as another loop
+		d.rounds = nil
+		return fmt.Sprintf(loopOscillationNote, 2*period)
+	}
+	return ""
+}
+
+// oscillation returns the period when the last iterations went through the
+// same 2 or 3 distinct call sets twice in a row (A B A B), or 0.
+func (d *loopDetector) oscillation() int {
+	for period := 2; period <= 3; period++ {
+		n := len(d.rounds)
+		if n < 2*period {
+			continue
+		}
+		tail := d.rounds[n-2*period:]
+		cyclic := true
+		for i := period; i < 2*period; i++ {
+			if tail[i] != tail[i-period] {
+				cyclic = false
+				break
+			}
+		}
+		// Back-to-back identical sets are repeats, caught by the call counts
+		distinct := true
+		for i := 1; i < period; i++ {
+			if tail[i] == tail[i-1] {
+				distinct = false
+				break
+			}
+		}
+		if cyclic && distinct {
+			return period
+		}
+	}
+	return 0
+}
+
+// exhausted reports whether the model kept looping after its warnings.
+func (d *loopDetector) exhausted() bool {
+	return d.loops > maxLoopWarnings
+}
+
+// callSignature identifies a call by tool name, arguments and a hash of its
+// result. JSON encoding sorts map keys, so equal arguments give equal
+// signatures.
+func callSignature(tc providers.ToolCall, result string) string {
+	args, _ := json.Marshal(tc.Arguments)
+	sum := sha256.Sum256([]byte(result))
+	return tc.Name + " " + string(args) + " " + hex.EncodeToString(sum[:8])
+}
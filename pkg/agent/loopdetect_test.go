@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+)
+
+func loopCall(name string, args map[string]interface{}) []providers.ToolCall {
+	return []providers.ToolCall{{ID: "id", Name: name, Arguments: args}}
+}
+
+func TestLoopDetector_RepeatedIdenticalCalls(t *testing.T) {
+	d := newLoopDetector()
+	args := map[string]interface{}{"path": "a.txt", "limit": 10}
+
+	for i := 1; i < repeatCallLimit; i++ {
+		if note := d.observe(loopCall("read_file", args), nil); note != "" {
+			t.Fatalf("call %d flagged early: %q", i, note)
+		}
+	}
+	// Same arguments in another key order are the same call
+	note := d.observe(loopCall("read_file", map[string]interface{}{"limit": 10, "path": "a.txt"}), nil)
+	if !strings.Contains(note, "read_file") || !strings.Contains(note, "3 times") {
+		t.Fatalf("note = %q, want repeat warning for read_file", note)
+	}
+	if d.exhausted() {
+		t.Fatal("exhausted after the first warning")
+	}
+
+	d.observe(loopCall("read_file", args), nil)
+	if !d.exhausted() {
+		t.Error("not exhausted after repeating past the warning")
+	}
+}
+
+func TestLoopDetector_ChangedResultsAreProgress(t *testing.T) {
+	d := newLoopDetector()
+	read := loopCall("read_file", map[string]interface{}{"path": "a.txt"})
+	edit := loopCall("edit_file", map[string]interface{}{"path": "a.txt", "old_text": "x", "new_text": "y"})
+
+	// read, edit, read again: the file changed in between
+	steps := []struct {
+		calls  []providers.ToolCall
+		result string
+	}{
+		{read, "x"}, {edit, "edited"}, {read, "y"}, {edit, "old_text not found"}, {read, "y"},
+	}
+	for i, s := range steps {
+		if note := d.observe(s.calls, []string{s.result}); note != "" {
+			t.Fatalf("step %d flagged: %q", i+1, note)
+		}
+	}
+	// The same result a third time is a loop
+	if note := d.observe(read, []string{"y"}); !strings.Contains(note, "3 times") {
+		t.Errorf("note = %q, want repeat warning", note)
+	}
+}
+
+func TestLoopDetector_Oscillation(t *testing.T) {
+	d := newLoopDetector()
+	a := loopCall("read_file", map[string]interface{}{"path": "a.txt"})
+	b := loopCall("list_dir", map[string]interface{}{"path": "."})
+
+	for i, calls := range [][]providers.ToolCall{a, b, a} {
+		if note := d.observe(calls, nil); note != "" {
+			t.Fatalf("round %d flagged early: %q", i+1, note)
+		}
+	}
+	if note := d.observe(b, nil); !strings.Contains(note, "alternating") {
+		t.Errorf("note = %q, want oscillation warning", note)
+	}
+}
+
+func TestLoopDetector_ProgressIsNotFlagged(t *testing.T) {
+	d := newLoopDetector()
+	for _, path := range []string{"a", "b", "c", "d", "e", "f"} {
+		if note := d.observe(loopCall("read_file", map[string]interface{}{"path": path}), nil); note != "" {
+			t.Fatalf("distinct call %q flagged: %q", path, note)
+		}
+	}
+}
@@ -107,14 +107,47 @@ var imagePathRe = regexp.MustCompile(`\[Image: ([^\]]+)\]`)
 // CleanupMediaFiles extracts [Image: <path>] references from messages
 // and deletes the corresponding files.
 func CleanupMediaFiles(messages []providers.Message) {
+	for _, path := range mediaPaths(messages) {
+		removeMediaFile(path)
+	}
+}
+
+// mediaPaths returns the [Image: <path>] references in messages.
+func mediaPaths(messages []providers.Message) []string {
+	var paths []string
 	for _, msg := range messages {
-		matches := imagePathRe.FindAllStringSubmatch(msg.Content, -1)
-		for _, m := range matches {
-			path := m[1]
-			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-				logger.WarnCF("media", "Failed to remove media file",
-					map[string]interface{}{"path": path, "error": err.Error()})
-			}
+		for _, m := range imagePathRe.FindAllStringSubmatch(msg.Content, -1) {
+			paths = append(paths, m[1])
+		}
+	}
+	return paths
+}
+
+func removeMediaFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.WarnCF("media", "Failed to remove media file",
+			map[string]interface{}{"path": path, "error": err.Error()})
+	}
+}
+
+// dropMedia deletes the media files of messages removed from a session's
+// history. Files that any stored history still references (other sessions,
+// or branches sharing files with the active one) are kept, so it must run
+// after the session is updated.
+func (al *AgentLoop) dropMedia(removed []providers.Message) {
+	paths := mediaPaths(removed)
+	if len(paths) == 0 {
+		return
+	}
+	inUse := make(map[string]bool)
+	al.sessions.EachMessage(func(m providers.Message) {
+		for _, path := range mediaPaths([]providers.Message{m}) {
+			inUse[path] = true
+		}
+	})
+	for _, path := range paths {
+		if !inUse[path] {
+			removeMediaFile(path)
 		}
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/session"
 )
 
 // --- mimeToExt ---
@@ -155,3 +156,33 @@ func TestCleanupMediaFiles_NonExistentFile(t *testing.T) {
 	}
 	CleanupMediaFiles(messages)
 }
+
+// --- dropMedia ---
+
+func TestDropMedia_KeepsFilesStillReferenced(t *testing.T) {
+	tmpDir := t.TempDir()
+	shared := filepath.Join(tmpDir, "shared.jpg")
+	unused := filepath.Join(tmpDir, "unused.jpg")
+	for _, path := range []string{shared, unused} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	al := &AgentLoop{sessions: session.NewSessionManager("")}
+	// A stashed branch still shows the shared image
+	al.sessions.AddMessage("s1", "user", "[Image: "+shared+"]")
+	if err := al.sessions.Fork("s1", "alt"); err != nil {
+		t.Fatal(err)
+	}
+	al.sessions.Reset("s1")
+
+	al.dropMedia([]providers.Message{{Content: "[Image: " + shared + "] [Image: " + unused + "]"}})
+
+	if _, err := os.Stat(shared); err != nil {
+		t.Error("file referenced by another branch was deleted")
+	}
+	if _, err := os.Stat(unused); !os.IsNotExist(err) {
+		t.Error("unreferenced file was kept")
+	}
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+)
+
+// persona is a named agent definition from agents.personas.
+type persona struct {
+	name    string
+	dir     string      // Bootstrap files directory
+	profile *llmProfile // Chat model and settings (nil = default)
+	tools   []string    // Allowed tools (nil = all)
+	skills  []string    // Skills offered in the prompt (nil = all)
+}
+
+// personaRouter picks the persona that handles a message.
+type personaRouter struct {
+	personas map[string]*persona
+	routes   []config.PersonaRouteConfig
+}
+
+// newPersonaRouter builds the personas and routes from config. Profiles
+// inherit unset sampling settings from chat. It returns nil when no routes
+// are configured.
+func newPersonaRouter(cfg *config.Config, dataDir string, chat llmProfile) *personaRouter {
+	if len(cfg.Agents.Routes) == 0 {
+		return nil
+	}
+
+	r := &personaRouter{personas: make(map[string]*persona, len(cfg.Agents.Personas))}
+	for name, pc := range cfg.Agents.Personas {
+		p := &persona{
+			name:   name,
+			dir:    pc.Dir,
+			tools:  pc.Tools,
+			skills: pc.Skills,
+		}
+		if p.dir == "" {
+			p.dir = filepath.Join(dataDir, "personas", name)
+		} else if !filepath.IsAbs(p.dir) {
+			p.dir = filepath.Join(dataDir, p.dir)
+		}
+		if pc.Profile != "" {
+			profile := resolveProfile(cfg.LLM, "persona:"+name, pc.Profile, chat)
+			p.profile = &profile
+		}
+		r.personas[name] = p
+	}
+
+	for _, route := range cfg.Agents.Routes {
+		if _, ok := r.personas[route.Persona]; !ok {
+			logger.WarnCF("agent", "Persona route names an unknown persona, ignoring it",
+				map[string]interface{}{"persona": route.Persona})
+			continue
+		}
+		r.routes = append(r.routes, route)
+	}
+	return r
+}
+
+// resolve returns the persona of the first route matching the message, or
+// nil for the default agent.
+func (r *personaRouter) resolve(channel, chatID, senderID string, user *User) *persona {
+	if r == nil {
+		return nil
+	}
+	for _, route := range r.routes {
+		if route.Channel != "" && route.Channel != channel {
+			continue
+		}
+		if route.ChatID != "" && route.ChatID != chatID {
+			continue
+		}
+		if route.User != "" && !routeMatchesUser(route.User, senderID, user) {
+			continue
+		}
+		return r.personas[route.Persona]
+	}
+	return nil
+}
+
+// routeMatchesUser reports whether a route's user matches the sender's user
+// directory ID or name, or the channel sender ID ("123" matches "123|alice").
+func routeMatchesUser(want, senderID string, user *User) bool {
+	if user != nil && (want == user.ID || strings.EqualFold(want, user.Name)) {
+		return true
+	}
+	id, _, _ := strings.Cut(senderID, "|")
+	return want == senderID || want == id
+}
+
+// personaTools returns the registry of tools a persona may use, or nil when
+// it may use every tool.
+func (al *AgentLoop) personaTools(p *persona) *tools.ToolRegistry {
+	if p == nil || len(p.tools) == 0 {
+		return nil
+	}
+	return al.tools.Subset(p.tools)
+}
+
+// personaContext returns the context builder for a turn's persona.
+func (al *AgentLoop) personaContext(opts processOptions) *ContextBuilder {
+	if opts.Persona == nil {
+		return al.contextBuilder
+	}
+	return al.contextBuilder.WithPersona(opts.Persona.dir, opts.Tools, opts.Persona.skills)
+}
+
+// turnTools returns the tools available to a turn.
+func (al *AgentLoop) turnTools(opts processOptions) *tools.ToolRegistry {
+	if opts.Tools != nil {
+		return opts.Tools
+	}
+	return al.tools
+}
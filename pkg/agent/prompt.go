@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+// Built-in system prompt sections. Each can be overridden by a file of the
+// same name in <data_dir>/prompts/ (or the persona's prompts/ directory).
+//
+//go:embed prompts/*.tmpl
+var builtinPromptFS embed.FS
+
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+var builtinPrompts = template.Must(template.New("").Funcs(promptFuncs).ParseFS(builtinPromptFS, "prompts/*.tmpl"))
+
+// promptSections are the system prompt sections, in order. Sections that
+// render empty are left out.
+var promptSections = []string{
+	"identity",
+	"safety",
+	"tool_call_style",
+	"subagents",
+	"messaging",
+	"cron",
+	"memory",
+	"users",
+	"bootstrap",
+	"skills",
+	"mcp",
+	"channels",
+	"memory_context",
+	"silent_reply",
+	"heartbeat",
+}
+
+// sessionSections follow the system prompt sections and describe the
+// current conversation.
+var sessionSections = []string{
+	"session",
+	"voice",
+}
+
+// PromptContext describes the conversation a system prompt is built for.
+type PromptContext struct {
+	Channel   string
+	ChatID    string
+	InputMode string // "text", "voice" or "assistant"
+	Locale    string // Normalized locale code (e.g. "en", "ja")
+	User      *User  // Resolved sender (nil if unknown)
+}
+
+// PromptData is the data prompt templates are executed with.
+type PromptData struct {
+	Time             string    // Current time, formatted for the prompt
+	Now              time.Time // Current time, for custom formatting
+	Workspace        string    // Absolute workspace path
+	DataDir          string    // Absolute data directory path
+	Tools            []string  // Tool summaries ("- `name` - description")
+	ToolNames        []string  // Names of the available tools
+	Channels         []string  // Enabled channels
+	Channel          string    // Current channel
+	ChatID           string    // Current chat ID
+	InputMode        string    // Current input mode
+	Locale           string    // Sender's locale
+	User             *User     // Resolved sender (nil if unknown)
+	MemoryTool       bool      // Whether the memory tool is registered
+	Bootstrap        string    // Loaded bootstrap files (SOUL.md, etc.)
+	Skills           string    // Skills summary
+	MCP              string    // MCP server summary
+	Memory           string    // Memory contents
+	SilentReplyToken string
+}
+
+// HasTool reports whether the named tool is available.
+func (d PromptData) HasTool(name string) bool {
+	for _, n := range d.ToolNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// promptData collects the template variables for a conversation.
+func (cb *ContextBuilder) promptData(pc PromptContext) PromptData {
+	now := time.Now()
+	workspace, _ := filepath.Abs(cb.workspace)
+	dataDir, _ := filepath.Abs(cb.dataDir)
+
+	data := PromptData{
+		Time:             now.Format("2006-01-02 15:04 MST (Monday)"),
+		Now:              now,
+		Workspace:        workspace,
+		DataDir:          dataDir,
+		Channels:         cb.enabledChannels,
+		Channel:          pc.Channel,
+		ChatID:           pc.ChatID,
+		InputMode:        pc.InputMode,
+		Locale:           pc.Locale,
+		User:             pc.User,
+		MemoryTool:       cb.memoryToolEnabled,
+		Bootstrap:        cb.LoadBootstrapFiles(),
+		Skills:           cb.skillsLoader.BuildSkillsSummaryFor(cb.skills),
+		Memory:           cb.memory.GetMemoryContext(),
+		SilentReplyToken: SilentReplyToken,
+	}
+	if cb.tools != nil {
+		data.Tools = cb.tools.GetSummaries()
+		data.ToolNames = cb.tools.List()
+	}
+	if cb.mcpManager != nil {
+		data.MCP = cb.mcpManager.BuildSummary()
+	}
+	return data
+}
+
+// renderSection renders a prompt section, preferring an override template
+// from the persona or data directory. An override that fails to parse or
+// execute is logged and the built-in section is used instead.
+func (cb *ContextBuilder) renderSection(name string, data PromptData) string {
+	if text, path, ok := cb.readPromptOverride(name); ok {
+		out, err := executePrompt(name, text, data)
+		if err == nil {
+			return out
+		}
+		logger.WarnCF("agent", "Invalid prompt template, using built-in",
+			map[string]interface{}{
+				"path":  path,
+				"error": err.Error(),
+			})
+	}
+
+	var buf bytes.Buffer
+	if err := builtinPrompts.ExecuteTemplate(&buf, name+".tmpl", data); err != nil {
+		logger.ErrorCF("agent", "Failed to render built-in prompt section",
+			map[string]interface{}{
+				"section": name,
+				"error":   err.Error(),
+			})
+		return ""
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// readPromptOverride reads prompts/<name>.tmpl from the persona directory,
+// falling back to the data directory.
+func (cb *ContextBuilder) readPromptOverride(name string) (string, string, bool) {
+	for _, dir := range []string{cb.bootstrapDir, cb.dataDir} {
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, "prompts", name+".tmpl")
+		if data, err := os.ReadFile(path); err == nil {
+			return string(data), path, true
+		}
+	}
+	return "", "", false
+}
+
+func executePrompt(name, text string, data PromptData) (string, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// renderSections renders the named sections, dropping empty ones.
+func (cb *ContextBuilder) renderSections(names []string, data PromptData) []string {
+	var parts []string
+	for _, name := range names {
+		if out := cb.renderSection(name, data); out != "" {
+			parts = append(parts, out)
+		}
+	}
+	return parts
+}
+
+// RenderSystemPrompt returns the system prompt a message would be answered
+// with, including the persona it routes to and its session section. userID
+// selects a registered user as the sender instead of resolving msg.SenderID.
+func (al *AgentLoop) RenderSystemPrompt(msg bus.InboundMessage, userID string) (string, error) {
+	_, user := al.senderMessage(msg)
+	if userID != "" {
+		if al.userStore != nil {
+			user = al.userStore.Get(userID)
+		}
+		if user == nil {
+			return "", fmt.Errorf("user not found: %s", userID)
+		}
+	}
+
+	opts := processOptions{
+		Channel:      msg.Channel,
+		ChatID:       msg.ChatID,
+		InputMode:    "text",
+		Locale:       "en",
+		ResolvedUser: user,
+		Persona:      al.personas.resolve(msg.Channel, msg.ChatID, msg.SenderID, user),
+	}
+	if mode := msg.Metadata["input_mode"]; mode != "" {
+		opts.InputMode = mode
+	}
+	if l := msg.Metadata["locale"]; l != "" {
+		opts.Locale = i18n.NormalizeLocale(l)
+	}
+	opts.Tools = al.personaTools(opts.Persona)
+
+	messages := al.personaContext(opts).BuildMessages(nil, "", "", nil, opts.promptContext())
+	return messages[0].Content, nil
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+func TestBuildSystemPrompt_TemplateOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	cb := NewContextBuilder(tmpDir, tmpDir)
+	cb.SetEnabledChannels([]string{"telegram"})
+
+	prompt := cb.BuildSystemPrompt(PromptContext{})
+	for _, want := range []string{"## Safety", "## Messaging", "- telegram\n", "## Silent Replies", "## Heartbeats"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("built-in prompt is missing %q", want)
+		}
+	}
+
+	promptsDir := filepath.Join(tmpDir, "prompts")
+	if err := os.MkdirAll(promptsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	overrides := map[string]string{
+		"safety.tmpl":       "## House Rules\nReply in {{.Locale}} on {{join .Channels \", \"}}.",
+		"heartbeat.tmpl":    "",            // Removes the section
+		"silent_reply.tmpl": "{{.Missing}", // Broken: falls back to the built-in
+	}
+	for name, text := range overrides {
+		if err := os.WriteFile(filepath.Join(promptsDir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompt = cb.BuildSystemPrompt(PromptContext{Locale: "ja"})
+	if strings.Contains(prompt, "## Safety") || !strings.Contains(prompt, "## House Rules\nReply in ja on telegram.") {
+		t.Error("safety section was not replaced by the override")
+	}
+	if strings.Contains(prompt, "## Heartbeats") {
+		t.Error("empty override should remove the heartbeat section")
+	}
+	if !strings.Contains(prompt, "## Silent Replies") {
+		t.Error("broken override should fall back to the built-in section")
+	}
+	if strings.Contains(prompt, "---\n\n---") {
+		t.Error("removed section left an empty separator")
+	}
+}
+
+func TestRenderSystemPrompt_SessionAndVoice(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				DataDir:           tmpDir,
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	user, err := al.userStore.Create("Alice", "telegram", "555")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := al.userStore.AddMemo(user.ID, "prefers short answers"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		ChatID:   "42",
+		SenderID: "555",
+		Metadata: map[string]string{"input_mode": "voice"},
+	}
+	prompt, err := al.RenderSystemPrompt(msg, "")
+	if err != nil {
+		t.Fatalf("RenderSystemPrompt failed: %v", err)
+	}
+	want := "## Current Session\nChannel: telegram\nChat ID: 42\nInput Mode: voice\nSender: Alice (ID: " + user.ID + ")\nUser Notes:\n- prefers short answers"
+	if !strings.Contains(prompt, want) {
+		t.Errorf("session section missing, prompt ends with:\n%s", prompt[len(prompt)-600:])
+	}
+	if !strings.Contains(prompt, "## Voice Mode Instructions") {
+		t.Error("voice input should add voice mode instructions")
+	}
+
+	if _, err := al.RenderSystemPrompt(msg, "unknown"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+}
@@ -34,6 +34,16 @@ func statusLabel(toolName string, args map[string]interface{}, locale string) st
 			return i18n.Tf(locale, "status.listing_dir_q", filepath.Base(p)+"/")
 		}
 		return i18n.T(locale, "status.listing_dir")
+	case "grep":
+		if p := strArg(args, "pattern"); p != "" {
+			return i18n.Tf(locale, "status.searching_files_q", truncLabel(p, 20))
+		}
+		return i18n.T(locale, "status.searching_files")
+	case "find_files":
+		if p := strArg(args, "pattern"); p != "" {
+			return i18n.Tf(locale, "status.finding_files_q", truncLabel(p, 20))
+		}
+		return i18n.T(locale, "status.finding_files")
 	case "exec":
 		if c := strArg(args, "command"); c != "" {
 			return i18n.Tf(locale, "status.running_command_q", truncLabel(c, 30))
@@ -101,6 +101,8 @@ func TestStatusLabel(t *testing.T) {
 		{"append_file", "append_file", map[string]interface{}{}, "ファイル追記中..."},
 		{"list_dir with path", "list_dir", map[string]interface{}{"path": "/home/user/docs"}, "docs/"},
 		{"list_dir no path", "list_dir", map[string]interface{}{}, "フォルダ確認中..."},
+		{"grep with pattern", "grep", map[string]interface{}{"pattern": "TODO"}, "TODO"},
+		{"find_files no pattern", "find_files", map[string]interface{}{}, "ファイル検索中..."},
 		{"exec with command", "exec", map[string]interface{}{"command": "ls -la"}, "ls -la"},
 		{"exec no command", "exec", map[string]interface{}{}, "コマンド実行中..."},
 		{"memory", "memory", map[string]interface{}{"action": "read_long_term"}, "メモリ読み込み中..."},
@@ -140,6 +142,7 @@ func TestStatusLabelEnglish(t *testing.T) {
 	}{
 		{"web_search", "web_search", map[string]interface{}{}, "Searching..."},
 		{"read_file", "read_file", map[string]interface{}{}, "Reading file..."},
+		{"grep", "grep", map[string]interface{}{}, "Searching files..."},
 		{"exec", "exec", map[string]interface{}{}, "Running command..."},
 		{"memory", "memory", map[string]interface{}{"action": "read_long_term"}, "Loading memory..."},
 		{"exit", "exit", map[string]interface{}{}, "Shutting down assistant..."},
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+)
+
+// steerQueue carries follow-up messages into a running turn (steer mode).
+// The turn takes them at its next iteration checkpoint; messages arriving
+// after the last checkpoint become the process's next turn.
+type steerQueue struct {
+	mu     sync.Mutex
+	msgs   []bus.InboundMessage
+	closed bool
+}
+
+func newSteerQueue() *steerQueue {
+	return &steerQueue{}
+}
+
+// push adds a follow-up unless the process has stopped accepting them.
+func (q *steerQueue) push(msg bus.InboundMessage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	q.msgs = append(q.msgs, msg)
+	return true
+}
+
+// take removes and returns all pending follow-ups. Safe on a nil queue.
+func (q *steerQueue) take() []bus.InboundMessage {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.msgs
+	q.msgs = nil
+	return msgs
+}
+
+// next pops the oldest pending follow-up once a turn has finished. When none
+// is pending the queue closes, so later messages start a new process.
+func (q *steerQueue) next() (bus.InboundMessage, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.msgs) == 0 {
+		q.closed = true
+		return bus.InboundMessage{}, false
+	}
+	msg := q.msgs[0]
+	q.msgs = q.msgs[1:]
+	return msg, true
+}
+
+// isCommand reports whether content is a slash command, which is never
+// steered into a running turn.
+func isCommand(content string) bool {
+	return strings.HasPrefix(strings.TrimSpace(content), "/")
+}
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/constants"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+	"github.com/KarakuriAgent/clawdroid/pkg/trace"
+	"github.com/KarakuriAgent/clawdroid/pkg/utils"
+)
+
+// defaultMaxParallelTools bounds concurrent tool calls when the config leaves it unset.
+const defaultMaxParallelTools = 4
+
+// toolImagesNote introduces the images returned by tool calls, which are
+// sent after the tool results.
+const toolImagesNote = "[System: Images returned by the tool calls above.]"
+
+// toolBatches splits tool calls into consecutive groups that may run together.
+// Runs of concurrency-safe calls form one batch; every other call runs alone,
+// so a write is never reordered with the reads around it.
+func (al *AgentLoop) toolBatches(calls []providers.ToolCall) [][]providers.ToolCall {
+	var batches [][]providers.ToolCall
+	safeRun := false
+	for _, tc := range calls {
+		safe := al.tools.IsConcurrencySafe(tc.Name)
+		if safe && safeRun {
+			batches[len(batches)-1] = append(batches[len(batches)-1], tc)
+			continue
+		}
+		batches = append(batches, []providers.ToolCall{tc})
+		safeRun = safe
+	}
+	return batches
+}
+
+// runToolBatch executes a batch with at most maxParallelTools calls in flight
+// and returns the results in call order. Rate limits are checked and status
+// labels published in call order before any call starts.
+func (al *AgentLoop) runToolBatch(ctx context.Context, batch []providers.ToolCall, opts processOptions, locale string, iteration int, currentStatus *atomic.Value) []*tools.ToolResult {
+	results := make([]*tools.ToolResult, len(batch))
+	runnable := make([]int, 0, len(batch))
+	var labels []string
+
+	for i, tc := range batch {
+		// Check tool call rate limit
+		if err := al.rateLimiter.checkToolCall(); err != nil {
+			logger.WarnCF("agent", "Tool call rate limited",
+				map[string]interface{}{
+					"tool":      tc.Name,
+					"iteration": iteration,
+				})
+			results[i] = tools.ErrorResult(i18n.Tf(locale, "agent.rate_limited_tool", err))
+			continue
+		}
+
+		// Log tool call with arguments preview
+		argsJSON, _ := json.Marshal(tc.Arguments)
+		argsPreview := utils.Truncate(string(argsJSON), 200)
+		logger.InfoCF("agent", fmt.Sprintf("Tool call: %s(%s)", tc.Name, argsPreview),
+			map[string]interface{}{
+				"tool":      tc.Name,
+				"iteration": iteration,
+			})
+
+		if label := statusLabel(tc.Name, tc.Arguments, locale); label != "" && !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+		runnable = append(runnable, i)
+	}
+
+	// Emit tool use status indicator; a parallel batch shows all its labels
+	if len(labels) > 0 && !constants.IsInternalChannel(opts.Channel) {
+		label := strings.Join(labels, "\n")
+		currentStatus.Store(label)
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: label,
+			Type:    "status",
+		})
+	}
+
+	if len(runnable) == 1 {
+		i := runnable[0]
+		results[i] = al.executeTool(ctx, batch[i], opts)
+		return results
+	}
+
+	if len(runnable) > 1 {
+		logger.DebugCF("agent", "Running tool calls in parallel",
+			map[string]interface{}{
+				"count":     len(runnable),
+				"limit":     al.maxParallelTools,
+				"iteration": iteration,
+			})
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, al.maxParallelTools)
+	for _, i := range runnable {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = al.executeTool(ctx, batch[i], opts)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// executeTool runs a single tool call.
+func (al *AgentLoop) executeTool(ctx context.Context, tc providers.ToolCall, opts processOptions) *tools.ToolResult {
+	// Create async callback for tools that implement AsyncTool
+	// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
+	// Instead, they notify the agent via PublishInbound, and the agent decides
+	// whether to forward the result to the user (in processSystemMessage).
+	asyncCallback := func(callbackCtx context.Context, result *tools.ToolResult) {
+		// Log the async completion but don't send directly to user
+		// The agent will handle user notification via processSystemMessage
+		if !result.Silent && result.ForUser != "" {
+			logger.InfoCF("agent", "Async tool completed, agent will handle notification",
+				map[string]interface{}{
+					"tool":        tc.Name,
+					"content_len": len(result.ForUser),
+				})
+		}
+	}
+
+	args, _ := json.Marshal(tc.Arguments)
+	// Traces are saved to disk, so arguments are redacted like tool results
+	traceArgs := string(args)
+	if al.redactor.ToolResults() {
+		traceArgs, _ = al.redactor.Redact(opts.Channel, traceArgs)
+	}
+	ctx, span := trace.Start(ctx, "tool."+tc.Name, map[string]interface{}{
+		"tool":       tc.Name,
+		"args":       utils.Truncate(traceArgs, 500),
+		"args_bytes": len(args),
+	})
+	result := al.turnTools(opts).ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+	span.SetAttr("result_bytes", len(result.ForLLM))
+	span.SetAttr("is_error", result.IsError)
+	span.SetAttr("async", result.Async)
+	span.SetError(result.Err)
+	span.End()
+	return result
+}
+
+// toolResultMessage delivers a tool result's user-facing content and builds
+// the tool message for the LLM.
+func (al *AgentLoop) toolResultMessage(tc providers.ToolCall, toolResult *tools.ToolResult, opts processOptions) providers.Message {
+	// Send ForUser content to user immediately if not Silent
+	if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
+		al.bus.PublishOutbound(bus.OutboundMessage{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Content: toolResult.ForUser,
+		})
+		logger.DebugCF("agent", "Sent tool result to user",
+			map[string]interface{}{
+				"tool":        tc.Name,
+				"content_len": len(toolResult.ForUser),
+			})
+	}
+
+	// Determine content for LLM based on tool result
+	contentForLLM := toolResult.ForLLM
+	if contentForLLM == "" && toolResult.Err != nil {
+		contentForLLM = toolResult.Err.Error()
+	}
+
+	// Persist media files from tool results (e.g. screenshots); the images
+	// themselves are sent to the LLM separately, see runLLMIteration
+	if len(toolResult.Media) > 0 {
+		paths := PersistMedia(toolResult.Media, al.mediaDir)
+		for _, p := range paths {
+			contentForLLM += fmt.Sprintf("\n[Image: %s]", p)
+		}
+	}
+
+	return providers.Message{
+		Role:       "tool",
+		Content:    contentForLLM,
+		ToolCallID: tc.ID,
+	}
+}
+
+// redactToolResult masks secrets and personal data in a tool result before
+// it is saved to session history, following the channel's redaction policy.
+// The current turn still sees the original result.
+func (al *AgentLoop) redactToolResult(msg providers.Message, opts processOptions) providers.Message {
+	if !al.redactor.ToolResults() {
+		return msg
+	}
+	content, n := al.redactor.Redact(opts.Channel, msg.Content)
+	if n > 0 {
+		logger.InfoCF("agent", "Redacted tool result before saving",
+			map[string]interface{}{
+				"tool_call_id": msg.ToolCallID,
+				"redactions":   n,
+			})
+		msg.Content = content
+	}
+	return msg
+}
+
+// execToolFactory returns a constructor for exec tools with the configured
+// policy and sandbox. It returns nil tools when exec is disabled or its
+// config is invalid, so a bad pattern never loosens the guard.
+func execToolFactory(cfg *config.Config, workspace string, restrict bool, sandbox tools.Sandbox) func() *tools.ExecTool {
+	disabled := func() *tools.ExecTool { return nil }
+	execCfg := cfg.Tools.Exec
+	if !execCfg.Enabled {
+		return disabled
+	}
+	if _, err := tools.NewExecToolFromConfig(execCfg, workspace, restrict); err != nil {
+		logger.ErrorCF("agent", "Invalid exec config; exec disabled",
+			map[string]interface{}{"error": err.Error()})
+		return disabled
+	}
+	return func() *tools.ExecTool {
+		execTool, _ := tools.NewExecToolFromConfig(execCfg, workspace, restrict)
+		if sandbox != nil {
+			execTool.SetSandbox(sandbox)
+		}
+		return execTool
+	}
+}
+
+// newExecSandbox sets up the exec sandbox when enabled. A sandbox that cannot
+// be set up is replaced by one that refuses every command, so exec never
+// silently runs unsandboxed.
+func newExecSandbox(cfg *config.Config, workspace string) tools.Sandbox {
+	sc := cfg.Tools.Exec.Sandbox
+	if !cfg.Tools.Exec.Enabled || !sc.Enabled {
+		return nil
+	}
+	sb, err := tools.NewSandbox(sc.Backend, tools.SandboxOptions{
+		Workspace:     workspace,
+		WritablePaths: sc.WritablePaths,
+		ReadOnlyPaths: sc.ReadOnlyPaths,
+		Network:       sc.Network,
+		CPUSeconds:    sc.CPUSeconds,
+		MemoryMB:      sc.MemoryMB,
+		PassEnv:       cfg.Tools.Exec.EnvPassthrough,
+		EnvScrub:      cfg.Tools.Exec.EnvScrub,
+	})
+	if err != nil {
+		logger.ErrorCF("agent", "Exec sandbox unavailable; exec commands will be refused",
+			map[string]interface{}{"backend": sc.Backend, "error": err.Error()})
+		return tools.UnavailableSandbox(err)
+	}
+	logger.InfoCF("agent", "Exec sandbox enabled",
+		map[string]interface{}{"backend": sb.Name(), "network": sc.Network})
+	return sb
+}
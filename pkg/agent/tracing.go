@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/trace"
+)
+
+// startTrace starts a trace for an operation when tracing is enabled.
+// Without it the context is returned unchanged with a nil trace, and spans
+// started from it are no-ops.
+func (al *AgentLoop) startTrace(ctx context.Context, name string, attrs map[string]interface{}) (context.Context, *trace.Trace) {
+	if al.traces == nil {
+		return ctx, nil
+	}
+	return trace.New(ctx, name, attrs)
+}
+
+// finishTrace ends a trace, stores it, and exports it when an OTLP endpoint
+// is configured.
+func (al *AgentLoop) finishTrace(t *trace.Trace, err error) {
+	if t == nil {
+		return
+	}
+	t.Root().SetError(err)
+	spans := t.Finish()
+	if saveErr := al.traces.Save(spans); saveErr != nil {
+		logger.WarnCF("agent", "Failed to save trace",
+			map[string]interface{}{"trace_id": t.ID, "error": saveErr.Error()})
+	}
+	logger.DebugCF("agent", "Trace saved",
+		map[string]interface{}{"trace_id": t.ID, "spans": len(spans)})
+
+	if al.traceExporter == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := al.traceExporter.Export(ctx, spans); err != nil {
+			logger.WarnCF("agent", "Failed to export trace",
+				map[string]interface{}{"trace_id": t.ID, "error": err.Error()})
+		}
+	}()
+}
+
+// endLLMSpan records the outcome of an LLM call on its span and ends it.
+func endLLMSpan(span *trace.ActiveSpan, resp *providers.LLMResponse, err error) {
+	span.SetError(err)
+	if resp != nil {
+		span.SetAttr("finish_reason", resp.FinishReason)
+		span.SetAttr("tool_calls", len(resp.ToolCalls))
+		span.SetAttr("response_chars", len(resp.Content))
+		if resp.Model != "" {
+			span.SetAttr("response_model", resp.Model)
+		}
+		if resp.Usage != nil {
+			span.SetAttr("prompt_tokens", resp.Usage.PromptTokens)
+			span.SetAttr("completion_tokens", resp.Usage.CompletionTokens)
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+	"github.com/KarakuriAgent/clawdroid/pkg/usage"
+)
+
+// recordUsage adds the token usage of resp to the usage tracker.
+// entry identifies the caller; token counts are filled in from resp.
+func (al *AgentLoop) recordUsage(entry usage.Entry, resp *providers.LLMResponse) {
+	if al.usage == nil || resp == nil || resp.Usage == nil {
+		return
+	}
+	if resp.Model != "" {
+		entry.Model = resp.Model
+	}
+	entry.PromptTokens = resp.Usage.PromptTokens
+	entry.CompletionTokens = resp.Usage.CompletionTokens
+
+	if _, err := al.usage.Record(entry); err != nil {
+		logger.WarnCF("agent", "Failed to save usage",
+			map[string]interface{}{"error": err.Error()})
+	}
+}
+
+// usageEntry builds the usage entry for a call made while processing opts.
+func usageEntry(opts processOptions, model string) usage.Entry {
+	entry := usage.Entry{
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		Model:      model,
+	}
+	if opts.ResolvedUser != nil {
+		entry.UserID = opts.ResolvedUser.ID
+	}
+	return entry
+}
+
+// sessionUsageEntry builds the usage entry for a call made on behalf of a
+// session outside message processing (e.g. summarization).
+func sessionUsageEntry(sessionKey, model string) usage.Entry {
+	channel, _, _ := strings.Cut(sessionKey, ":")
+	return usage.Entry{
+		SessionKey: sessionKey,
+		Channel:    channel,
+		Model:      model,
+	}
+}
+
+// budgetProfileFor returns the profile to use in place of profile once the
+// soft budget is reached, or profile itself.
+func (al *AgentLoop) budgetProfileFor(profile llmProfile) llmProfile {
+	if al.usage == nil || al.budgetProfile == nil || al.usage.Budget() == usage.BudgetOK {
+		return profile
+	}
+	if profile.Model != al.budgetProfile.Model {
+		logger.InfoCF("agent", "Daily soft budget reached, using budget model",
+			map[string]interface{}{
+				"model":        al.budgetProfile.Model,
+				"instead_of":   profile.Model,
+				"spent_today":  al.usage.SpentToday(),
+				"daily_budget": al.softBudget(),
+			})
+	}
+	return *al.budgetProfile
+}
+
+func (al *AgentLoop) softBudget() float64 {
+	soft, _ := al.usage.Budgets()
+	return soft
+}
+
+// errHardBudget stops LLM calls once the daily hard budget is spent.
+var errHardBudget = errors.New("daily hard budget reached")
+
+// checkBudget returns errHardBudget once the daily hard budget is reached.
+func (al *AgentLoop) checkBudget() error {
+	if al.overHardBudget() {
+		return errHardBudget
+	}
+	return nil
+}
+
+// overHardBudget reports whether the daily hard budget has been reached.
+func (al *AgentLoop) overHardBudget() bool {
+	return al.usage != nil && al.usage.Budget() == usage.BudgetHard
+}
+
+// formatUsage renders today's usage for the /usage command.
+func (al *AgentLoop) formatUsage(sessionKey, channel string, user *User, locale string) string {
+	if al.usage == nil {
+		return i18n.T(locale, "agent.cmd.usage.unavailable")
+	}
+	today := al.usage.Today()
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T(locale, "agent.cmd.usage.title"))
+	writeTotals := func(labelKey string, t *usage.Totals) {
+		if t == nil {
+			t = &usage.Totals{}
+		}
+		sb.WriteString("\n")
+		sb.WriteString(i18n.Tf(locale, labelKey, formatTokens(t), formatCost(t.Cost)))
+	}
+	writeTotals("agent.cmd.usage.session", today.Sessions[sessionKey])
+	if user != nil {
+		writeTotals("agent.cmd.usage.user", today.Users[user.ID])
+	}
+	writeTotals("agent.cmd.usage.channel", today.Channels[channel])
+	writeTotals("agent.cmd.usage.total", &today.Total)
+
+	soft, hard := al.usage.Budgets()
+	if soft > 0 || hard > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(i18n.Tf(locale, "agent.cmd.usage.budget", formatBudget(soft), formatBudget(hard)))
+	}
+	return sb.String()
+}
+
+func formatTokens(t *usage.Totals) string {
+	return fmt.Sprintf("%d in / %d out", t.PromptTokens, t.CompletionTokens)
+}
+
+func formatCost(cost float64) string {
+	return fmt.Sprintf("%.4f", cost)
+}
+
+func formatBudget(budget float64) string {
+	if budget <= 0 {
+		return "-"
+	}
+	return formatCost(budget)
+}
@@ -11,10 +11,11 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
-	Type    string `json:"type,omitempty"` // "message" (default when empty), "status", "error"
+	Channel  string            `json:"channel"`
+	ChatID   string            `json:"chat_id"`
+	Content  string            `json:"content"`
+	Type     string            `json:"type,omitempty"`     // "message" (default when empty), "status", "stream", "error", "approval_request"
+	Metadata map[string]string `json:"metadata,omitempty"` // e.g. request_id and locale of an approval request
 }
 
 type MessageHandler func(InboundMessage) error
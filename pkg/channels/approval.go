@@ -0,0 +1,81 @@
+package channels
+
+import (
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
+)
+
+// approvalCallbackPrefix marks button payloads carrying approval decisions.
+const approvalCallbackPrefix = "approval:"
+
+// approvalCallbackData encodes a decision as a button payload.
+func approvalCallbackData(requestID string, approved bool) string {
+	if approved {
+		return approvalCallbackPrefix + "approve:" + requestID
+	}
+	return approvalCallbackPrefix + "deny:" + requestID
+}
+
+// parseApprovalCallback decodes a button payload from approvalCallbackData.
+func parseApprovalCallback(data string) (requestID string, approved bool, ok bool) {
+	rest, ok := strings.CutPrefix(data, approvalCallbackPrefix)
+	if !ok {
+		return "", false, false
+	}
+	decision, requestID, ok := strings.Cut(rest, ":")
+	if !ok || requestID == "" || (decision != "approve" && decision != "deny") {
+		return "", false, false
+	}
+	return requestID, decision == "approve", true
+}
+
+// parseApprovalReply recognizes typed "/approve <id>" and "/deny <id>" replies.
+func parseApprovalReply(content string) (requestID string, approved bool, ok bool) {
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return "", false, false
+	}
+	switch fields[0] {
+	case "/approve":
+		return fields[1], true, true
+	case "/deny":
+		return fields[1], false, true
+	}
+	return "", false, false
+}
+
+// approvalMeta returns the request ID and locale of an approval request.
+func approvalMeta(msg bus.OutboundMessage) (requestID, locale string) {
+	locale = msg.Metadata["locale"]
+	if locale == "" {
+		locale = "en"
+	}
+	return msg.Metadata["request_id"], locale
+}
+
+// deliverApproval passes a decision from senderID in chatID to the waiting
+// tool call. It reports whether the decision was accepted; only the person
+// who triggered the call, in the chat it was asked in, may decide it.
+func (c *BaseChannel) deliverApproval(senderID, chatID, requestID string, approved bool) bool {
+	logger.InfoCF(c.name, "Approval decision received", map[string]interface{}{
+		"approval_id": requestID,
+		"approved":    approved,
+		"sender_id":   senderID,
+		"chat_id":     chatID,
+	})
+	return tools.DeliverApproval(requestID, approved, c.name, chatID, senderID)
+}
+
+// handleApprovalReply delivers a typed approval decision, reporting whether
+// content was one. Works on every channel, with or without buttons.
+func (c *BaseChannel) handleApprovalReply(senderID, chatID, content string) bool {
+	requestID, approved, ok := parseApprovalReply(content)
+	if !ok {
+		return false
+	}
+	c.deliverApproval(senderID, chatID, requestID, approved)
+	return true
+}
@@ -0,0 +1,40 @@
+package channels
+
+import "testing"
+
+func TestParseApprovalCallback(t *testing.T) {
+	id, approved, ok := parseApprovalCallback(approvalCallbackData("ab12cd34", true))
+	if !ok || !approved || id != "ab12cd34" {
+		t.Errorf("approve payload parsed as (%q, %v, %v)", id, approved, ok)
+	}
+	id, approved, ok = parseApprovalCallback(approvalCallbackData("ab12cd34", false))
+	if !ok || approved || id != "ab12cd34" {
+		t.Errorf("deny payload parsed as (%q, %v, %v)", id, approved, ok)
+	}
+	for _, data := range []string{"", "approval:", "approval:maybe:ab12", "approval:approve:", "other:approve:ab12"} {
+		if _, _, ok := parseApprovalCallback(data); ok {
+			t.Errorf("parseApprovalCallback(%q) accepted", data)
+		}
+	}
+}
+
+func TestParseApprovalReply(t *testing.T) {
+	tests := []struct {
+		content  string
+		id       string
+		approved bool
+		ok       bool
+	}{
+		{"/approve ab12cd34", "ab12cd34", true, true},
+		{"  /deny ab12cd34 ", "ab12cd34", false, true},
+		{"/approve", "", false, false},
+		{"please /approve ab12cd34", "", false, false},
+		{"hello", "", false, false},
+	}
+	for _, tt := range tests {
+		id, approved, ok := parseApprovalReply(tt.content)
+		if id != tt.id || approved != tt.approved || ok != tt.ok {
+			t.Errorf("parseApprovalReply(%q) = (%q, %v, %v), want (%q, %v, %v)", tt.content, id, approved, ok, tt.id, tt.approved, tt.ok)
+		}
+	}
+}
@@ -87,6 +87,11 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		return
 	}
 
+	// Approval replies go to the waiting tool call, not the agent
+	if c.handleApprovalReply(senderID, chatID, content) {
+		return
+	}
+
 	// Build session key: channel:chatID
 	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
 
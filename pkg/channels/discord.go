@@ -9,7 +9,9 @@ import (
 
 	"github.com/KarakuriAgent/clawdroid/pkg/bus"
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
 	"github.com/KarakuriAgent/clawdroid/pkg/utils"
 	"github.com/bwmarrin/discordgo"
 )
@@ -23,6 +25,7 @@ type DiscordChannel struct {
 	session *discordgo.Session
 	config  config.DiscordConfig
 	ctx     context.Context
+	streams *streamTracker
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -38,6 +41,7 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 		session:     session,
 		config:      cfg,
 		ctx:         context.Background(),
+		streams:     newStreamTracker(),
 	}, nil
 }
 
@@ -46,6 +50,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 
 	c.ctx = ctx
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -86,6 +91,16 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel ID is empty")
 	}
 
+	switch msg.Type {
+	case "stream":
+		return c.sendStream(ctx, channelID, msg)
+	case "status_end":
+		c.streams.finish(msg.ChatID)
+		return nil
+	case tools.ApprovalRequestType:
+		return c.sendApprovalRequest(ctx, channelID, msg)
+	}
+
 	runes := []rune(msg.Content)
 	if len(runes) == 0 {
 		return nil
@@ -93,6 +108,15 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 
 	chunks := splitMessage(msg.Content, 1500) // Discord has a limit of 2000 characters per message, leave 500 for natural split e.g. code blocks
 
+	// The final response or an error replaces the streamed reply with its first chunk
+	if msg.Type == "" || msg.Type == "error" {
+		if id, ok := c.streams.finish(msg.ChatID); ok {
+			if err := c.editMessage(ctx, channelID, id, chunks[0]); err == nil {
+				chunks = chunks[1:]
+			}
+		}
+	}
+
 	for _, chunk := range chunks {
 		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
 			return err
@@ -248,6 +272,146 @@ func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content strin
 	}
 }
 
+// sendApprovalRequest posts an approval request with Approve/Deny buttons.
+func (c *DiscordChannel) sendApprovalRequest(ctx context.Context, channelID string, msg bus.OutboundMessage) error {
+	requestID, locale := approvalMeta(msg)
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	_, err := c.session.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: msg.Content,
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    i18n.T(locale, "channel.approval.approve"),
+					Style:    discordgo.SuccessButton,
+					CustomID: approvalCallbackData(requestID, true),
+				},
+				discordgo.Button{
+					Label:    i18n.T(locale, "channel.approval.deny"),
+					Style:    discordgo.DangerButton,
+					CustomID: approvalCallbackData(requestID, false),
+				},
+			}},
+		},
+	}, discordgo.WithContext(sendCtx))
+	if err != nil {
+		return fmt.Errorf("failed to send approval request: %w", err)
+	}
+	return nil
+}
+
+// handleInteraction delivers approval button decisions and replaces the
+// buttons with the outcome.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	requestID, approved, ok := parseApprovalCallback(i.MessageComponentData().CustomID)
+	if !ok {
+		return
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil || !c.IsAllowed(user.ID) {
+		return
+	}
+	if !c.deliverApproval(user.ID, i.ChannelID, requestID, approved) {
+		_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredMessageUpdate,
+		})
+		return
+	}
+
+	locale := i18n.NormalizeLocale(string(i.Locale))
+	outcome := i18n.T(locale, "channel.approval.denied")
+	if approved {
+		outcome = i18n.T(locale, "channel.approval.approved")
+	}
+	content := outcome
+	if i.Message != nil {
+		content = i.Message.Content + "\n\n" + outcome
+	}
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: []discordgo.MessageComponent{},
+		},
+	}); err != nil {
+		logger.ErrorCF("discord", "Failed to update approval message", map[string]any{
+			"error": err.Error(),
+		})
+	}
+}
+
+// sendStream renders a partial response by editing a single message in place.
+func (c *DiscordChannel) sendStream(ctx context.Context, channelID string, msg bus.OutboundMessage) error {
+	// Replies that need splitting are left to the final message
+	if len([]rune(msg.Content)) > 1500 {
+		return nil
+	}
+
+	id, ok := c.streams.next(msg.ChatID, msg.Content)
+	if !ok {
+		return nil
+	}
+
+	if id == "" {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			sent, err := c.session.ChannelMessageSend(channelID, msg.Content)
+			if err == nil {
+				c.streams.update(msg.ChatID, sent.ID, msg.Content)
+			}
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				return fmt.Errorf("failed to send streamed message: %w", err)
+			}
+			return nil
+		case <-sendCtx.Done():
+			return fmt.Errorf("send message timeout: %w", sendCtx.Err())
+		}
+	}
+
+	if err := c.editMessage(ctx, channelID, id, msg.Content); err != nil {
+		return err
+	}
+	c.streams.update(msg.ChatID, id, msg.Content)
+	return nil
+}
+
+func (c *DiscordChannel) editMessage(ctx context.Context, channelID, messageID, content string) error {
+	editCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.session.ChannelMessageEdit(channelID, messageID, content)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to edit discord message: %w", err)
+		}
+		return nil
+	case <-editCtx.Done():
+		return fmt.Errorf("edit message timeout: %w", editCtx.Err())
+	}
+}
+
 // appendContent 安全地追加内容到现有文本
 func appendContent(content, suffix string) string {
 	if content == "" {
@@ -15,6 +15,7 @@ import (
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
 	"github.com/KarakuriAgent/clawdroid/pkg/constants"
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/redact"
 )
 
 type Manager struct {
@@ -22,6 +23,7 @@ type Manager struct {
 	bus          *bus.MessageBus
 	config       *config.Config
 	configPath   string
+	redactor     *redact.Redactor
 	dispatchTask *asyncTask
 	mu           sync.RWMutex
 }
@@ -36,6 +38,7 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus, configPath strin
 		bus:        messageBus,
 		config:     cfg,
 		configPath: configPath,
+		redactor:   redact.NewForConfig(cfg),
 	}
 
 	if err := m.initChannels(); err != nil {
@@ -216,6 +219,11 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			// Partial responses only go to channels that can render them
+			if msg.Type == "stream" && !streamingChannels[msg.Channel] {
+				continue
+			}
+
 			m.mu.RLock()
 			channel, exists := m.channels[msg.Channel]
 			m.mu.RUnlock()
@@ -227,6 +235,14 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			// Partial responses carry the whole text so far. Their tail is
+			// held back until a secret in it could be matched; redactions
+			// are logged once, with the final message.
+			if msg.Type == "stream" {
+				msg.Content, _ = m.redactor.RedactPartial(msg.Channel, msg.Content)
+			} else {
+				msg.Content = m.redact(msg.Channel, msg.Content, true)
+			}
 			if err := channel.Send(ctx, msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]interface{}{
 					"channel": msg.Channel,
@@ -293,8 +309,21 @@ func (m *Manager) SendToChannel(ctx context.Context, channelName, chatID, conten
 	msg := bus.OutboundMessage{
 		Channel: channelName,
 		ChatID:  chatID,
-		Content: content,
+		Content: m.redact(channelName, content, true),
 	}
 
 	return channel.Send(ctx, msg)
 }
+
+// redact masks secrets and personal data the channel's redaction policy
+// covers.
+func (m *Manager) redact(channel, content string, log bool) string {
+	redacted, n := m.redactor.Redact(channel, content)
+	if n > 0 && log {
+		logger.InfoCF("channels", "Redacted outbound message", map[string]interface{}{
+			"channel":    channel,
+			"redactions": n,
+		})
+	}
+	return redacted
+}
@@ -13,7 +13,9 @@ import (
 
 	"github.com/KarakuriAgent/clawdroid/pkg/bus"
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
 	"github.com/KarakuriAgent/clawdroid/pkg/utils"
 )
 
@@ -26,6 +28,8 @@ type SlackChannel struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
+	streams      *streamTracker
+	approvals    sync.Map // approval request ID -> locale
 }
 
 type slackMessageRef struct {
@@ -52,6 +56,7 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 		config:       cfg,
 		api:          api,
 		socketClient: socketClient,
+		streams:      newStreamTracker(),
 	}, nil
 }
 
@@ -110,17 +115,38 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
 
-	opts := []slack.MsgOption{
-		slack.MsgOptionText(msg.Content, false),
+	switch msg.Type {
+	case "stream":
+		return c.sendStream(ctx, channelID, threadTS, msg)
+	case "status_end":
+		c.streams.finish(msg.ChatID)
+		return nil
+	case tools.ApprovalRequestType:
+		return c.sendApprovalRequest(ctx, channelID, threadTS, msg)
 	}
 
-	if threadTS != "" {
-		opts = append(opts, slack.MsgOptionTS(threadTS))
+	// The final response or an error replaces the streamed reply
+	updated := false
+	if msg.Type == "" || msg.Type == "error" {
+		if ts, ok := c.streams.finish(msg.ChatID); ok {
+			_, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(msg.Content, false))
+			updated = err == nil
+		}
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
-	if err != nil {
-		return fmt.Errorf("failed to send slack message: %w", err)
+	if !updated {
+		opts := []slack.MsgOption{
+			slack.MsgOptionText(msg.Content, false),
+		}
+
+		if threadTS != "" {
+			opts = append(opts, slack.MsgOptionTS(threadTS))
+		}
+
+		_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to send slack message: %w", err)
+		}
 	}
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
@@ -157,11 +183,85 @@ func (c *SlackChannel) eventLoop() {
 				if event.Request != nil {
 					c.socketClient.Ack(*event.Request)
 				}
+				c.handleInteractive(event)
 			}
 		}
 	}
 }
 
+// sendApprovalRequest posts an approval request with Approve/Deny buttons.
+func (c *SlackChannel) sendApprovalRequest(ctx context.Context, channelID, threadTS string, msg bus.OutboundMessage) error {
+	requestID, locale := approvalMeta(msg)
+	c.approvals.Store(requestID, locale)
+
+	approve := slack.NewButtonBlockElement(approvalCallbackData(requestID, true), requestID,
+		slack.NewTextBlockObject(slack.PlainTextType, i18n.T(locale, "channel.approval.approve"), true, false)).
+		WithStyle(slack.StylePrimary)
+	deny := slack.NewButtonBlockElement(approvalCallbackData(requestID, false), requestID,
+		slack.NewTextBlockObject(slack.PlainTextType, i18n.T(locale, "channel.approval.deny"), true, false)).
+		WithStyle(slack.StyleDanger)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(msg.Content, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, msg.Content, false, false), nil, nil),
+			slack.NewActionBlock("approval", approve, deny),
+		),
+	}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	if _, _, err := c.api.PostMessageContext(ctx, channelID, opts...); err != nil {
+		c.approvals.Delete(requestID)
+		return fmt.Errorf("failed to send approval request: %w", err)
+	}
+	return nil
+}
+
+// handleInteractive delivers approval button decisions and replaces the
+// buttons with the outcome.
+func (c *SlackChannel) handleInteractive(event socketmode.Event) {
+	callback, ok := event.Data.(slack.InteractionCallback)
+	if !ok || callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+	if !c.IsAllowed(callback.User.ID) {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		requestID, approved, ok := parseApprovalCallback(action.ActionID)
+		if !ok {
+			continue
+		}
+		chatID := callback.Channel.ID
+		if callback.Message.ThreadTimestamp != "" {
+			chatID += "/" + callback.Message.ThreadTimestamp
+		}
+		if !c.deliverApproval(callback.User.ID, chatID, requestID, approved) {
+			continue
+		}
+
+		locale := "en"
+		if l, ok := c.approvals.LoadAndDelete(requestID); ok {
+			locale = l.(string)
+		}
+		outcome := i18n.T(locale, "channel.approval.denied")
+		if approved {
+			outcome = i18n.T(locale, "channel.approval.approved")
+		}
+		text := callback.Message.Text + "\n\n" + outcome
+		_, _, _, err := c.api.UpdateMessage(callback.Channel.ID, callback.Message.Timestamp,
+			slack.MsgOptionText(text, false),
+			slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)))
+		if err != nil {
+			logger.ErrorCF("slack", "Failed to update approval message", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
 func (c *SlackChannel) handleEventsAPI(event socketmode.Event) {
 	if event.Request != nil {
 		c.socketClient.Ack(*event.Request)
@@ -394,6 +494,36 @@ func (c *SlackChannel) stripBotMention(text string) string {
 	return strings.TrimSpace(text)
 }
 
+// sendStream renders a partial response by updating a single message in place.
+func (c *SlackChannel) sendStream(ctx context.Context, channelID, threadTS string, msg bus.OutboundMessage) error {
+	ts, ok := c.streams.next(msg.ChatID, msg.Content)
+	if !ok {
+		return nil
+	}
+
+	if ts == "" {
+		opts := []slack.MsgOption{
+			slack.MsgOptionText(msg.Content, false),
+		}
+		if threadTS != "" {
+			opts = append(opts, slack.MsgOptionTS(threadTS))
+		}
+
+		_, sentTS, err := c.api.PostMessageContext(ctx, channelID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to send streamed slack message: %w", err)
+		}
+		c.streams.update(msg.ChatID, sentTS, msg.Content)
+		return nil
+	}
+
+	if _, _, _, err := c.api.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(msg.Content, false)); err != nil {
+		return fmt.Errorf("failed to update streamed slack message: %w", err)
+	}
+	c.streams.update(msg.ChatID, ts, msg.Content)
+	return nil
+}
+
 func parseSlackChatID(chatID string) (channelID, threadTS string) {
 	parts := strings.SplitN(chatID, "/", 2)
 	channelID = parts[0]
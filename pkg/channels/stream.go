@@ -0,0 +1,72 @@
+package channels
+
+import (
+	"sync"
+	"time"
+)
+
+// streamEditInterval is the minimum delay between progressive edits of a
+// streamed reply, keeping edit-based channels within platform rate limits.
+const streamEditInterval = time.Second
+
+// streamingChannels lists the channels that can render partial ("stream")
+// messages. Other channels, including the Android clients on websocket,
+// only receive the final response.
+var streamingChannels = map[string]bool{
+	"telegram": true,
+	"discord":  true,
+	"slack":    true,
+}
+
+// streamedReply is a message that is edited in place as a response streams in.
+type streamedReply struct {
+	id       string
+	content  string
+	lastEdit time.Time
+}
+
+// streamTracker tracks in-flight streamed replies per chat for channels that
+// render partial responses by editing a single message.
+type streamTracker struct {
+	mu      sync.Mutex
+	replies map[string]*streamedReply
+}
+
+func newStreamTracker() *streamTracker {
+	return &streamTracker{replies: make(map[string]*streamedReply)}
+}
+
+// next reports whether a partial update for chatID should be rendered now.
+// It returns the ID of the message to edit, or "" when a new message must be sent.
+func (t *streamTracker) next(chatID, content string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r, ok := t.replies[chatID]
+	if !ok {
+		return "", true
+	}
+	if r.content == content || time.Since(r.lastEdit) < streamEditInterval {
+		return r.id, false
+	}
+	return r.id, true
+}
+
+// update records that the streamed reply for chatID (message id) now shows content.
+func (t *streamTracker) update(chatID, id, content string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.replies[chatID] = &streamedReply{id: id, content: content, lastEdit: time.Now()}
+}
+
+// finish stops tracking the streamed reply for chatID and returns its message ID.
+func (t *streamTracker) finish(chatID string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.replies[chatID]
+	if !ok {
+		return "", false
+	}
+	delete(t.replies, chatID)
+	return r.id, true
+}
@@ -0,0 +1,49 @@
+package channels
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamTracker_FirstUpdateSendsNewMessage(t *testing.T) {
+	tr := newStreamTracker()
+	id, ok := tr.next("chat1", "Hel")
+	if !ok || id != "" {
+		t.Errorf("next() = (%q, %v), want (\"\", true)", id, ok)
+	}
+}
+
+func TestStreamTracker_ThrottlesEdits(t *testing.T) {
+	tr := newStreamTracker()
+	tr.update("chat1", "m1", "Hel")
+
+	// Too soon after the last edit
+	if id, ok := tr.next("chat1", "Hello"); ok || id != "m1" {
+		t.Errorf("next() = (%q, %v), want (\"m1\", false)", id, ok)
+	}
+
+	tr.replies["chat1"].lastEdit = time.Now().Add(-2 * streamEditInterval)
+	if id, ok := tr.next("chat1", "Hello"); !ok || id != "m1" {
+		t.Errorf("next() = (%q, %v), want (\"m1\", true)", id, ok)
+	}
+	// Unchanged content is never re-rendered
+	if _, ok := tr.next("chat1", "Hel"); ok {
+		t.Error("next() should skip unchanged content")
+	}
+}
+
+func TestStreamTracker_Finish(t *testing.T) {
+	tr := newStreamTracker()
+	tr.update("chat1", "m1", "Hello")
+
+	id, ok := tr.finish("chat1")
+	if !ok || id != "m1" {
+		t.Errorf("finish() = (%q, %v), want (\"m1\", true)", id, ok)
+	}
+	if _, ok := tr.finish("chat1"); ok {
+		t.Error("finish() should report nothing after the reply was finished")
+	}
+	if id, ok := tr.next("chat1", "Next"); !ok || id != "" {
+		t.Errorf("next() after finish = (%q, %v), want (\"\", true)", id, ok)
+	}
+}
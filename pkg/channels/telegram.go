@@ -7,9 +7,11 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/mymmrac/telego"
 	th "github.com/mymmrac/telego/telegohandler"
@@ -19,6 +21,7 @@ import (
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
 	"github.com/KarakuriAgent/clawdroid/pkg/i18n"
 	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/KarakuriAgent/clawdroid/pkg/tools"
 	"github.com/KarakuriAgent/clawdroid/pkg/utils"
 )
 
@@ -30,8 +33,12 @@ type TelegramChannel struct {
 	chatIDs      map[string]int64
 	placeholders sync.Map // chatID -> messageID
 	stopThinking sync.Map // chatID -> thinkingCancel
+	streams      *streamTracker
 }
 
+// telegramMaxMessageLength is the Telegram limit for a single text message.
+const telegramMaxMessageLength = 4096
+
 type thinkingCancel struct {
 	fn context.CancelFunc
 }
@@ -73,6 +80,7 @@ func NewTelegramChannel(cfg *config.Config, bus *bus.MessageBus) (*TelegramChann
 		chatIDs:      make(map[string]int64),
 		placeholders: sync.Map{},
 		stopThinking: sync.Map{},
+		streams:      newStreamTracker(),
 	}, nil
 }
 
@@ -98,14 +106,25 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.commands.Start(ctx, message)
 	}, th.CommandEqual("start"))
 
+	// Model commands depend on the chat's session, so the agent answers them.
 	bh.HandleMessage(func(ctx *th.Context, message telego.Message) error {
+		if commandArgs(message.Text) == "model" {
+			return c.handleMessage(ctx, &message)
+		}
 		return c.commands.Show(ctx, message)
 	}, th.CommandEqual("show"))
 
 	bh.HandleMessage(func(ctx *th.Context, message telego.Message) error {
+		if commandArgs(message.Text) == "models" {
+			return c.handleMessage(ctx, &message)
+		}
 		return c.commands.List(ctx, message)
 	}, th.CommandEqual("list"))
 
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		return c.handleApprovalCallback(ctx, query)
+	}, th.CallbackDataPrefix(approvalCallbackPrefix))
+
 	bh.HandleMessage(func(ctx *th.Context, message telego.Message) error {
 		return c.handleMessage(ctx, &message)
 	}, th.AnyMessage())
@@ -140,20 +159,32 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
-	// Stop thinking animation
-	if stop, ok := c.stopThinking.Load(msg.ChatID); ok {
-		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
-			cf.Cancel()
-		}
-		c.stopThinking.Delete(msg.ChatID)
+	switch msg.Type {
+	case "stream":
+		return c.sendStream(ctx, chatID, msg)
+	case "status_end":
+		c.streams.finish(msg.ChatID)
+		return nil
+	case tools.ApprovalRequestType:
+		return c.sendApprovalRequest(ctx, chatID, msg)
 	}
 
+	c.stopThinkingAnimation(msg.ChatID)
+
 	htmlContent := markdownToTelegramHTML(msg.Content)
 
-	// Try to edit placeholder
-	if pID, ok := c.placeholders.Load(msg.ChatID); ok {
-		c.placeholders.Delete(msg.ChatID)
-		editMsg := tu.EditMessageText(tu.ID(chatID), pID.(int), htmlContent)
+	// Replace the streamed reply (final response or error) or the placeholder
+	editID := 0
+	if msg.Type == "" || msg.Type == "error" {
+		if id, ok := c.streams.finish(msg.ChatID); ok {
+			editID, _ = strconv.Atoi(id)
+		}
+	}
+	if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok && editID == 0 {
+		editID = pID.(int)
+	}
+	if editID != 0 {
+		editMsg := tu.EditMessageText(tu.ID(chatID), editID, htmlContent)
 		editMsg.ParseMode = telego.ModeHTML
 
 		if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
@@ -177,6 +208,111 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	return nil
 }
 
+// sendStream renders a partial response by editing a single message in place.
+// Partial text is sent without HTML formatting because unfinished markdown
+// may not convert to valid markup; the final response restores formatting.
+func (c *TelegramChannel) sendStream(ctx context.Context, chatID int64, msg bus.OutboundMessage) error {
+	// Oversized replies are left to the final message
+	if utf8.RuneCountInString(msg.Content) > telegramMaxMessageLength {
+		return nil
+	}
+
+	id, ok := c.streams.next(msg.ChatID, msg.Content)
+	if !ok {
+		return nil
+	}
+
+	c.stopThinkingAnimation(msg.ChatID)
+
+	// The first partial takes over the "thinking" placeholder
+	if id == "" {
+		if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok {
+			id = strconv.Itoa(pID.(int))
+		}
+	}
+
+	if id == "" {
+		sent, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(chatID), msg.Content))
+		if err != nil {
+			return fmt.Errorf("failed to send streamed message: %w", err)
+		}
+		c.streams.update(msg.ChatID, strconv.Itoa(sent.MessageID), msg.Content)
+		return nil
+	}
+
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid streamed message ID %q: %w", id, err)
+	}
+	if _, err := c.bot.EditMessageText(ctx, tu.EditMessageText(tu.ID(chatID), messageID, msg.Content)); err != nil {
+		return fmt.Errorf("failed to edit streamed message: %w", err)
+	}
+	c.streams.update(msg.ChatID, id, msg.Content)
+	return nil
+}
+
+// sendApprovalRequest posts an approval request with Approve/Deny buttons.
+func (c *TelegramChannel) sendApprovalRequest(ctx context.Context, chatID int64, msg bus.OutboundMessage) error {
+	requestID, locale := approvalMeta(msg)
+	keyboard := tu.InlineKeyboard(tu.InlineKeyboardRow(
+		tu.InlineKeyboardButton(i18n.T(locale, "channel.approval.approve")).WithCallbackData(approvalCallbackData(requestID, true)),
+		tu.InlineKeyboardButton(i18n.T(locale, "channel.approval.deny")).WithCallbackData(approvalCallbackData(requestID, false)),
+	))
+
+	tgMsg := tu.Message(tu.ID(chatID), markdownToTelegramHTML(msg.Content)).WithReplyMarkup(keyboard)
+	tgMsg.ParseMode = telego.ModeHTML
+	if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
+		tgMsg.Text = msg.Content
+		tgMsg.ParseMode = ""
+		if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
+			return fmt.Errorf("failed to send approval request: %w", err)
+		}
+	}
+	return nil
+}
+
+// handleApprovalCallback delivers a button decision and replaces the buttons
+// with the outcome.
+func (c *TelegramChannel) handleApprovalCallback(ctx context.Context, query telego.CallbackQuery) error {
+	senderID := fmt.Sprintf("%d", query.From.ID)
+	if query.From.Username != "" {
+		senderID = fmt.Sprintf("%d|%s", query.From.ID, query.From.Username)
+	}
+	if !c.IsAllowed(senderID) {
+		return c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+	}
+
+	requestID, approved, ok := parseApprovalCallback(query.Data)
+	if !ok || query.Message == nil {
+		return c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+	}
+	chatID := fmt.Sprintf("%d", query.Message.GetChat().ID)
+	if !c.deliverApproval(senderID, chatID, requestID, approved) {
+		return c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID))
+	}
+
+	locale := i18n.NormalizeLocale(query.From.LanguageCode)
+	outcome := i18n.T(locale, "channel.approval.denied")
+	if approved {
+		outcome = i18n.T(locale, "channel.approval.approved")
+	}
+	if query.Message != nil && query.Message.IsAccessible() {
+		m := query.Message.Message()
+		// Editing without a reply markup removes the buttons
+		_, _ = c.bot.EditMessageText(ctx, tu.EditMessageText(m.Chat.ChatID(), m.MessageID, m.Text+"\n\n"+outcome))
+	}
+	return c.bot.AnswerCallbackQuery(ctx, tu.CallbackQuery(query.ID).WithText(outcome))
+}
+
+// stopThinkingAnimation cancels the thinking indicator for chatID, if any.
+func (c *TelegramChannel) stopThinkingAnimation(chatID string) {
+	if stop, ok := c.stopThinking.LoadAndDelete(chatID); ok {
+		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
+			cf.Cancel()
+		}
+	}
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message) error {
 	if message == nil {
 		return fmt.Errorf("message is nil")
@@ -200,6 +336,11 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		return nil
 	}
 
+	// Typed approval replies skip the thinking placeholder
+	if c.handleApprovalReply(senderID, fmt.Sprintf("%d", message.Chat.ID), message.Text) {
+		return nil
+	}
+
 	chatID := message.Chat.ID
 	c.chatIDs[senderID] = chatID
 
@@ -75,8 +75,6 @@ func (c *cmd) Show(ctx context.Context, message telego.Message) error {
 
 	var response string
 	switch args {
-	case "model":
-		response = i18n.Tf(locale, "cmd.show.model", c.config.LLM.Model)
 	case "channel":
 		response = i18n.T(locale, "cmd.show.channel")
 	default:
@@ -108,9 +106,6 @@ func (c *cmd) List(ctx context.Context, message telego.Message) error {
 
 	var response string
 	switch args {
-	case "models":
-		response = i18n.Tf(locale, "cmd.list.models", c.config.LLM.Model)
-
 	case "channels":
 		var enabled []string
 		if c.config.Channels.Telegram.Enabled {
@@ -25,14 +25,15 @@ type wsIncoming struct {
 	SenderID  string   `json:"sender_id,omitempty"`
 	Images    []string `json:"images,omitempty"`
 	InputMode string   `json:"input_mode,omitempty"`
-	Type      string   `json:"type,omitempty"`       // "tool_response" for device tool responses
-	RequestID string   `json:"request_id,omitempty"` // correlates with tool_request
+	Type      string   `json:"type,omitempty"`       // "tool_response" for device tool responses, "approval_response" for approval decisions
+	RequestID string   `json:"request_id,omitempty"` // correlates with tool_request or approval_request
 }
 
 // wsOutgoing is the JSON message sent from clawdroid to APK.
 type wsOutgoing struct {
-	Content string `json:"content"`
-	Type    string `json:"type,omitempty"`
+	Content   string `json:"content"`
+	Type      string `json:"type,omitempty"`
+	RequestID string `json:"request_id,omitempty"` // set on approval_request
 }
 
 // WebSocketChannel is a server-side WebSocket channel that accepts
@@ -148,7 +149,7 @@ func (c *WebSocketChannel) Send(ctx context.Context, msg bus.OutboundMessage) er
 		return c.maybeBroadcast(msg, clientType, fmt.Errorf("no connection for chat %s", msg.ChatID))
 	}
 
-	out := wsOutgoing{Content: msg.Content, Type: msg.Type}
+	out := wsOutgoing{Content: msg.Content, Type: msg.Type, RequestID: msg.Metadata["request_id"]}
 	data, err := json.Marshal(out)
 	if err != nil {
 		return fmt.Errorf("failed to marshal response: %w", err)
@@ -174,12 +175,12 @@ func (c *WebSocketChannel) Send(ctx context.Context, msg bus.OutboundMessage) er
 }
 
 // maybeBroadcast sends a message via Android broadcast if the disconnected
-// client is of type "main". Status/status_end messages are ephemeral and
-// skipped. Returns the original error if broadcast is not applicable.
+// client is of type "main". Status/status_end/stream messages are ephemeral
+// and skipped. Returns the original error if broadcast is not applicable.
 // clientType must be read under lock by the caller to avoid data races.
 func (c *WebSocketChannel) maybeBroadcast(msg bus.OutboundMessage, clientType string, originalErr error) error {
-	// Status messages are ephemeral — don't broadcast.
-	if msg.Type == "status" || msg.Type == "status_end" {
+	// Status and partial stream messages are ephemeral — don't broadcast.
+	if msg.Type == "status" || msg.Type == "status_end" || msg.Type == "stream" {
 		return originalErr
 	}
 
@@ -333,6 +334,14 @@ func (c *WebSocketChannel) readPump(conn *websocket.Conn, clientID, chatID, clie
 			senderID = incoming.SenderID
 		}
 
+		// Intercept approval_response messages ("approve" or "deny") the same way.
+		if incoming.Type == "approval_response" && incoming.RequestID != "" {
+			if c.IsAllowed(senderID) {
+				c.deliverApproval(senderID, chatID, incoming.RequestID, incoming.Content == "approve")
+			}
+			continue
+		}
+
 		content := incoming.Content
 		var media []string
 
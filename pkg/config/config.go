@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/caarlos0/env/v11"
@@ -44,9 +46,111 @@ func (f *FlexibleStringSlice) UnmarshalJSON(data []byte) error {
 }
 
 type LLMConfig struct {
-	Model   string `json:"model" label:"Model" env:"CLAWDROID_LLM_MODEL"`
-	APIKey  string `json:"api_key" label:"API Key" env:"CLAWDROID_LLM_API_KEY"`
-	BaseURL string `json:"base_url" label:"Base URL" env:"CLAWDROID_LLM_BASE_URL"`
+	Model     string                      `json:"model" label:"Model" env:"CLAWDROID_LLM_MODEL"`
+	APIKey    string                      `json:"api_key" label:"API Key" env:"CLAWDROID_LLM_API_KEY"`
+	BaseURL   string                      `json:"base_url" label:"Base URL" env:"CLAWDROID_LLM_BASE_URL"`
+	Fallbacks []LLMFallbackConfig         `json:"fallbacks,omitempty" label:"Fallback Models" envPrefix:"CLAWDROID_LLM_FALLBACKS_"`
+	Profiles  map[string]LLMProfileConfig `json:"profiles,omitempty" label:"Model Profiles"`
+	Roles     LLMRolesConfig              `json:"roles" label:"Model Roles"`
+
+	// Record/replay of LLM calls for offline testing (not shown in the UI).
+	CassetteMode string `json:"cassette_mode,omitempty" env:"CLAWDROID_LLM_CASSETTE_MODE"` // "record", "replay", or "" (off)
+	Cassette     string `json:"cassette,omitempty" env:"CLAWDROID_LLM_CASSETTE"`           // JSONL cassette path
+}
+
+// Models returns the configured model names, primary first, without duplicates.
+func (c LLMConfig) Models() []string {
+	var models []string
+	seen := make(map[string]bool)
+	add := func(m string) {
+		if m != "" && !seen[m] {
+			seen[m] = true
+			models = append(models, m)
+		}
+	}
+	add(c.Model)
+	for _, fb := range c.Fallbacks {
+		add(fb.Model)
+	}
+	for _, name := range c.ProfileNames() {
+		add(c.Profiles[name].Model)
+	}
+	return models
+}
+
+// ProfileNames returns the configured profile names in sorted order.
+func (c LLMConfig) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile. An empty name selects the default
+// model. ok is false when a non-empty name is not configured, in which case
+// the default model is returned.
+func (c LLMConfig) Profile(name string) (profile LLMProfileConfig, ok bool) {
+	if p, found := c.Profiles[name]; found && p.Model != "" {
+		return p, true
+	}
+	return LLMProfileConfig{Model: c.Model, APIKey: c.APIKey, BaseURL: c.BaseURL}, name == ""
+}
+
+// LLMFallbackConfig is a model tried, in order, when the primary model fails.
+// Environment variables are indexed from 0, e.g. CLAWDROID_LLM_FALLBACKS_0_MODEL.
+type LLMFallbackConfig struct {
+	Model   string `json:"model" env:"MODEL"`
+	APIKey  string `json:"api_key" env:"API_KEY"`
+	BaseURL string `json:"base_url" env:"BASE_URL"`
+}
+
+// LLMProfileConfig is a named model with its own credentials and sampling
+// settings. A zero MaxTokens and an unset Temperature inherit the caller's
+// defaults; a temperature of 0 is sent as is. The API key can be set with
+// CLAWDROID_LLM_PROFILES_<NAME>_API_KEY (the name upper-cased, other
+// characters than letters and digits replaced by "_").
+type LLMProfileConfig struct {
+	Model       string   `json:"model"`
+	APIKey      string   `json:"api_key"`
+	BaseURL     string   `json:"base_url"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// ProfileEnvName returns the environment variable that sets the API key of
+// the named profile.
+func ProfileEnvName(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToUpper(name) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return "CLAWDROID_LLM_PROFILES_" + sb.String() + "_API_KEY"
+}
+
+// applyProfileEnv sets the API keys of configured profiles from the
+// environment. env.Parse cannot reach into the profiles map.
+func (c *LLMConfig) applyProfileEnv() {
+	for name, p := range c.Profiles {
+		if key, ok := os.LookupEnv(ProfileEnvName(name)); ok {
+			p.APIKey = key
+			c.Profiles[name] = p
+		}
+	}
+}
+
+// LLMRolesConfig assigns a profile to each kind of LLM call.
+// An empty name uses the default model.
+type LLMRolesConfig struct {
+	Chat       string `json:"chat" label:"Chat Profile" env:"CLAWDROID_LLM_ROLES_CHAT"`
+	Summarizer string `json:"summarizer" label:"Summarizer Profile" env:"CLAWDROID_LLM_ROLES_SUMMARIZER"`
+	Subagent   string `json:"subagent" label:"Subagent Profile" env:"CLAWDROID_LLM_ROLES_SUBAGENT"`
+	Heartbeat  string `json:"heartbeat" label:"Heartbeat Profile" env:"CLAWDROID_LLM_ROLES_HEARTBEAT"`
 }
 
 type Config struct {
@@ -58,11 +162,35 @@ type Config struct {
 	Tools      ToolsConfig      `json:"tools" label:"Tool Settings"`
 	Heartbeat  HeartbeatConfig  `json:"heartbeat" label:"Heartbeat"`
 	RateLimits RateLimitsConfig `json:"rate_limits" label:"Rate Limits"`
+	Usage      UsageConfig      `json:"usage" label:"Usage & Budgets"`
+	Traces     TracesConfig     `json:"traces" label:"Traces"`
+	Redaction  RedactionConfig  `json:"redaction" label:"Redaction"`
 	mu         sync.RWMutex
 }
 
 type AgentsConfig struct {
-	Defaults AgentDefaults `json:"defaults" label:"Defaults"`
+	Defaults AgentDefaults            `json:"defaults" label:"Defaults"`
+	Personas map[string]PersonaConfig `json:"personas,omitempty" label:"Personas"`
+	Routes   []PersonaRouteConfig     `json:"routes,omitempty" label:"Persona Routes"`
+}
+
+// PersonaConfig is a named agent with its own bootstrap files, tools, model
+// and skills. Empty fields use the default agent's settings.
+type PersonaConfig struct {
+	Dir     string   `json:"dir,omitempty"`     // AGENT.md/SOUL.md/IDENTITY.md directory (default <data_dir>/personas/<name>)
+	Profile string   `json:"profile,omitempty"` // LLM profile for chat (default llm.roles.chat)
+	Tools   []string `json:"tools,omitempty"`   // Allowed tool names (empty = all)
+	Skills  []string `json:"skills,omitempty"`  // Skills offered in the prompt (empty = all)
+}
+
+// PersonaRouteConfig maps messages to a persona. Every non-empty field must
+// match; the first matching route wins, and unmatched messages use the
+// default agent.
+type PersonaRouteConfig struct {
+	Persona string `json:"persona"`
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chat_id,omitempty"`
+	User    string `json:"user,omitempty"` // User directory ID or name, or channel sender ID
 }
 
 type AgentDefaults struct {
@@ -74,8 +202,11 @@ type AgentDefaults struct {
 	Temperature         float64 `json:"temperature" label:"Temperature" env:"CLAWDROID_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int     `json:"max_tool_iterations" label:"Max Tool Iterations" env:"CLAWDROID_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
 	QueueMessages       bool    `json:"queue_messages" label:"Queue Messages" env:"CLAWDROID_AGENTS_DEFAULTS_QUEUE_MESSAGES"`
+	SteerMessages       bool    `json:"steer_messages" label:"Steer Running Turn" env:"CLAWDROID_AGENTS_DEFAULTS_STEER_MESSAGES"`
 	ShowErrors          bool    `json:"show_errors" label:"Show Errors" env:"CLAWDROID_AGENTS_DEFAULTS_SHOW_ERRORS"`
 	ShowWarnings        bool    `json:"show_warnings" label:"Show Warnings" env:"CLAWDROID_AGENTS_DEFAULTS_SHOW_WARNINGS"`
+	Streaming           bool    `json:"streaming" label:"Stream Responses" env:"CLAWDROID_AGENTS_DEFAULTS_STREAMING"`
+	MaxParallelTools    int     `json:"max_parallel_tools" label:"Max Parallel Tools" env:"CLAWDROID_AGENTS_DEFAULTS_MAX_PARALLEL_TOOLS"`
 }
 
 type ChannelsConfig struct {
@@ -142,6 +273,47 @@ type RateLimitsConfig struct {
 	MaxRequestsPerMinute  int `json:"max_requests_per_minute" label:"Max Requests Per Minute" env:"CLAWDROID_RATE_LIMITS_MAX_REQUESTS_PER_MINUTE"`       // 0 = unlimited
 }
 
+// UsageConfig sets the price table used for cost accounting and the daily
+// spend budgets. Budgets are in the price table's currency; 0 disables them.
+type UsageConfig struct {
+	Prices          map[string]ModelPrice `json:"prices,omitempty" label:"Model Prices"`
+	DailySoftBudget float64               `json:"daily_soft_budget" label:"Daily Soft Budget" env:"CLAWDROID_USAGE_DAILY_SOFT_BUDGET"` // downgrade to BudgetProfile once reached
+	DailyHardBudget float64               `json:"daily_hard_budget" label:"Daily Hard Budget" env:"CLAWDROID_USAGE_DAILY_HARD_BUDGET"` // refuse LLM calls once reached
+	BudgetProfile   string                `json:"budget_profile" label:"Budget Profile" env:"CLAWDROID_USAGE_BUDGET_PROFILE"`
+}
+
+// TracesConfig controls the execution traces written for each processed
+// message.
+type TracesConfig struct {
+	Enabled      bool   `json:"enabled" label:"Enabled" env:"CLAWDROID_TRACES_ENABLED"`
+	MaxTraces    int    `json:"max_traces" label:"Max Stored Traces" env:"CLAWDROID_TRACES_MAX_TRACES"`   // oldest are deleted beyond this
+	OTLPEndpoint string `json:"otlp_endpoint" label:"OTLP Endpoint" env:"CLAWDROID_TRACES_OTLP_ENDPOINT"` // OTLP/HTTP JSON traces URL; empty disables export
+}
+
+// RedactionConfig masks secrets and personal data in messages sent to
+// channels and in tool results saved to session history. Detectors are
+// "api_key", "email", "phone", "config_secret", or a name from Patterns.
+type RedactionConfig struct {
+	Enabled     bool                              `json:"enabled" label:"Enabled" env:"CLAWDROID_REDACTION_ENABLED"`
+	Detectors   FlexibleStringSlice               `json:"detectors" label:"Detectors" env:"CLAWDROID_REDACTION_DETECTORS"`
+	ToolResults bool                              `json:"tool_results" label:"Redact Tool Results" env:"CLAWDROID_REDACTION_TOOL_RESULTS"`
+	Patterns    map[string]string                 `json:"patterns,omitempty" label:""` // Custom detectors: name -> regular expression
+	Channels    map[string]RedactionChannelConfig `json:"channels,omitempty" label:""`
+}
+
+// RedactionChannelConfig adjusts the detectors for one channel.
+type RedactionChannelConfig struct {
+	Detectors FlexibleStringSlice `json:"detectors,omitempty"` // Additional detectors
+	Exempt    FlexibleStringSlice `json:"exempt,omitempty"`    // Detectors skipped on this channel ("*" = all)
+}
+
+// ModelPrice is the price per million tokens, keyed by "provider/model_name"
+// (or the bare model name) in UsageConfig.Prices.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
 type GatewayConfig struct {
 	Port   int    `json:"port" label:"Port" env:"CLAWDROID_GATEWAY_PORT"`
 	APIKey string `json:"api_key" label:"API Key" env:"CLAWDROID_GATEWAY_API_KEY"`
@@ -163,8 +335,50 @@ type WebToolsConfig struct {
 	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo" label:"DuckDuckGo"`
 }
 
+// ExecToolsConfig is the policy of the exec tool, shared by the main agent,
+// subagents, the process tool and cron jobs. Patterns are regular
+// expressions matched against the lowercased command; environment entries
+// are variable names or globs such as "LC_*".
 type ExecToolsConfig struct {
-	Enabled bool `json:"enabled" label:"Enabled" env:"CLAWDROID_TOOLS_EXEC_ENABLED"`
+	Enabled        bool                `json:"enabled" label:"Enabled" env:"CLAWDROID_TOOLS_EXEC_ENABLED"`
+	Timeout        int                 `json:"timeout" label:"Timeout (seconds)" env:"CLAWDROID_TOOLS_EXEC_TIMEOUT"`
+	AllowPatterns  FlexibleStringSlice `json:"allow_patterns" label:"Allowed Patterns" env:"CLAWDROID_TOOLS_EXEC_ALLOW_PATTERNS"`
+	DenyPatterns   FlexibleStringSlice `json:"deny_patterns" label:"Denied Patterns" env:"CLAWDROID_TOOLS_EXEC_DENY_PATTERNS"`
+	EnvPassthrough FlexibleStringSlice `json:"env_passthrough" label:"Passed Environment" env:"CLAWDROID_TOOLS_EXEC_ENV_PASSTHROUGH"`
+	EnvScrub       FlexibleStringSlice `json:"env_scrub" label:"Scrubbed Environment" env:"CLAWDROID_TOOLS_EXEC_ENV_SCRUB"`
+	MaxOutput      int                 `json:"max_output" label:"Max Output (characters)" env:"CLAWDROID_TOOLS_EXEC_MAX_OUTPUT"`
+	Shell          string              `json:"shell" label:"Shell" env:"CLAWDROID_TOOLS_EXEC_SHELL"`
+	ProcessTimeout int                 `json:"process_timeout" label:"Background Process Timeout (seconds)" env:"CLAWDROID_TOOLS_EXEC_PROCESS_TIMEOUT"`
+	Sandbox        ExecSandboxConfig   `json:"sandbox" label:"Sandbox"`
+}
+
+// ExecSandboxConfig runs exec commands isolated from the host (Linux only).
+// Backend is "auto" (bwrap when installed, otherwise namespaces),
+// "namespaces" or "bwrap".
+type ExecSandboxConfig struct {
+	Enabled       bool                `json:"enabled" label:"Enabled" env:"CLAWDROID_TOOLS_EXEC_SANDBOX_ENABLED"`
+	Backend       string              `json:"backend" label:"Backend" env:"CLAWDROID_TOOLS_EXEC_SANDBOX_BACKEND"`
+	Network       bool                `json:"network" label:"Allow Network" env:"CLAWDROID_TOOLS_EXEC_SANDBOX_NETWORK"`
+	CPUSeconds    int                 `json:"cpu_seconds" label:"CPU Limit (seconds)" env:"CLAWDROID_TOOLS_EXEC_SANDBOX_CPU_SECONDS"`
+	MemoryMB      int                 `json:"memory_mb" label:"Memory Limit (MB)" env:"CLAWDROID_TOOLS_EXEC_SANDBOX_MEMORY_MB"`
+	ReadOnlyPaths FlexibleStringSlice `json:"read_only_paths,omitempty" label:""`
+	WritablePaths FlexibleStringSlice `json:"writable_paths,omitempty" label:""`
+}
+
+// ApprovalConfig makes sensitive tool calls wait for the user's confirmation.
+// Rules name a tool ("exec") or a tool action ("android:dial").
+type ApprovalConfig struct {
+	Enabled bool                          `json:"enabled" label:"Enabled" env:"CLAWDROID_TOOLS_APPROVAL_ENABLED"`
+	Timeout int                           `json:"timeout" label:"Timeout (seconds)" env:"CLAWDROID_TOOLS_APPROVAL_TIMEOUT"`
+	Require FlexibleStringSlice           `json:"require" label:"Require Approval" env:"CLAWDROID_TOOLS_APPROVAL_REQUIRE"`
+	Users   map[string]ApprovalUserConfig `json:"users,omitempty" label:""`
+}
+
+// ApprovalUserConfig adjusts the approval rules for one user, keyed by user
+// directory ID or channel sender ID.
+type ApprovalUserConfig struct {
+	Require FlexibleStringSlice `json:"require,omitempty"` // Additional rules
+	Exempt  FlexibleStringSlice `json:"exempt,omitempty"`  // Rules skipped for this user ("*" = all)
 }
 
 type MCPServerConfig struct {
@@ -358,11 +572,22 @@ type MemoryToolsConfig struct {
 }
 
 type ToolsConfig struct {
-	Web     WebToolsConfig             `json:"web" label:"Web Search"`
-	Exec    ExecToolsConfig            `json:"exec" label:"Shell Exec"`
-	Android AndroidToolsConfig         `json:"android" label:"Android"`
-	Memory  MemoryToolsConfig          `json:"memory" label:"Memory"`
-	MCP     map[string]MCPServerConfig `json:"mcp,omitempty" label:"MCP Servers"`
+	Web      WebToolsConfig             `json:"web" label:"Web Search"`
+	Exec     ExecToolsConfig            `json:"exec" label:"Shell Exec"`
+	Android  AndroidToolsConfig         `json:"android" label:"Android"`
+	Memory   MemoryToolsConfig          `json:"memory" label:"Memory"`
+	MCP      map[string]MCPServerConfig `json:"mcp,omitempty" label:"MCP Servers"`
+	Approval ApprovalConfig             `json:"approval" label:"Approval"`
+	Hooks    []ToolHookConfig           `json:"hooks,omitempty" label:"Tool Hooks"`
+}
+
+// ToolHookConfig runs a command before or after matching tool calls. The
+// command gets the call as JSON on stdin and may print a decision as JSON.
+type ToolHookConfig struct {
+	Tool    string   `json:"tool"`              // Tool name, tool:action, or "*"
+	Stage   string   `json:"stage"`             // "pre" or "post"
+	Command []string `json:"command"`           // Program and arguments, run in the workspace
+	Timeout int      `json:"timeout,omitempty"` // Seconds (default 10)
 }
 
 func DefaultConfig() *Config {
@@ -382,6 +607,8 @@ func DefaultConfig() *Config {
 				MaxToolIterations:   10,
 				ShowErrors:          true,
 				ShowWarnings:        true,
+				Streaming:           true,
+				MaxParallelTools:    4,
 			},
 		},
 		Channels: ChannelsConfig{
@@ -428,12 +655,37 @@ func DefaultConfig() *Config {
 		},
 		Tools: ToolsConfig{
 			Exec: ExecToolsConfig{
-				Enabled: false,
+				Enabled:        false,
+				Timeout:        60,
+				AllowPatterns:  FlexibleStringSlice{},
+				DenyPatterns:   FlexibleStringSlice{},
+				EnvPassthrough: FlexibleStringSlice{},
+				EnvScrub: FlexibleStringSlice{
+					"CLAWDROID_*", "*_API_KEY", "*_TOKEN", "*_SECRET", "*PASSWORD*",
+				},
+				MaxOutput:      10000,
+				Shell:          "",
+				ProcessTimeout: 3600,
+				Sandbox: ExecSandboxConfig{
+					Enabled:    false,
+					Backend:    "auto",
+					CPUSeconds: 60,
+					MemoryMB:   1024,
+				},
 			},
 			Android: DefaultAndroidToolsConfig(),
 			Memory: MemoryToolsConfig{
 				Enabled: true,
 			},
+			Approval: ApprovalConfig{
+				Enabled: false,
+				Timeout: 120,
+				Require: FlexibleStringSlice{
+					"exec", "write_file",
+					"android:compose_sms", "android:dial", "android:delete_event",
+					"mcp:mcp_call",
+				},
+			},
 			Web: WebToolsConfig{
 				Brave: BraveConfig{
 					Enabled:    false,
@@ -454,6 +706,20 @@ func DefaultConfig() *Config {
 			MaxToolCallsPerMinute: 30,
 			MaxRequestsPerMinute:  15,
 		},
+		Traces: TracesConfig{
+			Enabled:   false,
+			MaxTraces: 500,
+		},
+		Redaction: RedactionConfig{
+			Enabled:     true,
+			Detectors:   FlexibleStringSlice{"api_key", "config_secret"},
+			ToolResults: true,
+			Channels: map[string]RedactionChannelConfig{
+				// Shared servers: also hide contact details
+				"discord": {Detectors: FlexibleStringSlice{"email", "phone"}},
+				"slack":   {Detectors: FlexibleStringSlice{"email", "phone"}},
+			},
+		},
 	}
 }
 
@@ -476,6 +742,7 @@ func LoadConfig(path string) (*Config, error) {
 	if err := env.Parse(cfg); err != nil {
 		return nil, err
 	}
+	cfg.LLM.applyProfileEnv()
 
 	if migrateConfig(cfg) {
 		_ = saveConfigLocked(path, cfg)
@@ -526,6 +793,66 @@ func (c *Config) CopyFrom(src *Config) {
 	c.Tools = src.Tools
 	c.Heartbeat = src.Heartbeat
 	c.RateLimits = src.RateLimits
+	c.Usage = src.Usage
+	c.Traces = src.Traces
+	c.Redaction = src.Redaction
+}
+
+// Secrets returns the credentials set in the config (API keys, tokens and
+// channel secrets), so they can be kept out of messages.
+func (c *Config) Secrets() []string {
+	secrets := []string{
+		c.LLM.APIKey,
+		c.Channels.Telegram.Token,
+		c.Channels.Discord.Token,
+		c.Channels.Slack.BotToken,
+		c.Channels.Slack.AppToken,
+		c.Channels.LINE.ChannelSecret,
+		c.Channels.LINE.ChannelAccessToken,
+		c.Channels.WebSocket.APIKey,
+		c.Gateway.APIKey,
+		c.Tools.Web.Brave.APIKey,
+	}
+	for _, fb := range c.LLM.Fallbacks {
+		secrets = append(secrets, fb.APIKey)
+	}
+	for _, name := range c.LLM.ProfileNames() {
+		secrets = append(secrets, c.LLM.Profiles[name].APIKey)
+	}
+	for _, server := range c.Tools.MCP {
+		for k, v := range server.Env {
+			if isSecretName(k) {
+				secrets = append(secrets, v)
+			}
+		}
+		for k, v := range server.Headers {
+			if isSecretName(k) {
+				secrets = append(secrets, strings.TrimPrefix(v, "Bearer "))
+			}
+		}
+	}
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, s := range secrets {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// isSecretName reports whether an environment variable or header name
+// suggests a credential.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, hint := range []string{"key", "token", "secret", "password", "auth"} {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Config) WorkspacePath() string {
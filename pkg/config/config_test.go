@@ -369,6 +369,80 @@ func TestLoadConfig_ValidFile(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_FallbackModels(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.json")
+
+	data := `{"llm":{"model":"openai/gpt-4","fallbacks":[{"model":"anthropic/claude-test","api_key":"sk-file"}]}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Indices are contiguous from 0; env values override the file per entry
+	t.Setenv("CLAWDROID_LLM_FALLBACKS_0_API_KEY", "sk-env")
+	t.Setenv("CLAWDROID_LLM_FALLBACKS_1_MODEL", "gemini/gemini-test")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.LLM.Fallbacks) != 2 {
+		t.Fatalf("len(Fallbacks) = %d, want 2", len(cfg.LLM.Fallbacks))
+	}
+	if fb := cfg.LLM.Fallbacks[0]; fb.Model != "anthropic/claude-test" || fb.APIKey != "sk-env" {
+		t.Errorf("Fallbacks[0] = %+v", fb)
+	}
+	if got := cfg.LLM.Fallbacks[1].Model; got != "gemini/gemini-test" {
+		t.Errorf("Fallbacks[1].Model = %q, want %q", got, "gemini/gemini-test")
+	}
+}
+
+func TestLoadConfig_ModelProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.json")
+
+	data := `{"llm":{"model":"anthropic/claude-strong","api_key":"sk-main",
+		"profiles":{"cheap":{"model":"gemini/gemini-flash","api_key":"g-key","max_tokens":512},
+			"exact-v2":{"model":"openai/gpt-test","temperature":0}},
+		"roles":{"summarizer":"cheap"}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLAWDROID_LLM_ROLES_HEARTBEAT", "cheap")
+	t.Setenv("CLAWDROID_LLM_PROFILES_EXACT_V2_API_KEY", "sk-env")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.LLM.Roles.Summarizer != "cheap" || cfg.LLM.Roles.Heartbeat != "cheap" {
+		t.Errorf("Roles = %+v", cfg.LLM.Roles)
+	}
+
+	p, ok := cfg.LLM.Profile(cfg.LLM.Roles.Summarizer)
+	if !ok || p.Model != "gemini/gemini-flash" || p.APIKey != "g-key" || p.MaxTokens != 512 {
+		t.Errorf("Profile(cheap) = %+v, %v", p, ok)
+	}
+	if p, ok := cfg.LLM.Profile(""); !ok || p.Model != "anthropic/claude-strong" || p.APIKey != "sk-main" {
+		t.Errorf("Profile(\"\") = %+v, %v, want the default model", p, ok)
+	}
+	if p, ok := cfg.LLM.Profile("missing"); ok || p.Model != "anthropic/claude-strong" {
+		t.Errorf("Profile(missing) = %+v, %v, want default model and ok=false", p, ok)
+	}
+
+	// An explicit 0 temperature is kept apart from an unset one
+	if p, _ := cfg.LLM.Profile("exact-v2"); p.Temperature == nil || *p.Temperature != 0 || p.APIKey != "sk-env" {
+		t.Errorf("Profile(exact-v2) = %+v, want temperature 0 and the key from the environment", p)
+	}
+	if p, _ := cfg.LLM.Profile("cheap"); p.Temperature != nil {
+		t.Errorf("Profile(cheap).Temperature = %v, want unset", *p.Temperature)
+	}
+
+	models := cfg.LLM.Models()
+	if len(models) != 3 || models[1] != "gemini/gemini-flash" {
+		t.Errorf("Models() = %v", models)
+	}
+}
+
 // --- WorkspacePath ---
 
 func TestWorkspacePath_ExpandsTilde(t *testing.T) {
@@ -2,7 +2,7 @@ package config
 
 // ConfigVersion is the current config schema version.
 // Increment this when adding new fields that must appear in existing config files.
-const ConfigVersion = 4
+const ConfigVersion = 5
 
 // migrateConfig runs version-based migrations on cfg.
 // Returns true if migrations were applied and config should be re-saved.
@@ -16,6 +16,7 @@ func migrateConfig(cfg *Config) bool {
 		migrateV1ToV2,
 		migrateV2ToV3,
 		migrateV3ToV4,
+		migrateV4ToV5,
 	}
 
 	for i := cfg.Version; i < ConfigVersion && i < len(migrations); i++ {
@@ -64,6 +65,11 @@ func migrateV3ToV4(cfg *Config) {
 	cfg.Tools.Android.Intent = def.Intent
 }
 
+func migrateV4ToV5(cfg *Config) {
+	// streaming: the missing field keeps its default from DefaultConfig.
+	// Version bump + re-save writes the new field into config.json.
+}
+
 // disableActions sets action fields to false for any action name present in disabled.
 func disableActions(cfg *AndroidToolsConfig, disabled map[string]bool) {
 	// Alarm
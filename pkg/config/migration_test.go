@@ -160,6 +160,19 @@ func TestMigrateConfig_FromV2ToV3_WithDisabledActions(t *testing.T) {
 	}
 }
 
+func TestMigrateConfig_FromV4ToV5(t *testing.T) {
+	cfg := &Config{Version: 4}
+	if !migrateConfig(cfg) {
+		t.Error("migrateConfig should return true when migrating from version 4")
+	}
+	if cfg.Version != ConfigVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, ConfigVersion)
+	}
+	if cfg.Agents.Defaults.Streaming {
+		t.Error("migration from v4 should not turn Streaming on")
+	}
+}
+
 func TestLoadConfig_NoMigrationWhenCurrent(t *testing.T) {
 	tmpDir := t.TempDir()
 	path := filepath.Join(tmpDir, "config.json")
@@ -0,0 +1,224 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+)
+
+const testSuite = `name: notes
+data_dir: agent
+cases:
+  - name: lists-notes
+    turns:
+      - user: find my notes
+        expect:
+          tool_calls:
+            - name: list_dir
+              args: {path: notes}
+          contains: [3 notes]
+          max_tokens: 500
+          judge: mentions the notes
+  - name: regressed
+    turns:
+      - user: find my notes
+        expect:
+          no_tool_calls: [list_dir]
+          matches: ["^Nothing"]
+          max_tokens: 100
+          judge: says nothing was found
+`
+
+// scriptedProvider lists a directory, then answers, and grades judge
+// prompts by whether the criterion mentions notes.
+type scriptedProvider struct {
+	mu      sync.Mutex
+	systems []string
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if strings.HasPrefix(last.Content, "You are grading") {
+		if strings.Contains(last.Content, "Criterion: mentions the notes") {
+			return &providers.LLMResponse{Content: "PASS\nThe reply mentions notes."}, nil
+		}
+		return &providers.LLMResponse{Content: "FAIL\nThe reply found notes."}, nil
+	}
+
+	p.mu.Lock()
+	p.systems = append(p.systems, messages[0].Content)
+	p.mu.Unlock()
+
+	usage := &providers.UsageInfo{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}
+	if last.Role == "tool" {
+		return &providers.LLMResponse{Content: "Found 3 notes.", Usage: usage}, nil
+	}
+	return &providers.LLMResponse{
+		ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Type:      "function",
+			Name:      "list_dir",
+			Arguments: map[string]interface{}{"path": "notes/"},
+		}},
+		Usage: usage,
+	}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestRunner_ChecksExpectationsAndWritesJUnit(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "agent", "skills"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "agent", "AGENT.md"), []byte("Always count the notes."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suitePath := filepath.Join(dir, "suite.yaml")
+	if err := os.WriteFile(suitePath, []byte(testSuite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suite, err := LoadSuite(suitePath)
+	if err != nil {
+		t.Fatalf("LoadSuite failed: %v", err)
+	}
+
+	workspace := filepath.Join(dir, "workspace")
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				DataDir:           filepath.Join(dir, "unused"),
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 5,
+			},
+		},
+	}
+	provider := &scriptedProvider{}
+	report := NewRunner(cfg, provider).Run(context.Background(), suite)
+
+	if len(report.Cases) != 2 {
+		t.Fatalf("ran %d cases, want 2", len(report.Cases))
+	}
+	if c := report.Cases[0]; !c.Passed() || c.Turns[0].Tokens != 240 {
+		t.Errorf("lists-notes: failures %v, error %q, tokens %d", c.Failures, c.Error, c.Turns[0].Tokens)
+	}
+	failures := strings.Join(report.Cases[1].Failures, "\n")
+	for _, want := range []string{"unexpected list_dir call", `does not match "^Nothing"`, "used 240 tokens, budget 100", "FAIL"} {
+		if !strings.Contains(failures, want) {
+			t.Errorf("regressed failures missing %q:\n%s", want, failures)
+		}
+	}
+	for _, system := range provider.systems {
+		if !strings.Contains(system, "Always count the notes.") {
+			t.Error("case did not run with the suite's data directory")
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+	var parsed junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, buf.String())
+	}
+	s := parsed.Suites[0]
+	if s.Name != "notes" || s.Tests != 2 || s.Failures != 1 || s.Cases[1].Failure == nil {
+		t.Errorf("JUnit suite = %+v", s)
+	}
+	if !strings.Contains(s.Cases[0].SystemOut, `tools: list_dir{"path":"notes/"}`) {
+		t.Errorf("transcript = %q", s.Cases[0].SystemOut)
+	}
+}
+
+func TestLoadSuite_Validates(t *testing.T) {
+	tests := map[string]string{
+		"no cases":      "name: empty\n",
+		"no user":       "cases:\n  - name: a\n    turns:\n      - expect: {contains: [x]}\n",
+		"duplicate":     "cases:\n  - name: a\n    turns: [{user: hi}]\n  - name: a\n    turns: [{user: hi}]\n",
+		"invalid regex": "cases:\n  - name: a\n    turns: [{user: hi, expect: {matches: [\"(\"]}}]\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "suite.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSuite(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// writingProvider overwrites a workspace file, then answers.
+type writingProvider struct{}
+
+func (writingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	if messages[len(messages)-1].Role == "tool" {
+		return &providers.LLMResponse{Content: messages[len(messages)-1].Content}, nil
+	}
+	return &providers.LLMResponse{
+		ToolCalls: []providers.ToolCall{{
+			ID:        "call_1",
+			Type:      "function",
+			Name:      "write_file",
+			Arguments: map[string]interface{}{"path": "notes.txt", "content": "overwritten"},
+		}},
+	}, nil
+}
+
+func (writingProvider) GetDefaultModel() string {
+	return "test-model"
+}
+
+func TestRunner_CasesRunInWorkspaceCopy(t *testing.T) {
+	dir := t.TempDir()
+	workspace := filepath.Join(dir, "workspace")
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		t.Fatal(err)
+	}
+	notes := filepath.Join(workspace, "notes.txt")
+	if err := os.WriteFile(notes, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	suitePath := filepath.Join(dir, "suite.yaml")
+	suite := "cases:\n  - name: overwrite\n    turns: [{user: overwrite my notes}]\n"
+	if err := os.WriteFile(suitePath, []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSuite(suitePath)
+	if err != nil {
+		t.Fatalf("LoadSuite failed: %v", err)
+	}
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         workspace,
+				DataDir:           filepath.Join(dir, "data"),
+				MaxTokens:         4096,
+				ContextWindow:     128000,
+				MaxToolIterations: 5,
+			},
+		},
+	}
+
+	report := NewRunner(cfg, writingProvider{}).Run(context.Background(), s)
+	if c := report.Cases[0]; c.Error != "" || len(c.Turns[0].ToolCalls) != 1 {
+		t.Fatalf("case = %+v", c)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "original" {
+		t.Errorf("case changed the real workspace: %q", data)
+	}
+}
@@ -0,0 +1,95 @@
+package eval
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, one testcase per eval case with
+// the conversation transcript as its output.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:  r.Suite,
+		Tests: len(r.Cases),
+		Time:  seconds(r.Duration.Seconds()),
+	}
+	for _, c := range r.Cases {
+		jc := junitCase{
+			Name:      c.Name,
+			Classname: r.Suite,
+			Time:      seconds(c.Duration.Seconds()),
+			SystemOut: transcript(c),
+		}
+		switch {
+		case c.Error != "":
+			suite.Errors++
+			jc.Error = &junitMessage{Message: c.Error, Text: c.Error}
+		case len(c.Failures) > 0:
+			suite.Failures++
+			jc.Failure = &junitMessage{
+				Message: fmt.Sprintf("%d expectation(s) failed", len(c.Failures)),
+				Text:    strings.Join(c.Failures, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, jc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// transcript renders a case's turns for the JUnit output.
+func transcript(c CaseResult) string {
+	var sb strings.Builder
+	for i, t := range c.Turns {
+		fmt.Fprintf(&sb, "--- turn %d (%d tokens)\n", i+1, t.Tokens)
+		fmt.Fprintf(&sb, "user: %s\n", t.User)
+		if len(t.ToolCalls) > 0 {
+			fmt.Fprintf(&sb, "tools: %s\n", describeCalls(t.ToolCalls))
+		}
+		fmt.Fprintf(&sb, "assistant: %s\n", t.Response)
+	}
+	return sb.String()
+}
+
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
@@ -0,0 +1,417 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/agent"
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+)
+
+// seedDirs are the data directory entries copied for each case, besides the
+// top-level files (AGENT.md, SOUL.md, users.json, ...). Sessions, usage and
+// other state are left behind so cases start clean.
+var seedDirs = []string{"skills", "prompts", "personas", "memory"}
+
+const judgePrompt = `You are grading an AI assistant's reply against a criterion.
+
+Criterion: %s
+
+User message:
+%s
+
+Assistant reply:
+%s
+
+Answer PASS or FAIL on the first line, then explain why in one sentence.`
+
+// Runner runs suites against agent loops built from a config and provider.
+type Runner struct {
+	cfg      *config.Config
+	provider providers.LLMProvider
+	// Progress, when set, receives a line per finished case.
+	Progress io.Writer
+}
+
+// NewRunner creates a Runner. cfg is copied for each case, with the data
+// directory and workspace replaced by temporary copies. Tools are restricted
+// to the copied workspace and the Android tool is disabled, so cases never
+// change the user's files or device.
+func NewRunner(cfg *config.Config, provider providers.LLMProvider) *Runner {
+	return &Runner{cfg: cfg, provider: provider}
+}
+
+// Report is the outcome of a suite run.
+type Report struct {
+	Suite    string
+	Cases    []CaseResult
+	Duration time.Duration
+}
+
+// Failed returns the number of cases that failed or errored.
+func (r *Report) Failed() int {
+	n := 0
+	for _, c := range r.Cases {
+		if !c.Passed() {
+			n++
+		}
+	}
+	return n
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	Name     string
+	Duration time.Duration
+	Turns    []TurnResult
+	Failures []string // Failed expectations
+	Error    string   // Set when the case could not run to completion
+}
+
+// Passed reports whether every expectation held.
+func (c CaseResult) Passed() bool {
+	return c.Error == "" && len(c.Failures) == 0
+}
+
+// TurnResult is what the agent did in one turn.
+type TurnResult struct {
+	User      string
+	Response  string
+	ToolCalls []providers.ToolCall
+	Tokens    int
+}
+
+// Run runs every case of the suite, or only the named ones.
+func (r *Runner) Run(ctx context.Context, suite *Suite, only ...string) *Report {
+	start := time.Now()
+	report := &Report{Suite: suite.Name}
+	for _, c := range suite.Cases {
+		if len(only) > 0 && !contains(only, c.Name) {
+			continue
+		}
+		res := r.runCase(ctx, suite, c)
+		report.Cases = append(report.Cases, res)
+		if r.Progress != nil {
+			status := "PASS"
+			if !res.Passed() {
+				status = "FAIL"
+			}
+			fmt.Fprintf(r.Progress, "%s  %s (%.1fs)\n", status, c.Name, res.Duration.Seconds())
+		}
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, suite *Suite, c Case) CaseResult {
+	start := time.Now()
+	res := CaseResult{Name: c.Name}
+	defer func() { res.Duration = time.Since(start) }()
+
+	root, err := os.MkdirTemp("", "clawdroid-eval-")
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer func() { _ = os.RemoveAll(root) }()
+	dataDir := filepath.Join(root, "data")
+	workspace := filepath.Join(root, "workspace")
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if err := seedDataDir(suite.seedDir(r.cfg.DataPath()), dataDir); err != nil {
+		res.Error = fmt.Sprintf("copying data directory: %v", err)
+		return res
+	}
+	if err := copyTree(r.cfg.WorkspacePath(), workspace); err != nil {
+		res.Error = fmt.Sprintf("copying workspace: %v", err)
+		return res
+	}
+
+	cfg := config.DefaultConfig()
+	r.cfg.RLock()
+	cfg.CopyFrom(r.cfg)
+	r.cfg.RUnlock()
+	cfg.Agents.Defaults.DataDir = dataDir
+	cfg.Agents.Defaults.Workspace = workspace
+	cfg.Agents.Defaults.RestrictToWorkspace = true
+	cfg.Tools.Android.Enabled = false
+
+	recorder := &recordingProvider{inner: r.provider}
+	al := agent.NewAgentLoop(cfg, bus.NewMessageBus(), recorder)
+	defer al.Stop()
+
+	channel := c.Channel
+	if channel == "" {
+		channel = "cli"
+	}
+	sessionKey := "eval:" + c.Name
+	for i, turn := range c.Turns {
+		response, err := al.ProcessDirectWithChannel(ctx, turn.User, sessionKey, channel, "eval")
+		calls, tokens := recorder.take()
+		res.Turns = append(res.Turns, TurnResult{User: turn.User, Response: response, ToolCalls: calls, Tokens: tokens})
+		if err != nil {
+			res.Error = fmt.Sprintf("turn %d: %v", i+1, err)
+			return res
+		}
+		for _, f := range r.check(ctx, suite, turn, response, calls, tokens) {
+			res.Failures = append(res.Failures, fmt.Sprintf("turn %d: %s", i+1, f))
+		}
+	}
+	return res
+}
+
+// check returns the expectations of a turn that do not hold.
+func (r *Runner) check(ctx context.Context, suite *Suite, turn Turn, response string, calls []providers.ToolCall, tokens int) []string {
+	var failures []string
+	exp := turn.Expect
+
+	for _, want := range exp.ToolCalls {
+		if !anyCallMatches(calls, want) {
+			failures = append(failures, fmt.Sprintf("expected a %s call with args %v, got %s", want.Name, want.Args, describeCalls(calls)))
+		}
+	}
+	for _, name := range exp.NoToolCalls {
+		for _, tc := range calls {
+			if callName(tc) == name {
+				failures = append(failures, fmt.Sprintf("unexpected %s call", name))
+				break
+			}
+		}
+	}
+	for _, s := range exp.Contains {
+		if !strings.Contains(response, s) {
+			failures = append(failures, fmt.Sprintf("reply does not contain %q", s))
+		}
+	}
+	for _, s := range exp.NotContains {
+		if strings.Contains(response, s) {
+			failures = append(failures, fmt.Sprintf("reply contains %q", s))
+		}
+	}
+	for _, pattern := range exp.Matches {
+		if !regexp.MustCompile(pattern).MatchString(response) {
+			failures = append(failures, fmt.Sprintf("reply does not match %q", pattern))
+		}
+	}
+	if exp.MaxTokens > 0 && tokens > exp.MaxTokens {
+		failures = append(failures, fmt.Sprintf("used %d tokens, budget %d", tokens, exp.MaxTokens))
+	}
+	if exp.Judge != "" {
+		if f := r.judge(ctx, suite, exp.Judge, turn.User, response); f != "" {
+			failures = append(failures, f)
+		}
+	}
+	return failures
+}
+
+// judge asks the LLM whether the reply meets the criterion. It returns a
+// failure message, or "" when the judge passes the reply.
+func (r *Runner) judge(ctx context.Context, suite *Suite, criterion, user, response string) string {
+	model := suite.JudgeModel
+	if model == "" {
+		model = r.provider.GetDefaultModel()
+	}
+	prompt := fmt.Sprintf(judgePrompt, criterion, user, response)
+	resp, err := r.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, model,
+		map[string]interface{}{"max_tokens": 256})
+	if err != nil {
+		return fmt.Sprintf("judge %q: %v", criterion, err)
+	}
+	verdict := strings.TrimSpace(resp.Content)
+	if strings.HasPrefix(strings.ToUpper(verdict), "PASS") {
+		return ""
+	}
+	return fmt.Sprintf("judge %q: %s", criterion, verdict)
+}
+
+func anyCallMatches(calls []providers.ToolCall, want ToolCallExpect) bool {
+	for _, tc := range calls {
+		if callName(tc) == want.Name && argsMatch(callArgs(tc), want.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+// argsMatch reports whether every expected argument is present: strings by
+// substring, other values by equality after a JSON round trip (so YAML ints
+// compare equal to JSON numbers).
+func argsMatch(actual, want map[string]interface{}) bool {
+	for key, w := range want {
+		a, ok := actual[key]
+		if !ok {
+			return false
+		}
+		if ws, isString := w.(string); isString {
+			if !strings.Contains(fmt.Sprint(a), ws) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(normalize(a), normalize(w)) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
+
+func callName(tc providers.ToolCall) string {
+	if tc.Name == "" && tc.Function != nil {
+		return tc.Function.Name
+	}
+	return tc.Name
+}
+
+func callArgs(tc providers.ToolCall) map[string]interface{} {
+	if tc.Arguments != nil || tc.Function == nil {
+		return tc.Arguments
+	}
+	var args map[string]interface{}
+	_ = json.Unmarshal([]byte(tc.Function.Arguments), &args)
+	return args
+}
+
+func describeCalls(calls []providers.ToolCall) string {
+	if len(calls) == 0 {
+		return "no tool calls"
+	}
+	parts := make([]string, len(calls))
+	for i, tc := range calls {
+		args, _ := json.Marshal(callArgs(tc))
+		parts[i] = callName(tc) + string(args)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingProvider collects the tool calls and token usage of the responses
+// passing through it.
+type recordingProvider struct {
+	inner  providers.LLMProvider
+	mu     sync.Mutex
+	calls  []providers.ToolCall
+	tokens int
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	if resp != nil {
+		p.mu.Lock()
+		p.calls = append(p.calls, resp.ToolCalls...)
+		if u := resp.Usage; u != nil {
+			if u.TotalTokens > 0 {
+				p.tokens += u.TotalTokens
+			} else {
+				p.tokens += u.PromptTokens + u.CompletionTokens
+			}
+		}
+		p.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (p *recordingProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// take returns and clears what was collected since the last call.
+func (p *recordingProvider) take() ([]providers.ToolCall, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls, tokens := p.calls, p.tokens
+	p.calls, p.tokens = nil, 0
+	return calls, tokens
+}
+
+// seedDataDir copies the top-level files and seedDirs of src into dst.
+func seedDataDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		from := filepath.Join(src, e.Name())
+		to := filepath.Join(dst, e.Name())
+		switch {
+		case e.Type().IsRegular():
+			err = copyFile(from, to)
+		case e.IsDir() && contains(seedDirs, e.Name()):
+			err = os.CopyFS(to, os.DirFS(from))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTree copies the directories and regular files under src into dst,
+// creating dst even if src does not exist. Symlinks and special files are
+// skipped, so nothing outside src is copied.
+func copyTree(src, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(to, 0755)
+		case d.Type().IsRegular():
+			return copyFile(path, to)
+		}
+		return nil
+	})
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0644)
+}
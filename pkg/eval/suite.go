@@ -0,0 +1,117 @@
+// Package eval runs scripted conversations against the agent and checks the
+// tool calls it makes, its replies and its token usage, so prompt, skill and
+// bootstrap file changes can be tested like code.
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of eval cases loaded from a YAML file.
+type Suite struct {
+	Name       string `yaml:"name"`
+	DataDir    string `yaml:"data_dir"`    // Seed data directory (default: the configured one), relative to the suite file
+	JudgeModel string `yaml:"judge_model"` // Model for judge expectations (default: the provider's default model)
+	Cases      []Case `yaml:"cases"`
+
+	dir string // Directory of the suite file
+}
+
+// Case is one conversation. Each case runs in a fresh session and a fresh
+// copy of the data directory.
+type Case struct {
+	Name    string `yaml:"name"`
+	Channel string `yaml:"channel"` // Channel the messages arrive on (default "cli")
+	Turns   []Turn `yaml:"turns"`
+}
+
+// Turn is a user message and what the agent must do in response.
+type Turn struct {
+	User   string `yaml:"user"`
+	Expect Expect `yaml:"expect"`
+}
+
+// Expect lists the checks for a turn. Empty fields are not checked.
+type Expect struct {
+	ToolCalls   []ToolCallExpect `yaml:"tool_calls"`    // Calls that must be made, in any order
+	NoToolCalls []string         `yaml:"no_tool_calls"` // Tools that must not be called
+	Contains    []string         `yaml:"contains"`      // Substrings the reply must contain
+	NotContains []string         `yaml:"not_contains"`  // Substrings the reply must not contain
+	Matches     []string         `yaml:"matches"`       // Regular expressions the reply must match
+	Judge       string           `yaml:"judge"`         // Criterion an LLM judge checks the reply against
+	MaxTokens   int              `yaml:"max_tokens"`    // Token budget for the turn's LLM calls
+}
+
+// ToolCallExpect matches a tool call by name and arguments. String argument
+// values match when the actual value contains them; other values must be
+// equal.
+type ToolCallExpect struct {
+	Name string                 `yaml:"name"`
+	Args map[string]interface{} `yaml:"args"`
+}
+
+// LoadSuite reads and validates a suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Suite
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	s.dir = filepath.Dir(path)
+	if s.Name == "" {
+		s.Name = filepath.Base(path)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s *Suite) validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("suite has no cases")
+	}
+	seen := make(map[string]bool)
+	for i, c := range s.Cases {
+		if c.Name == "" {
+			return fmt.Errorf("case %d has no name", i+1)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate case name %q", c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.Turns) == 0 {
+			return fmt.Errorf("case %q has no turns", c.Name)
+		}
+		for j, t := range c.Turns {
+			if t.User == "" {
+				return fmt.Errorf("case %q turn %d has no user message", c.Name, j+1)
+			}
+			for _, pattern := range t.Expect.Matches {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("case %q turn %d: invalid pattern %q: %w", c.Name, j+1, pattern, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// seedDir returns the data directory cases are seeded from.
+func (s *Suite) seedDir(configured string) string {
+	if s.DataDir == "" {
+		return configured
+	}
+	if filepath.IsAbs(s.DataDir) {
+		return s.DataDir
+	}
+	return filepath.Join(s.dir, s.DataDir)
+}
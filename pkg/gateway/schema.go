@@ -41,6 +41,12 @@ var secretKeys = map[string]bool{
 	"channel_access_token": true,
 }
 
+// secretFields lists full dot-separated JSON keys of fields that hold secrets
+// under another name, such as maps whose entries carry API keys.
+var secretFields = map[string]bool{
+	"profiles": true, // llm.profiles.<name>.api_key
+}
+
 // directoryKeys lists full dot-separated JSON keys that represent directory paths.
 // Fields matching these keys are reported as type "directory" so that
 // Android can render a SAF directory-picker instead of a plain text field.
@@ -198,7 +204,7 @@ func buildFields(t reflect.Type, v reflect.Value, prefix string, group string, d
 			Group:   group,
 			Depth:   depth,
 			Type:    schemaType,
-			Secret:  secretKeys[jk],
+			Secret:  secretKeys[jk] || secretFields[fullKey],
 			Default: defVal,
 		})
 	}
@@ -181,6 +181,10 @@ func TestBuildSchema_SecretFlag(t *testing.T) {
 			t.Errorf("field %q should be marked as secret", k)
 		}
 	}
+	// Profiles carry API keys inside the map
+	if f, ok := fieldMap["profiles"]; !ok || !f.Secret {
+		t.Errorf("profiles = %+v, %v, want a secret field", f, ok)
+	}
 
 	nonSecrets := []string{"model", "base_url", "enabled", "host", "port"}
 	for _, k := range nonSecrets {
@@ -364,6 +368,12 @@ func TestBuildSchema_DefaultValues(t *testing.T) {
 		{"agents", "defaults.restrict_to_workspace", true},
 		{"heartbeat", "enabled", true},
 		{"heartbeat", "interval", float64(30)},
+		{"tools", "exec.timeout", float64(60)},
+		{"tools", "exec.max_output", float64(10000)},
+		{"tools", "exec.shell", ""},
+		{"tools", "exec.process_timeout", float64(3600)},
+		{"tools", "exec.allow_patterns", []string{}},
+		{"tools", "exec.env_scrub", []string{"CLAWDROID_*", "*_API_KEY", "*_TOKEN", "*_SECRET", "*PASSWORD*"}},
 	}
 
 	for _, tc := range tests {
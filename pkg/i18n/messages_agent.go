@@ -7,6 +7,9 @@ func init() {
 		"agent.memory_threshold_warning": "⚠️ Memory threshold reached. Optimizing conversation history...",
 		"agent.rate_limited":             "Rate limited: %s. Please try again later.",
 		"agent.rate_limited_tool":        "Rate limited: %s",
+		"agent.budget_exceeded":          "The daily usage budget has been reached. Please try again tomorrow.",
+		"agent.stream_failed":            "⚠️ The response could not be completed. Please try again.",
+		"agent.approval_request":         "🔐 Approval needed: %s\n%s\n\nReply /approve %s or /deny %s",
 	})
 
 	register("ja", map[string]string{
@@ -15,5 +18,8 @@ func init() {
 		"agent.memory_threshold_warning": "⚠️ メモリしきい値に達しました。会話履歴を最適化しています...",
 		"agent.rate_limited":             "レート制限中: %s。しばらくしてからお試しください。",
 		"agent.rate_limited_tool":        "レート制限中: %s",
+		"agent.budget_exceeded":          "本日の利用予算に達しました。明日以降にお試しください。",
+		"agent.stream_failed":            "⚠️ 応答を完了できませんでした。もう一度お試しください。",
+		"agent.approval_request":         "🔐 承認が必要です: %s\n%s\n\n/approve %s または /deny %s で返信してください",
 	})
 }
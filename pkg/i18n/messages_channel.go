@@ -8,37 +8,69 @@ func init() {
 		// WebSocket
 		"channel.config_required": "Configuration required",
 
+		// Tool approval buttons
+		"channel.approval.approve":  "✅ Approve",
+		"channel.approval.deny":     "❌ Deny",
+		"channel.approval.approved": "✅ Approved",
+		"channel.approval.denied":   "❌ Denied",
+
 		// Telegram commands (/help, /start, /show, /list)
 		"cmd.help": `/start - Start the bot
 /help - Show this help message
 /show [model|channel] - Show current configuration
 /list [models|channels] - List available options
+/usage - Show today's token usage and cost
+/undo - Remove the last exchange
+/reset - Clear this conversation
+/history [N] - Show the last N messages
+/fork <name> - Branch the conversation and switch to the new branch
+/checkout [name] - Switch branches, or list them
 `,
 		"cmd.start":         "Hello! I am ClawDroid 🦞",
 		"cmd.show.usage":    "Usage: /show [model|channel]",
-		"cmd.show.model":    "Current Model: %s",
 		"cmd.show.channel":  "Current Channel: telegram",
 		"cmd.show.unknown":  "Unknown parameter: %s. Try 'model' or 'channel'.",
 		"cmd.list.usage":    "Usage: /list [models|channels]",
-		"cmd.list.models":   "Configured Model: %s\n\nTo change models, update config.json",
 		"cmd.list.channels": "Enabled Channels:\n- %s",
 		"cmd.list.unknown":  "Unknown parameter: %s. Try 'models' or 'channels'.",
 
-		// Agent loop commands (/show, /list, /switch)
+		// Agent loop commands (/show, /list, /switch, /usage, /undo, /reset, /history, /fork, /checkout)
 		// cmd.show.usage and cmd.list.usage are shared with Telegram commands
-		"agent.cmd.show.model":        "Current model: %s",
-		"agent.cmd.show.channel":      "Current channel: %s",
-		"agent.cmd.show.unknown":      "Unknown show target: %s",
-		"agent.cmd.list.models":       "Available models: glm-4.7, claude-3-5-sonnet, gpt-4o (configured in config.json/env)",
-		"agent.cmd.list.no_channels":  "No channels enabled",
-		"agent.cmd.list.channels":     "Enabled channels: %s",
-		"agent.cmd.list.unknown":      "Unknown list target: %s",
-		"agent.cmd.switch.usage":      "Usage: /switch [model|channel] to <name>",
-		"agent.cmd.switch.model":      "Switched model from %s to %s",
-		"agent.cmd.switch.channel":    "Switched target channel to %s (Note: this currently only validates existence)",
-		"agent.cmd.switch.not_found":  "Channel '%s' not found or not enabled",
-		"agent.cmd.switch.unknown":    "Unknown switch target: %s",
-		"agent.cmd.channel_mgr_error": "Channel manager not initialized",
+		"agent.cmd.show.model":           "Current model: %s",
+		"agent.cmd.show.channel":         "Current channel: %s",
+		"agent.cmd.show.unknown":         "Unknown show target: %s",
+		"agent.cmd.list.models":          "Configured models (* = in use for this chat):",
+		"agent.cmd.list.no_channels":     "No channels enabled",
+		"agent.cmd.list.channels":        "Enabled channels: %s",
+		"agent.cmd.list.unknown":         "Unknown list target: %s",
+		"agent.cmd.switch.usage":         "Usage: /switch [model|channel] to <name>",
+		"agent.cmd.switch.model":         "Switched model for this chat from %s to %s",
+		"agent.cmd.switch.model_invalid": "Invalid model '%s' (use provider/model_name, e.g. openai/gpt-4o)",
+		"agent.cmd.usage.title":          "Usage today (tokens, cost):",
+		"agent.cmd.usage.session":        "This chat: %s, %s",
+		"agent.cmd.usage.user":           "You: %s, %s",
+		"agent.cmd.usage.channel":        "This channel: %s, %s",
+		"agent.cmd.usage.total":          "Total: %s, %s",
+		"agent.cmd.usage.budget":         "Daily budget: soft %s / hard %s",
+		"agent.cmd.usage.unavailable":    "Usage tracking is not available",
+		"agent.cmd.undo.done":            "Removed the last exchange (%d messages)",
+		"agent.cmd.undo.empty":           "Nothing to undo",
+		"agent.cmd.reset.done":           "Cleared the conversation on branch %s",
+		"agent.cmd.history.usage":        "Usage: /history [N]",
+		"agent.cmd.history.empty":        "No messages in this conversation yet",
+		"agent.cmd.history.title":        "Last %d messages on branch %s:",
+		"agent.cmd.fork.usage":           "Usage: /fork <name>",
+		"agent.cmd.fork.done":            "Created branch %s from %s and switched to it",
+		"agent.cmd.fork.exists":          "Branch '%s' already exists",
+		"agent.cmd.branch.invalid":       "Invalid branch name '%s' (use up to 32 letters, digits, '-' or '_')",
+		"agent.cmd.branches":             "Branches (* = current): %s",
+		"agent.cmd.checkout.done":        "Switched to branch %s (%d messages)",
+		"agent.cmd.checkout.current":     "Already on branch %s",
+		"agent.cmd.checkout.not_found":   "Branch '%s' not found. Branches: %s",
+		"agent.cmd.switch.channel":       "Switched target channel to %s (Note: this currently only validates existence)",
+		"agent.cmd.switch.not_found":     "Channel '%s' not found or not enabled",
+		"agent.cmd.switch.unknown":       "Unknown switch target: %s",
+		"agent.cmd.channel_mgr_error":    "Channel manager not initialized",
 	})
 
 	register("ja", map[string]string{
@@ -48,36 +80,68 @@ func init() {
 		// WebSocket
 		"channel.config_required": "設定が必要です",
 
+		// Tool approval buttons
+		"channel.approval.approve":  "✅ 承認",
+		"channel.approval.deny":     "❌ 拒否",
+		"channel.approval.approved": "✅ 承認しました",
+		"channel.approval.denied":   "❌ 拒否しました",
+
 		// Telegram commands
 		"cmd.help": `/start - ボットを開始
 /help - このヘルプメッセージを表示
 /show [model|channel] - 現在の設定を表示
 /list [models|channels] - 利用可能なオプションを一覧表示
+/usage - 本日のトークン使用量とコストを表示
+/undo - 直前のやり取りを取り消す
+/reset - この会話をクリア
+/history [N] - 直近 N 件のメッセージを表示
+/fork <名前> - 会話を分岐して新しいブランチに切り替え
+/checkout [名前] - ブランチを切り替え、または一覧表示
 `,
 		"cmd.start":         "こんにちは！ClawDroid です 🦞",
 		"cmd.show.usage":    "使い方: /show [model|channel]",
-		"cmd.show.model":    "現在のモデル: %s",
 		"cmd.show.channel":  "現在のチャンネル: telegram",
 		"cmd.show.unknown":  "不明なパラメータ: %s。'model' か 'channel' を指定してください。",
 		"cmd.list.usage":    "使い方: /list [models|channels]",
-		"cmd.list.models":   "設定済みモデル: %s\n\nモデルを変更するには config.json を更新してください",
 		"cmd.list.channels": "有効なチャンネル:\n- %s",
 		"cmd.list.unknown":  "不明なパラメータ: %s。'models' か 'channels' を指定してください。",
 
 		// Agent loop commands
 		// cmd.show.usage and cmd.list.usage are shared with Telegram commands
-		"agent.cmd.show.model":        "現在のモデル: %s",
-		"agent.cmd.show.channel":      "現在のチャンネル: %s",
-		"agent.cmd.show.unknown":      "不明な表示対象: %s",
-		"agent.cmd.list.models":       "利用可能なモデル: glm-4.7, claude-3-5-sonnet, gpt-4o（config.json/env で設定）",
-		"agent.cmd.list.no_channels":  "有効なチャンネルはありません",
-		"agent.cmd.list.channels":     "有効なチャンネル: %s",
-		"agent.cmd.list.unknown":      "不明な一覧対象: %s",
-		"agent.cmd.switch.usage":      "使い方: /switch [model|channel] to <名前>",
-		"agent.cmd.switch.model":      "モデルを %s から %s に切り替えました",
-		"agent.cmd.switch.channel":    "対象チャンネルを %s に切り替えました（注: 現在は存在確認のみ）",
-		"agent.cmd.switch.not_found":  "チャンネル '%s' が見つからないか有効ではありません",
-		"agent.cmd.switch.unknown":    "不明な切り替え対象: %s",
-		"agent.cmd.channel_mgr_error": "チャンネルマネージャーが初期化されていません",
+		"agent.cmd.show.model":           "現在のモデル: %s",
+		"agent.cmd.show.channel":         "現在のチャンネル: %s",
+		"agent.cmd.show.unknown":         "不明な表示対象: %s",
+		"agent.cmd.list.models":          "設定済みのモデル（* = このチャットで使用中）:",
+		"agent.cmd.list.no_channels":     "有効なチャンネルはありません",
+		"agent.cmd.list.channels":        "有効なチャンネル: %s",
+		"agent.cmd.list.unknown":         "不明な一覧対象: %s",
+		"agent.cmd.switch.usage":         "使い方: /switch [model|channel] to <名前>",
+		"agent.cmd.switch.model":         "このチャットのモデルを %s から %s に切り替えました",
+		"agent.cmd.switch.model_invalid": "無効なモデル '%s'（provider/model_name 形式で指定してください。例: openai/gpt-4o）",
+		"agent.cmd.usage.title":          "本日の使用量（トークン、コスト）:",
+		"agent.cmd.usage.session":        "このチャット: %s、%s",
+		"agent.cmd.usage.user":           "あなた: %s、%s",
+		"agent.cmd.usage.channel":        "このチャンネル: %s、%s",
+		"agent.cmd.usage.total":          "合計: %s、%s",
+		"agent.cmd.usage.budget":         "1日の予算: ソフト %s / ハード %s",
+		"agent.cmd.usage.unavailable":    "使用量の記録は利用できません",
+		"agent.cmd.undo.done":            "直前のやり取りを取り消しました（%d 件のメッセージ）",
+		"agent.cmd.undo.empty":           "取り消すものはありません",
+		"agent.cmd.reset.done":           "ブランチ %s の会話をクリアしました",
+		"agent.cmd.history.usage":        "使い方: /history [N]",
+		"agent.cmd.history.empty":        "この会話にはまだメッセージがありません",
+		"agent.cmd.history.title":        "直近 %d 件のメッセージ（ブランチ %s）:",
+		"agent.cmd.fork.usage":           "使い方: /fork <名前>",
+		"agent.cmd.fork.done":            "ブランチ %s を %s から作成して切り替えました",
+		"agent.cmd.fork.exists":          "ブランチ '%s' は既に存在します",
+		"agent.cmd.branch.invalid":       "無効なブランチ名 '%s'（英数字・'-'・'_' で32文字まで）",
+		"agent.cmd.branches":             "ブランチ（* = 現在）: %s",
+		"agent.cmd.checkout.done":        "ブランチ %s に切り替えました（%d 件のメッセージ）",
+		"agent.cmd.checkout.current":     "既にブランチ %s にいます",
+		"agent.cmd.checkout.not_found":   "ブランチ '%s' が見つかりません。ブランチ: %s",
+		"agent.cmd.switch.channel":       "対象チャンネルを %s に切り替えました（注: 現在は存在確認のみ）",
+		"agent.cmd.switch.not_found":     "チャンネル '%s' が見つからないか有効ではありません",
+		"agent.cmd.switch.unknown":       "不明な切り替え対象: %s",
+		"agent.cmd.channel_mgr_error":    "チャンネルマネージャーが初期化されていません",
 	})
 }
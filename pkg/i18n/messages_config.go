@@ -15,14 +15,26 @@ func init() {
 		"config.Tool Settings":      "ツール設定",
 		"config.Heartbeat":          "ハートビート",
 		"config.Rate Limits":        "レート制限",
+		"config.Usage & Budgets":    "使用量と予算",
+		"config.Traces":             "トレース",
+		"config.Redaction":          "秘匿情報のマスク",
 
 		// LLM
-		"config.Model":    "モデル",
-		"config.API Key":  "APIキー",
-		"config.Base URL": "ベースURL",
+		"config.Model":              "モデル",
+		"config.API Key":            "APIキー",
+		"config.Base URL":           "ベースURL",
+		"config.Fallback Models":    "フォールバックモデル",
+		"config.Model Profiles":     "モデルプロファイル",
+		"config.Model Roles":        "役割別モデル",
+		"config.Chat Profile":       "チャット用プロファイル",
+		"config.Summarizer Profile": "要約用プロファイル",
+		"config.Subagent Profile":   "サブエージェント用プロファイル",
+		"config.Heartbeat Profile":  "ハートビート用プロファイル",
 
 		// Agent Defaults
 		"config.Defaults":              "デフォルト",
+		"config.Personas":              "ペルソナ",
+		"config.Persona Routes":        "ペルソナの振り分け",
 		"config.Workspace":             "ワークスペース",
 		"config.Data Directory":        "データディレクトリ",
 		"config.Restrict to Workspace": "ワークスペースに制限",
@@ -31,8 +43,11 @@ func init() {
 		"config.Temperature":           "温度",
 		"config.Max Tool Iterations":   "最大ツール反復回数",
 		"config.Queue Messages":        "メッセージキュー",
+		"config.Steer Running Turn":    "実行中の応答に追加指示",
 		"config.Show Errors":           "エラー表示",
 		"config.Show Warnings":         "警告表示",
+		"config.Stream Responses":      "応答のストリーミング",
+		"config.Max Parallel Tools":    "最大並列ツール数",
 
 		// Channels
 		"config.WhatsApp":             "WhatsApp",
@@ -64,12 +79,48 @@ func init() {
 		"config.Max Tool Calls Per Minute": "1分あたりの最大ツール呼び出し数",
 		"config.Max Requests Per Minute":   "1分あたりの最大リクエスト数",
 
+		// Usage & Budgets
+		"config.Model Prices":      "モデル料金",
+		"config.Daily Soft Budget": "1日のソフト予算",
+		"config.Daily Hard Budget": "1日のハード予算",
+		"config.Budget Profile":    "予算超過時のプロファイル",
+
+		// Traces
+		"config.Max Stored Traces": "保存するトレースの最大数",
+		"config.OTLP Endpoint":     "OTLPエンドポイント",
+
+		// Redaction
+		"config.Detectors":           "検出対象",
+		"config.Redact Tool Results": "ツール結果もマスク",
+
 		// Tools
 		"config.Web Search":  "Web検索",
 		"config.Shell Exec":  "シェル実行",
 		"config.Android":     "Android",
 		"config.Memory":      "メモリ",
 		"config.MCP Servers": "MCPサーバー",
+		"config.Approval":    "実行承認",
+		"config.Tool Hooks":  "ツールフック",
+
+		// Exec policy
+		"config.Allowed Patterns":                     "許可パターン",
+		"config.Denied Patterns":                      "拒否パターン",
+		"config.Passed Environment":                   "引き継ぐ環境変数",
+		"config.Scrubbed Environment":                 "除去する環境変数",
+		"config.Max Output (characters)":              "最大出力（文字数）",
+		"config.Shell":                                "シェル",
+		"config.Background Process Timeout (seconds)": "バックグラウンドプロセスのタイムアウト（秒）",
+
+		// Exec sandbox
+		"config.Sandbox":             "サンドボックス",
+		"config.Backend":             "バックエンド",
+		"config.Allow Network":       "ネットワークを許可",
+		"config.CPU Limit (seconds)": "CPU時間の上限（秒）",
+		"config.Memory Limit (MB)":   "メモリ上限（MB）",
+
+		// Approval sub
+		"config.Timeout (seconds)": "タイムアウト（秒）",
+		"config.Require Approval":  "承認が必要なツール",
 
 		// Web search sub
 		"config.Brave Search": "Brave検索",
@@ -145,116 +196,155 @@ func init() {
 // the "config." namespace prefix does not leak into displayed labels.
 func configLabelsEN() map[string]string {
 	return map[string]string{
-		"config.LLM":                       "LLM",
-		"config.Agent Defaults":            "Agent Defaults",
-		"config.Messaging Channels":        "Messaging Channels",
-		"config.Gateway":                   "Gateway",
-		"config.Tool Settings":             "Tool Settings",
-		"config.Heartbeat":                 "Heartbeat",
-		"config.Rate Limits":               "Rate Limits",
-		"config.Model":                     "Model",
-		"config.API Key":                   "API Key",
-		"config.Base URL":                  "Base URL",
-		"config.Defaults":                  "Defaults",
-		"config.Workspace":                 "Workspace",
-		"config.Data Directory":            "Data Directory",
-		"config.Restrict to Workspace":     "Restrict to Workspace",
-		"config.Max Tokens":                "Max Tokens",
-		"config.Context Window":            "Context Window",
-		"config.Temperature":               "Temperature",
-		"config.Max Tool Iterations":       "Max Tool Iterations",
-		"config.Queue Messages":            "Queue Messages",
-		"config.Show Errors":               "Show Errors",
-		"config.Show Warnings":             "Show Warnings",
-		"config.WhatsApp":                  "WhatsApp",
-		"config.Telegram":                  "Telegram",
-		"config.Discord":                   "Discord",
-		"config.Slack":                     "Slack",
-		"config.LINE":                      "LINE",
-		"config.WebSocket":                 "WebSocket",
-		"config.Enabled":                   "Enabled",
-		"config.Token":                     "Token",
-		"config.Bot Token":                 "Bot Token",
-		"config.App Token":                 "App Token",
-		"config.Proxy":                     "Proxy",
-		"config.Allow From":                "Allow From",
-		"config.Bridge URL":                "Bridge URL",
-		"config.Host":                      "Host",
-		"config.Port":                      "Port",
-		"config.Path":                      "Path",
-		"config.Channel Secret":            "Channel Secret",
-		"config.Channel Access Token":      "Channel Access Token",
-		"config.Webhook Host":              "Webhook Host",
-		"config.Webhook Port":              "Webhook Port",
-		"config.Webhook Path":              "Webhook Path",
-		"config.Interval":                  "Interval",
-		"config.Max Tool Calls Per Minute": "Max Tool Calls Per Minute",
-		"config.Max Requests Per Minute":   "Max Requests Per Minute",
-		"config.Web Search":                "Web Search",
-		"config.Shell Exec":                "Shell Exec",
-		"config.Android":                   "Android",
-		"config.Memory":                    "Memory",
-		"config.MCP Servers":               "MCP Servers",
-		"config.Brave Search":              "Brave Search",
-		"config.DuckDuckGo":                "DuckDuckGo",
-		"config.Max Results":               "Max Results",
-		"config.App":                       "App",
-		"config.UI Automation":             "UI Automation",
-		"config.Intent":                    "Intent",
-		"config.Alarm":                     "Alarm",
-		"config.Calendar":                  "Calendar",
-		"config.Calendar Account":          "Calendar Account",
-		"config.Contacts":                  "Contacts",
-		"config.Communication":             "Communication",
-		"config.Media":                     "Media",
-		"config.Navigation":                "Navigation",
-		"config.Device Control":            "Device Control",
-		"config.Settings":                  "Settings",
-		"config.Web":                       "Web",
-		"config.Clipboard":                 "Clipboard",
-		"config.Search Apps":               "Search Apps",
-		"config.App Info":                  "App Info",
-		"config.Launch App":                "Launch App",
-		"config.Screenshot":                "Screenshot",
-		"config.Get UI Tree":               "Get UI Tree",
-		"config.Tap":                       "Tap",
-		"config.Swipe":                     "Swipe",
-		"config.Text Input":                "Text Input",
-		"config.Key Event":                 "Key Event",
-		"config.Broadcast":                 "Broadcast",
-		"config.Send Intent":               "Send Intent",
-		"config.Set Alarm":                 "Set Alarm",
-		"config.Set Timer":                 "Set Timer",
-		"config.Dismiss Alarm":             "Dismiss Alarm",
-		"config.Show Alarms":               "Show Alarms",
-		"config.Create Event":              "Create Event",
-		"config.Query Events":              "Query Events",
-		"config.Update Event":              "Update Event",
-		"config.Delete Event":              "Delete Event",
-		"config.List Calendars":            "List Calendars",
-		"config.Add Reminder":              "Add Reminder",
-		"config.Search Contacts":           "Search Contacts",
-		"config.Get Contact Detail":        "Get Contact Detail",
-		"config.Add Contact":               "Add Contact",
-		"config.Dial":                      "Dial",
-		"config.Compose SMS":               "Compose SMS",
-		"config.Compose Email":             "Compose Email",
-		"config.Play/Pause":                "Play/Pause",
-		"config.Next":                      "Next",
-		"config.Previous":                  "Previous",
-		"config.Play Music Search":         "Play Music Search",
-		"config.Navigate":                  "Navigate",
-		"config.Search Nearby":             "Search Nearby",
-		"config.Show Map":                  "Show Map",
-		"config.Get Current Location":      "Get Current Location",
-		"config.Flashlight":                "Flashlight",
-		"config.Set Volume":                "Set Volume",
-		"config.Set Ringer Mode":           "Set Ringer Mode",
-		"config.Set DND":                   "Set DND",
-		"config.Set Brightness":            "Set Brightness",
-		"config.Open Settings":             "Open Settings",
-		"config.Open URL":                  "Open URL",
-		"config.Copy":                      "Copy",
-		"config.Read":                      "Read",
+		"config.LLM":                                  "LLM",
+		"config.Agent Defaults":                       "Agent Defaults",
+		"config.Messaging Channels":                   "Messaging Channels",
+		"config.Gateway":                              "Gateway",
+		"config.Tool Settings":                        "Tool Settings",
+		"config.Heartbeat":                            "Heartbeat",
+		"config.Rate Limits":                          "Rate Limits",
+		"config.Usage & Budgets":                      "Usage & Budgets",
+		"config.Traces":                               "Traces",
+		"config.Redaction":                            "Redaction",
+		"config.Model":                                "Model",
+		"config.API Key":                              "API Key",
+		"config.Base URL":                             "Base URL",
+		"config.Fallback Models":                      "Fallback Models",
+		"config.Model Profiles":                       "Model Profiles",
+		"config.Model Roles":                          "Model Roles",
+		"config.Chat Profile":                         "Chat Profile",
+		"config.Summarizer Profile":                   "Summarizer Profile",
+		"config.Subagent Profile":                     "Subagent Profile",
+		"config.Heartbeat Profile":                    "Heartbeat Profile",
+		"config.Defaults":                             "Defaults",
+		"config.Personas":                             "Personas",
+		"config.Persona Routes":                       "Persona Routes",
+		"config.Workspace":                            "Workspace",
+		"config.Data Directory":                       "Data Directory",
+		"config.Restrict to Workspace":                "Restrict to Workspace",
+		"config.Max Tokens":                           "Max Tokens",
+		"config.Context Window":                       "Context Window",
+		"config.Temperature":                          "Temperature",
+		"config.Max Tool Iterations":                  "Max Tool Iterations",
+		"config.Queue Messages":                       "Queue Messages",
+		"config.Steer Running Turn":                   "Steer Running Turn",
+		"config.Show Errors":                          "Show Errors",
+		"config.Show Warnings":                        "Show Warnings",
+		"config.Stream Responses":                     "Stream Responses",
+		"config.Max Parallel Tools":                   "Max Parallel Tools",
+		"config.WhatsApp":                             "WhatsApp",
+		"config.Telegram":                             "Telegram",
+		"config.Discord":                              "Discord",
+		"config.Slack":                                "Slack",
+		"config.LINE":                                 "LINE",
+		"config.WebSocket":                            "WebSocket",
+		"config.Enabled":                              "Enabled",
+		"config.Token":                                "Token",
+		"config.Bot Token":                            "Bot Token",
+		"config.App Token":                            "App Token",
+		"config.Proxy":                                "Proxy",
+		"config.Allow From":                           "Allow From",
+		"config.Bridge URL":                           "Bridge URL",
+		"config.Host":                                 "Host",
+		"config.Port":                                 "Port",
+		"config.Path":                                 "Path",
+		"config.Channel Secret":                       "Channel Secret",
+		"config.Channel Access Token":                 "Channel Access Token",
+		"config.Webhook Host":                         "Webhook Host",
+		"config.Webhook Port":                         "Webhook Port",
+		"config.Webhook Path":                         "Webhook Path",
+		"config.Interval":                             "Interval",
+		"config.Max Tool Calls Per Minute":            "Max Tool Calls Per Minute",
+		"config.Max Requests Per Minute":              "Max Requests Per Minute",
+		"config.Model Prices":                         "Model Prices",
+		"config.Daily Soft Budget":                    "Daily Soft Budget",
+		"config.Daily Hard Budget":                    "Daily Hard Budget",
+		"config.Budget Profile":                       "Budget Profile",
+		"config.Max Stored Traces":                    "Max Stored Traces",
+		"config.OTLP Endpoint":                        "OTLP Endpoint",
+		"config.Detectors":                            "Detectors",
+		"config.Redact Tool Results":                  "Redact Tool Results",
+		"config.Web Search":                           "Web Search",
+		"config.Shell Exec":                           "Shell Exec",
+		"config.Allowed Patterns":                     "Allowed Patterns",
+		"config.Denied Patterns":                      "Denied Patterns",
+		"config.Passed Environment":                   "Passed Environment",
+		"config.Scrubbed Environment":                 "Scrubbed Environment",
+		"config.Max Output (characters)":              "Max Output (characters)",
+		"config.Shell":                                "Shell",
+		"config.Background Process Timeout (seconds)": "Background Process Timeout (seconds)",
+		"config.Sandbox":                              "Sandbox",
+		"config.Backend":                              "Backend",
+		"config.Allow Network":                        "Allow Network",
+		"config.CPU Limit (seconds)":                  "CPU Limit (seconds)",
+		"config.Memory Limit (MB)":                    "Memory Limit (MB)",
+		"config.Android":                              "Android",
+		"config.Memory":                               "Memory",
+		"config.MCP Servers":                          "MCP Servers",
+		"config.Approval":                             "Approval",
+		"config.Tool Hooks":                           "Tool Hooks",
+		"config.Timeout (seconds)":                    "Timeout (seconds)",
+		"config.Require Approval":                     "Require Approval",
+		"config.Brave Search":                         "Brave Search",
+		"config.DuckDuckGo":                           "DuckDuckGo",
+		"config.Max Results":                          "Max Results",
+		"config.App":                                  "App",
+		"config.UI Automation":                        "UI Automation",
+		"config.Intent":                               "Intent",
+		"config.Alarm":                                "Alarm",
+		"config.Calendar":                             "Calendar",
+		"config.Calendar Account":                     "Calendar Account",
+		"config.Contacts":                             "Contacts",
+		"config.Communication":                        "Communication",
+		"config.Media":                                "Media",
+		"config.Navigation":                           "Navigation",
+		"config.Device Control":                       "Device Control",
+		"config.Settings":                             "Settings",
+		"config.Web":                                  "Web",
+		"config.Clipboard":                            "Clipboard",
+		"config.Search Apps":                          "Search Apps",
+		"config.App Info":                             "App Info",
+		"config.Launch App":                           "Launch App",
+		"config.Screenshot":                           "Screenshot",
+		"config.Get UI Tree":                          "Get UI Tree",
+		"config.Tap":                                  "Tap",
+		"config.Swipe":                                "Swipe",
+		"config.Text Input":                           "Text Input",
+		"config.Key Event":                            "Key Event",
+		"config.Broadcast":                            "Broadcast",
+		"config.Send Intent":                          "Send Intent",
+		"config.Set Alarm":                            "Set Alarm",
+		"config.Set Timer":                            "Set Timer",
+		"config.Dismiss Alarm":                        "Dismiss Alarm",
+		"config.Show Alarms":                          "Show Alarms",
+		"config.Create Event":                         "Create Event",
+		"config.Query Events":                         "Query Events",
+		"config.Update Event":                         "Update Event",
+		"config.Delete Event":                         "Delete Event",
+		"config.List Calendars":                       "List Calendars",
+		"config.Add Reminder":                         "Add Reminder",
+		"config.Search Contacts":                      "Search Contacts",
+		"config.Get Contact Detail":                   "Get Contact Detail",
+		"config.Add Contact":                          "Add Contact",
+		"config.Dial":                                 "Dial",
+		"config.Compose SMS":                          "Compose SMS",
+		"config.Compose Email":                        "Compose Email",
+		"config.Play/Pause":                           "Play/Pause",
+		"config.Next":                                 "Next",
+		"config.Previous":                             "Previous",
+		"config.Play Music Search":                    "Play Music Search",
+		"config.Navigate":                             "Navigate",
+		"config.Search Nearby":                        "Search Nearby",
+		"config.Show Map":                             "Show Map",
+		"config.Get Current Location":                 "Get Current Location",
+		"config.Flashlight":                           "Flashlight",
+		"config.Set Volume":                           "Set Volume",
+		"config.Set Ringer Mode":                      "Set Ringer Mode",
+		"config.Set DND":                              "Set DND",
+		"config.Set Brightness":                       "Set Brightness",
+		"config.Open Settings":                        "Open Settings",
+		"config.Open URL":                             "Open URL",
+		"config.Copy":                                 "Copy",
+		"config.Read":                                 "Read",
 	}
 }
@@ -27,6 +27,12 @@ func init() {
 		"status.listing_dir":   "Checking folder...",
 		"status.listing_dir_q": "Checking folder... (%s)",
 
+		// search
+		"status.searching_files":   "Searching files...",
+		"status.searching_files_q": "Searching files... (%s)",
+		"status.finding_files":     "Finding files...",
+		"status.finding_files_q":   "Finding files... (%s)",
+
 		// exec
 		"status.running_command":   "Running command...",
 		"status.running_command_q": "Running command... (%s)",
@@ -115,6 +121,12 @@ func init() {
 		"status.listing_dir":   "フォルダ確認中...",
 		"status.listing_dir_q": "フォルダ確認中...（%s）",
 
+		// search
+		"status.searching_files":   "ファイル内検索中...",
+		"status.searching_files_q": "ファイル内検索中...（%s）",
+		"status.finding_files":     "ファイル検索中...",
+		"status.finding_files_q":   "ファイル検索中...（%s）",
+
 		// exec
 		"status.running_command":   "コマンド実行中...",
 		"status.running_command_q": "コマンド実行中...（%s）",
@@ -15,6 +15,7 @@ import (
 // AnyLLMAdapter wraps an any-llm-go provider to implement LLMProvider.
 type AnyLLMAdapter struct {
 	provider     anyllm.Provider // any-llm-go Provider interface
+	providerName string          // canonical provider family, e.g. "openai"
 	defaultModel string          // e.g. "openai/gpt-5.2-chat-latest"
 	modelName    string          // e.g. "gpt-5.2-chat-latest" (passed per-request)
 }
@@ -34,15 +35,41 @@ var providerAliases = map[string]string{
 	"google": "gemini",
 }
 
-// NewAnyLLMAdapter creates an AnyLLMAdapter from a model string (provider/model_name),
-// an API key, and an optional base URL override.
-func NewAnyLLMAdapter(model, apiKey, baseURL string) (*AnyLLMAdapter, error) {
-	providerName, modelName := parseModel(model)
+// providerConstructors creates the supported any-llm-go providers by
+// canonical name. It is the one list of supported providers: ValidateModel
+// and createAnyLLMProvider both use it.
+var providerConstructors = map[string]func(opts ...anyllm.Option) (anyllm.Provider, error){
+	"anthropic": func(opts ...anyllm.Option) (anyllm.Provider, error) { return anthropic.New(opts...) },
+	"gemini":    func(opts ...anyllm.Option) (anyllm.Provider, error) { return gemini.New(opts...) },
+	"openai":    func(opts ...anyllm.Option) (anyllm.Provider, error) { return openai.New(opts...) },
+}
 
-	// Apply aliases
+// canonicalModel splits model and resolves provider aliases.
+func canonicalModel(model string) (providerName, modelName string) {
+	providerName, modelName = parseModel(model)
 	if canonical, ok := providerAliases[providerName]; ok {
 		providerName = canonical
 	}
+	return providerName, modelName
+}
+
+// ValidateModel checks that model is in provider/model_name format and names
+// a supported provider.
+func ValidateModel(model string) error {
+	providerName, modelName := canonicalModel(model)
+	if providerName == "" || modelName == "" {
+		return fmt.Errorf("model must be in provider/model_name format (e.g. openai/gpt-4): %s", model)
+	}
+	if _, ok := providerConstructors[providerName]; !ok {
+		return fmt.Errorf("unsupported provider %q", providerName)
+	}
+	return nil
+}
+
+// NewAnyLLMAdapter creates an AnyLLMAdapter from a model string (provider/model_name),
+// an API key, and an optional base URL override.
+func NewAnyLLMAdapter(model, apiKey, baseURL string) (*AnyLLMAdapter, error) {
+	providerName, modelName := canonicalModel(model)
 
 	if providerName == "" {
 		return nil, fmt.Errorf("model must be in provider/model_name format (e.g. openai/gpt-4): %s", model)
@@ -55,6 +82,7 @@ func NewAnyLLMAdapter(model, apiKey, baseURL string) (*AnyLLMAdapter, error) {
 
 	return &AnyLLMAdapter{
 		provider:     p,
+		providerName: providerName,
 		defaultModel: model,
 		modelName:    modelName,
 	}, nil
@@ -70,22 +98,83 @@ func createAnyLLMProvider(name, apiKey, baseURL string) (anyllm.Provider, error)
 		opts = append(opts, anyllm.WithBaseURL(baseURL))
 	}
 
-	switch name {
-	case "anthropic":
-		return anthropic.New(opts...)
-	case "gemini":
-		return gemini.New(opts...)
-	case "openai":
-		return openai.New(opts...)
-	default:
+	newProvider, ok := providerConstructors[name]
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider %q (use openai with base_url for OpenAI-compatible providers)", name)
 	}
+	return newProvider(opts...)
 }
 
 // Chat implements LLMProvider.
 func (a *AnyLLMAdapter) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	model, modelName, err := a.resolveModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.provider.Completion(ctx, a.buildParams(modelName, messages, tools, options))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := convertAnyLLMResult(result)
+	resp.Model = model
+	return resp, nil
+}
+
+// ChatStream implements StreamingProvider.
+func (a *AnyLLMAdapter) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	model, modelName, err := a.resolveModel(model)
+	if err != nil {
+		return nil, err
+	}
+
+	params := a.buildParams(modelName, messages, tools, options)
+	params.Stream = true
+	params.StreamOptions = &anyllm.StreamOptions{IncludeUsage: true}
+
+	chunks, errs := a.provider.CompletionStream(ctx, params)
+
+	var acc streamAccumulator
+	for chunk := range chunks {
+		if delta := acc.add(chunk); delta != "" && onDelta != nil {
+			onDelta(delta)
+		}
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	// Providers stop streaming silently on cancellation.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resp := acc.response()
+	resp.Model = model
+	return resp, nil
+}
+
+// resolveModel maps the requested model to the name sent to the API. An empty
+// model selects the adapter's default; a model from another provider family
+// is rejected since this adapter's client cannot serve it.
+func (a *AnyLLMAdapter) resolveModel(model string) (fullName, modelName string, err error) {
+	if model == "" || model == a.defaultModel {
+		return a.defaultModel, a.modelName, nil
+	}
+	providerName, modelName := canonicalModel(model)
+	if providerName != "" && providerName != a.providerName {
+		return "", "", fmt.Errorf("model %q is not served by provider %q", model, a.providerName)
+	}
+	if providerName == "" {
+		model = a.providerName + "/" + modelName
+	}
+	return model, modelName, nil
+}
+
+// buildParams converts messages, tools, and options into request parameters.
+func (a *AnyLLMAdapter) buildParams(modelName string, messages []Message, tools []ToolDefinition, options map[string]interface{}) anyllm.CompletionParams {
 	params := anyllm.CompletionParams{
-		Model:    a.modelName,
+		Model:    modelName,
 		Messages: convertMessagesToAnyLLM(messages),
 		Tools:    convertToolsToAnyLLM(tools),
 	}
@@ -100,13 +189,11 @@ func (a *AnyLLMAdapter) Chat(ctx context.Context, messages []Message, tools []To
 	if temperature, ok := options["temperature"].(float64); ok {
 		params.Temperature = &temperature
 	}
-
-	result, err := a.provider.Completion(ctx, params)
-	if err != nil {
-		return nil, err
+	if choice, ok := options["tool_choice"].(string); ok && choice != "" && len(tools) > 0 {
+		params.ToolChoice = choice
 	}
 
-	return convertAnyLLMResult(result), nil
+	return params
 }
 
 // GetDefaultModel implements LLMProvider.
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"testing"
+
+	anyllm "github.com/mozilla-ai/any-llm-go"
+)
+
+func TestConvertMessagesToAnyLLM_ToolResultImages(t *testing.T) {
+	image := "data:image/png;base64,iVBORw0KGgo="
+	got := convertMessagesToAnyLLM([]Message{
+		{Role: "tool", Content: "Read image a.png\n[Image: /data/media/a.png]", ToolCallID: "call_1"},
+		{Role: "user", Content: "[System: Images returned by the tool calls above.]", Media: []string{image}},
+	})
+	if len(got) != 2 {
+		t.Fatalf("converted %d messages, want 2", len(got))
+	}
+
+	if got[0].Role != anyllm.RoleTool || got[0].ToolCallID != "call_1" || got[0].Content != "Read image a.png\n[Image: /data/media/a.png]" {
+		t.Errorf("tool message = %+v", got[0])
+	}
+	parts, ok := got[1].Content.([]anyllm.ContentPart)
+	if !ok || len(parts) != 2 {
+		t.Fatalf("image message content = %#v, want text and image parts", got[1].Content)
+	}
+	if parts[0].Type != "text" || parts[1].Type != "image_url" || parts[1].ImageURL == nil || parts[1].ImageURL.URL != image {
+		t.Errorf("image message parts = %+v", parts)
+	}
+}
+
+func TestValidateModel_MatchesConstructors(t *testing.T) {
+	for name := range providerConstructors {
+		if err := ValidateModel(name + "/some-model"); err != nil {
+			t.Errorf("ValidateModel(%s) = %v", name, err)
+		}
+		if _, err := createAnyLLMProvider(name, "test-key", ""); err != nil {
+			t.Errorf("createAnyLLMProvider(%s) = %v", name, err)
+		}
+	}
+	if err := ValidateModel("claude/some-model"); err != nil {
+		t.Errorf("alias rejected: %v", err)
+	}
+	for _, model := range []string{"unknown/some-model", "some-model", "openai/"} {
+		if err := ValidateModel(model); err == nil {
+			t.Errorf("ValidateModel(%q) accepted", model)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Cassette modes for LLMConfig.CassetteMode.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// cassetteRequest is the part of a call that identifies it in a cassette.
+type cassetteRequest struct {
+	Model    string                 `json:"model"`
+	Messages []Message              `json:"messages"`
+	Tools    []ToolDefinition       `json:"tools,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// cassetteEntry is one line of a JSONL cassette.
+type cassetteEntry struct {
+	Hash     string          `json:"hash"`
+	Request  cassetteRequest `json:"request"`
+	Response *LLMResponse    `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// RequestHash returns the cassette key of a call. System messages are left
+// out since the system prompt embeds the current time and workspace path,
+// and tool definitions are compared regardless of order; everything else,
+// including tool call IDs, is part of the key.
+func RequestHash(model string, messages []Message, tools []ToolDefinition, options map[string]interface{}) string {
+	sorted := slices.Clone(tools)
+	slices.SortFunc(sorted, func(a, b ToolDefinition) int {
+		return strings.Compare(a.Function.Name, b.Function.Name)
+	})
+
+	req := cassetteRequest{Model: model, Tools: sorted, Options: options}
+	for _, m := range messages {
+		if m.Role != "system" {
+			req.Messages = append(req.Messages, m)
+		}
+	}
+	// encoding/json sorts map keys, so equal requests marshal identically.
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordingProvider wraps a provider and appends every request/response pair
+// to a JSONL cassette.
+type RecordingProvider struct {
+	inner LLMProvider
+	path  string
+	mu    sync.Mutex
+}
+
+// NewRecordingProvider creates a RecordingProvider writing to path.
+func NewRecordingProvider(inner LLMProvider, path string) *RecordingProvider {
+	return &RecordingProvider{inner: inner, path: path}
+}
+
+// Chat implements LLMProvider.
+func (r *RecordingProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	resp, err := r.inner.Chat(ctx, messages, tools, model, options)
+	r.record(messages, tools, model, options, resp, err)
+	return resp, err
+}
+
+// ChatStream implements StreamingProvider. Only the final response is recorded.
+func (r *RecordingProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	sp, ok := r.inner.(StreamingProvider)
+	if !ok {
+		return r.Chat(ctx, messages, tools, model, options)
+	}
+	resp, err := sp.ChatStream(ctx, messages, tools, model, options, onDelta)
+	r.record(messages, tools, model, options, resp, err)
+	return resp, err
+}
+
+// GetDefaultModel implements LLMProvider.
+func (r *RecordingProvider) GetDefaultModel() string {
+	return r.inner.GetDefaultModel()
+}
+
+// record appends one entry. Cancelled calls are not recorded since they say
+// nothing about how the model answers.
+func (r *RecordingProvider) record(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, resp *LLMResponse, callErr error) {
+	if errors.Is(callErr, context.Canceled) || errors.Is(callErr, context.DeadlineExceeded) {
+		return
+	}
+
+	entry := cassetteEntry{
+		Hash:     RequestHash(model, messages, tools, options),
+		Request:  cassetteRequest{Model: model, Messages: messages, Tools: tools, Options: options},
+		Response: resp,
+	}
+	if callErr != nil {
+		entry.Response = nil
+		entry.Error = callErr.Error()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = os.MkdirAll(filepath.Dir(r.path), 0755)
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_, _ = f.Write(append(data, '\n'))
+}
+
+// ReplayProvider serves responses from a cassette by request hash, without
+// network access. Repeated identical requests get the recorded responses in
+// order; the last one is reused once they run out.
+type ReplayProvider struct {
+	defaultModel string
+	mu           sync.Mutex
+	entries      map[string][]cassetteEntry
+	served       map[string]int
+}
+
+// NewReplayProvider loads the cassette at path.
+func NewReplayProvider(path, defaultModel string) (*ReplayProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening cassette: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	p := &ReplayProvider{
+		defaultModel: defaultModel,
+		entries:      make(map[string][]cassetteEntry),
+		served:       make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry cassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("cassette line %d: %w", line, err)
+		}
+		p.entries[entry.Hash] = append(p.entries[entry.Hash], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+	return p, nil
+}
+
+// Chat implements LLMProvider.
+func (p *ReplayProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	hash := RequestHash(model, messages, tools, options)
+
+	p.mu.Lock()
+	recorded := p.entries[hash]
+	n := p.served[hash]
+	p.served[hash] = n + 1
+	p.mu.Unlock()
+
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("cassette has no response for request %s", hash[:12])
+	}
+	entry := recorded[min(n, len(recorded)-1)]
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	if entry.Response == nil {
+		return nil, fmt.Errorf("cassette entry %s has no response", hash[:12])
+	}
+	resp := *entry.Response
+	// Empty arguments are dropped by omitempty when recording; restore them so
+	// the agent echoes "{}" back exactly as it did against the live provider.
+	resp.ToolCalls = slices.Clone(resp.ToolCalls)
+	for i := range resp.ToolCalls {
+		if resp.ToolCalls[i].Arguments == nil {
+			resp.ToolCalls[i].Arguments = map[string]interface{}{}
+		}
+	}
+	return &resp, nil
+}
+
+// ChatStream implements StreamingProvider, delivering the recorded content
+// as a single delta.
+func (p *ReplayProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	resp, err := p.Chat(ctx, messages, tools, model, options)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content != "" && onDelta != nil {
+		onDelta(resp.Content)
+	}
+	return resp, nil
+}
+
+// GetDefaultModel implements LLMProvider.
+func (p *ReplayProvider) GetDefaultModel() string {
+	return p.defaultModel
+}
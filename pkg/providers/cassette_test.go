@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequestHash_IgnoresSystemMessages(t *testing.T) {
+	a := []Message{{Role: "system", Content: "now: 10:00"}, {Role: "user", Content: "hi"}}
+	b := []Message{{Role: "system", Content: "now: 10:01"}, {Role: "user", Content: "hi"}}
+	c := []Message{{Role: "system", Content: "now: 10:00"}, {Role: "user", Content: "hello"}}
+	opts := map[string]interface{}{"max_tokens": 1024}
+
+	if RequestHash("m", a, nil, opts) != RequestHash("m", b, nil, opts) {
+		t.Error("hash should not depend on the system prompt")
+	}
+	if RequestHash("m", a, nil, opts) == RequestHash("m", c, nil, opts) {
+		t.Error("hash should depend on user messages")
+	}
+	if RequestHash("m", a, nil, opts) == RequestHash("other", a, nil, opts) {
+		t.Error("hash should depend on the model")
+	}
+	// Options read back from JSON are float64 but must hash the same
+	if RequestHash("m", a, nil, opts) != RequestHash("m", a, nil, map[string]interface{}{"max_tokens": 1024.0}) {
+		t.Error("hash should not depend on the numeric type of options")
+	}
+}
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "conv.jsonl")
+	live := &scriptedProvider{content: "recorded answer"}
+	rec := NewRecordingProvider(live, path)
+
+	msgs := []Message{{Role: "user", Content: "question"}}
+	if _, err := rec.Chat(context.Background(), msgs, nil, "openai/gpt-test", nil); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	live.err = errors.New("429 rate limit")
+	errMsgs := []Message{{Role: "user", Content: "fails"}}
+	_, _ = rec.Chat(context.Background(), errMsgs, nil, "openai/gpt-test", nil)
+
+	replay, err := NewReplayProvider(path, "openai/gpt-test")
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+
+	var streamed string
+	resp, err := replay.ChatStream(context.Background(), msgs, nil, "openai/gpt-test", nil, func(d string) { streamed += d })
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if resp.Content != "recorded answer" || streamed != "recorded answer" {
+		t.Errorf("replayed (%q, streamed %q), want the recorded answer", resp.Content, streamed)
+	}
+
+	if _, err := replay.Chat(context.Background(), errMsgs, nil, "openai/gpt-test", nil); ClassifyError(err) != ErrorClassRateLimit {
+		t.Errorf("expected recorded rate limit error, got %v", err)
+	}
+
+	_, err = replay.Chat(context.Background(), []Message{{Role: "user", Content: "unknown"}}, nil, "openai/gpt-test", nil)
+	if err == nil || !strings.Contains(err.Error(), "no response") {
+		t.Errorf("expected missing-entry error, got %v", err)
+	}
+}
+
+func TestReplayProvider_RepeatedRequestsInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conv.jsonl")
+	live := &scriptedProvider{content: "first"}
+	rec := NewRecordingProvider(live, path)
+	msgs := []Message{{Role: "user", Content: "same"}}
+
+	_, _ = rec.Chat(context.Background(), msgs, nil, "", nil)
+	live.content = "second"
+	_, _ = rec.Chat(context.Background(), msgs, nil, "", nil)
+
+	replay, err := NewReplayProvider(path, "")
+	if err != nil {
+		t.Fatalf("NewReplayProvider failed: %v", err)
+	}
+	for _, want := range []string{"first", "second", "second"} {
+		resp, err := replay.Chat(context.Background(), msgs, nil, "", nil)
+		if err != nil {
+			t.Fatalf("Chat failed: %v", err)
+		}
+		if resp.Content != want {
+			t.Errorf("Content = %q, want %q", resp.Content, want)
+		}
+	}
+}
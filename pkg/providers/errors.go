@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	anyllm "github.com/mozilla-ai/any-llm-go"
+)
+
+// ErrorClass categorizes an LLM call failure.
+type ErrorClass string
+
+const (
+	ErrorClassUnknown       ErrorClass = "unknown"
+	ErrorClassCanceled      ErrorClass = "canceled"
+	ErrorClassRateLimit     ErrorClass = "rate_limit"
+	ErrorClassAuth          ErrorClass = "auth"
+	ErrorClassOverloaded    ErrorClass = "overloaded"
+	ErrorClassContextLength ErrorClass = "context_length"
+	ErrorClassContentFilter ErrorClass = "content_filter"
+)
+
+// ClassifyError maps a provider error to an ErrorClass. Typed any-llm-go errors
+// are checked first; the error text is used as a fallback for providers that
+// surface plain errors.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorClassUnknown
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassCanceled
+	case errors.Is(err, anyllm.ErrRateLimit):
+		return ErrorClassRateLimit
+	case errors.Is(err, anyllm.ErrAuthentication), errors.Is(err, anyllm.ErrMissingAPIKey):
+		return ErrorClassAuth
+	case errors.Is(err, anyllm.ErrContextLength):
+		return ErrorClassContextLength
+	case errors.Is(err, anyllm.ErrContentFilter):
+		return ErrorClassContentFilter
+	}
+
+	var provErr *anyllm.ProviderError
+	if errors.As(err, &provErr) {
+		switch {
+		case provErr.StatusCode == 429:
+			return ErrorClassRateLimit
+		case provErr.StatusCode >= 500:
+			return ErrorClassOverloaded
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, "429", "rate limit", "rate_limit", "too many requests", "quota"):
+		return ErrorClassRateLimit
+	case containsAny(msg, "401", "403", "unauthorized", "forbidden", "invalid api key", "authentication"):
+		return ErrorClassAuth
+	case containsAny(msg, "context length", "context_length", "maximum context", "too many tokens", "prompt is too long"):
+		return ErrorClassContextLength
+	case containsAny(msg, "content filter", "content_filter", "safety", "blocked"):
+		return ErrorClassContentFilter
+	case containsAny(msg, "overloaded", "unavailable", "500", "502", "503", "504", "529", "timeout"):
+		return ErrorClassOverloaded
+	}
+
+	return ErrorClassUnknown
+}
+
+func containsAny(s string, subs ...string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
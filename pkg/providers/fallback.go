@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+const (
+	fallbackBaseDelay = 500 * time.Millisecond
+	fallbackMaxDelay  = 8 * time.Second
+)
+
+// FallbackModel is one entry of a fallback chain.
+type FallbackModel struct {
+	Model    string // "provider/model_name"
+	Provider LLMProvider
+}
+
+// FallbackProvider tries an ordered chain of models, moving to the next entry
+// when a call fails with an error that another model may not hit. The model
+// that answered is recorded in LLMResponse.Model.
+type FallbackProvider struct {
+	models    []FallbackModel
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// NewFallbackProvider creates a FallbackProvider. The first entry is the
+// primary model.
+func NewFallbackProvider(models []FallbackModel) *FallbackProvider {
+	return &FallbackProvider{
+		models:    models,
+		baseDelay: fallbackBaseDelay,
+		maxDelay:  fallbackMaxDelay,
+	}
+}
+
+// Chat implements LLMProvider.
+func (f *FallbackProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	return f.run(ctx, func(m FallbackModel) (*LLMResponse, error) {
+		return m.Provider.Chat(ctx, messages, tools, m.Model, options)
+	}, nil)
+}
+
+// ChatStream implements StreamingProvider. Once any text has been streamed,
+// a failure is returned as-is rather than restarting the answer on another model.
+func (f *FallbackProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	streamed := false
+	return f.run(ctx, func(m FallbackModel) (*LLMResponse, error) {
+		sp, ok := m.Provider.(StreamingProvider)
+		if !ok {
+			return m.Provider.Chat(ctx, messages, tools, m.Model, options)
+		}
+		return sp.ChatStream(ctx, messages, tools, m.Model, options, func(delta string) {
+			streamed = true
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+	}, func() bool { return !streamed })
+}
+
+// GetDefaultModel implements LLMProvider.
+func (f *FallbackProvider) GetDefaultModel() string {
+	if len(f.models) == 0 {
+		return ""
+	}
+	return f.models[0].Model
+}
+
+// run calls each model in order until one succeeds. canRetry, when set,
+// vetoes moving on after a failure.
+func (f *FallbackProvider) run(ctx context.Context, call func(FallbackModel) (*LLMResponse, error), canRetry func() bool) (*LLMResponse, error) {
+	var lastErr error
+	for i, m := range f.models {
+		resp, err := call(m)
+		if err == nil {
+			if resp.Model == "" {
+				resp.Model = m.Model
+			}
+			if i > 0 {
+				logger.InfoCF("providers", "Fallback model answered",
+					map[string]interface{}{
+						"model":   m.Model,
+						"primary": f.models[0].Model,
+					})
+			}
+			return resp, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		class := ClassifyError(err)
+		if !shouldFallback(class) || (canRetry != nil && !canRetry()) {
+			return nil, err
+		}
+		if i == len(f.models)-1 {
+			break
+		}
+
+		logger.WarnCF("providers", "LLM call failed, trying next model",
+			map[string]interface{}{
+				"model":      m.Model,
+				"next_model": f.models[i+1].Model,
+				"class":      string(class),
+				"error":      err.Error(),
+			})
+
+		if delay := f.backoff(class, i); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+
+	if len(f.models) > 1 {
+		return nil, fmt.Errorf("all %d models failed: %w", len(f.models), lastErr)
+	}
+	return nil, lastErr
+}
+
+// shouldFallback reports whether another model may succeed where this one failed.
+// Unrecognized errors, such as invalid requests, would fail the same way on
+// every model, and context-length errors are left to the caller, which
+// compresses history instead.
+func shouldFallback(class ErrorClass) bool {
+	switch class {
+	case ErrorClassRateLimit, ErrorClassOverloaded, ErrorClassAuth, ErrorClassContentFilter:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before trying the entry after attempt.
+// Auth and content-filter failures move on immediately since waiting
+// does not change the outcome.
+func (f *FallbackProvider) backoff(class ErrorClass, attempt int) time.Duration {
+	switch class {
+	case ErrorClassAuth, ErrorClassContentFilter:
+		return 0
+	}
+	delay := f.baseDelay << attempt
+	if delay > f.maxDelay {
+		delay = f.maxDelay
+	}
+	return delay
+}
@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	llmerrors "github.com/mozilla-ai/any-llm-go/errors"
+)
+
+// scriptedProvider returns err when set, otherwise a response with content.
+type scriptedProvider struct {
+	content string
+	err     error
+	deltas  []string
+	calls   int
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &LLMResponse{Content: p.content}, nil
+}
+
+func (p *scriptedProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	p.calls++
+	for _, d := range p.deltas {
+		onDelta(d)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &LLMResponse{Content: p.content}, nil
+}
+
+func (p *scriptedProvider) GetDefaultModel() string { return "" }
+
+func newTestFallback(models ...FallbackModel) *FallbackProvider {
+	f := NewFallbackProvider(models)
+	f.baseDelay = 0
+	return f
+}
+
+func TestFallbackProvider_MovesToNextOnRateLimit(t *testing.T) {
+	primary := &scriptedProvider{err: llmerrors.NewRateLimitError("openai", errors.New("slow down"))}
+	backup := &scriptedProvider{content: "from backup"}
+	f := newTestFallback(
+		FallbackModel{Model: "openai/gpt-test", Provider: primary},
+		FallbackModel{Model: "anthropic/claude-test", Provider: backup},
+	)
+
+	resp, err := f.Chat(context.Background(), nil, nil, "", nil)
+	if err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if resp.Content != "from backup" {
+		t.Errorf("Content = %q, want %q", resp.Content, "from backup")
+	}
+	if resp.Model != "anthropic/claude-test" {
+		t.Errorf("Model = %q, want the fallback model", resp.Model)
+	}
+}
+
+func TestFallbackProvider_ContextLengthIsNotRetried(t *testing.T) {
+	primary := &scriptedProvider{err: llmerrors.NewContextLengthError("openai", errors.New("too long"))}
+	backup := &scriptedProvider{content: "from backup"}
+	f := newTestFallback(
+		FallbackModel{Model: "openai/gpt-test", Provider: primary},
+		FallbackModel{Model: "gemini/gemini-test", Provider: backup},
+	)
+
+	_, err := f.Chat(context.Background(), nil, nil, "", nil)
+	if ClassifyError(err) != ErrorClassContextLength {
+		t.Errorf("expected context length error, got %v", err)
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times, want 0", backup.calls)
+	}
+}
+
+func TestFallbackProvider_InvalidRequestIsNotRetried(t *testing.T) {
+	badRequest := &llmerrors.ProviderError{StatusCode: 400}
+	badRequest.Err = errors.New("400 invalid schema for function 'read_file'")
+	for _, primaryErr := range []error{
+		errors.New("something odd"),
+		badRequest,
+		llmerrors.NewInvalidRequestError("openai", errors.New("invalid tool schema")),
+	} {
+		primary := &scriptedProvider{err: primaryErr}
+		backup := &scriptedProvider{content: "from backup"}
+		f := NewFallbackProvider([]FallbackModel{
+			{Model: "openai/gpt-test", Provider: primary},
+			{Model: "gemini/gemini-test", Provider: backup},
+		})
+
+		start := time.Now()
+		_, err := f.Chat(context.Background(), nil, nil, "", nil)
+		if !errors.Is(err, primaryErr) {
+			t.Errorf("error = %v, want %v returned as-is", err, primaryErr)
+		}
+		if backup.calls != 0 {
+			t.Errorf("%v: backup called %d times, want 0", primaryErr, backup.calls)
+		}
+		if elapsed := time.Since(start); elapsed > fallbackBaseDelay/2 {
+			t.Errorf("%v: returned after %v, want no backoff", primaryErr, elapsed)
+		}
+	}
+}
+
+func TestFallbackProvider_AllFail(t *testing.T) {
+	f := newTestFallback(
+		FallbackModel{Model: "openai/a", Provider: &scriptedProvider{err: errors.New("503 service unavailable")}},
+		FallbackModel{Model: "openai/b", Provider: &scriptedProvider{err: errors.New("503 service unavailable")}},
+	)
+
+	_, err := f.Chat(context.Background(), nil, nil, "", nil)
+	if err == nil || !strings.Contains(err.Error(), "all 2 models failed") {
+		t.Errorf("expected all-models error, got %v", err)
+	}
+}
+
+func TestFallbackProvider_NoFallbackAfterStreamedText(t *testing.T) {
+	primary := &scriptedProvider{deltas: []string{"partial"}, err: errors.New("503 overloaded")}
+	backup := &scriptedProvider{content: "from backup"}
+	f := newTestFallback(
+		FallbackModel{Model: "openai/a", Provider: primary},
+		FallbackModel{Model: "openai/b", Provider: backup},
+	)
+
+	var got string
+	_, err := f.ChatStream(context.Background(), nil, nil, "", nil, func(d string) { got += d })
+	if err == nil {
+		t.Fatal("expected error once text was streamed")
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup called %d times, want 0", backup.calls)
+	}
+	if got != "partial" {
+		t.Errorf("streamed %q, want %q", got, "partial")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{llmerrors.NewRateLimitError("openai", errors.New("x")), ErrorClassRateLimit},
+		{llmerrors.NewAuthenticationError("openai", errors.New("x")), ErrorClassAuth},
+		{llmerrors.NewContextLengthError("openai", errors.New("x")), ErrorClassContextLength},
+		{llmerrors.NewContentFilterError("openai", errors.New("x")), ErrorClassContentFilter},
+		{fmt.Errorf("wrapped: %w", context.Canceled), ErrorClassCanceled},
+		{errors.New("HTTP 429 Too Many Requests"), ErrorClassRateLimit},
+		{errors.New("529 overloaded_error"), ErrorClassOverloaded},
+		{errors.New("something odd"), ErrorClassUnknown},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -1,12 +1,76 @@
 package providers
 
 import (
+	"fmt"
+
 	"github.com/KarakuriAgent/clawdroid/pkg/config"
 )
 
 // CreateProvider is the single entry point for constructing an LLMProvider.
 // When replacing the underlying LLM library, modify only this function
 // and the adapter it delegates to (currently AnyLLMAdapter).
+//
+// The returned provider is a ModelRouter, so callers may request any
+// provider/model_name per call, including the models of named profiles.
+// The configured model goes through a FallbackProvider when fallback models
+// are configured. With a cassette mode set, calls are recorded to or replayed
+// from the cassette file instead.
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
-	return NewAnyLLMAdapter(cfg.LLM.Model, cfg.LLM.APIKey, cfg.LLM.BaseURL)
+	if cfg.LLM.CassetteMode != "" && cfg.LLM.Cassette == "" {
+		return nil, fmt.Errorf("cassette mode %q requires a cassette path", cfg.LLM.CassetteMode)
+	}
+
+	switch cfg.LLM.CassetteMode {
+	case "":
+		return createLiveProvider(cfg)
+	case CassetteReplay:
+		return NewReplayProvider(cfg.LLM.Cassette, cfg.LLM.Model)
+	case CassetteRecord:
+		p, err := createLiveProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewRecordingProvider(p, cfg.LLM.Cassette), nil
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q (use %q or %q)", cfg.LLM.CassetteMode, CassetteRecord, CassetteReplay)
+	}
+}
+
+// createLiveProvider builds the provider that calls the configured LLM APIs.
+func createLiveProvider(cfg *config.Config) (LLMProvider, error) {
+	primary, err := NewAnyLLMAdapter(cfg.LLM.Model, cfg.LLM.APIKey, cfg.LLM.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := []ModelCredentials{{Model: cfg.LLM.Model, APIKey: cfg.LLM.APIKey, BaseURL: cfg.LLM.BaseURL}}
+	var defaultProvider LLMProvider = primary
+	if len(cfg.LLM.Fallbacks) > 0 {
+		models := []FallbackModel{{Model: cfg.LLM.Model, Provider: primary}}
+		for i, fb := range cfg.LLM.Fallbacks {
+			if fb.Model == "" {
+				continue
+			}
+			p, err := NewAnyLLMAdapter(fb.Model, fb.APIKey, fb.BaseURL)
+			if err != nil {
+				return nil, fmt.Errorf("fallback model %d (%s): %w", i+1, fb.Model, err)
+			}
+			models = append(models, FallbackModel{Model: fb.Model, Provider: p})
+			credentials = append(credentials, ModelCredentials{Model: fb.Model, APIKey: fb.APIKey, BaseURL: fb.BaseURL})
+		}
+		defaultProvider = NewFallbackProvider(models)
+	}
+
+	for _, name := range cfg.LLM.ProfileNames() {
+		p := cfg.LLM.Profiles[name]
+		if p.Model == "" {
+			continue
+		}
+		if err := ValidateModel(p.Model); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		credentials = append(credentials, ModelCredentials{Model: p.Model, APIKey: p.APIKey, BaseURL: p.BaseURL})
+	}
+
+	return NewModelRouter(defaultProvider, cfg.LLM.Model, credentials), nil
 }
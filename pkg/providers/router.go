@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ModelCredentials is the API key and base URL configured for a model.
+type ModelCredentials struct {
+	Model   string // "provider/model_name"
+	APIKey  string
+	BaseURL string
+}
+
+// ModelRouter dispatches each call to a provider for the requested model.
+// Calls for the default model (or no model) go to the default provider,
+// which may be a fallback chain. Any other model gets an adapter built on
+// first use, so a session can switch to another provider family at runtime.
+type ModelRouter struct {
+	defaultProvider LLMProvider
+	defaultModel    string
+	credentials     []ModelCredentials
+
+	mu       sync.Mutex
+	adapters map[string]LLMProvider
+}
+
+// NewModelRouter creates a ModelRouter. credentials lists the configured
+// models; the first entry of the same provider family supplies the API key
+// and base URL for a model that is not listed itself.
+func NewModelRouter(defaultProvider LLMProvider, defaultModel string, credentials []ModelCredentials) *ModelRouter {
+	return &ModelRouter{
+		defaultProvider: defaultProvider,
+		defaultModel:    defaultModel,
+		credentials:     credentials,
+		adapters:        make(map[string]LLMProvider),
+	}
+}
+
+// Chat implements LLMProvider.
+func (r *ModelRouter) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	p, err := r.providerFor(model)
+	if err != nil {
+		return nil, err
+	}
+	return p.Chat(ctx, messages, tools, model, options)
+}
+
+// ChatStream implements StreamingProvider.
+func (r *ModelRouter) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error) {
+	p, err := r.providerFor(model)
+	if err != nil {
+		return nil, err
+	}
+	if sp, ok := p.(StreamingProvider); ok {
+		return sp.ChatStream(ctx, messages, tools, model, options, onDelta)
+	}
+	return p.Chat(ctx, messages, tools, model, options)
+}
+
+// GetDefaultModel implements LLMProvider.
+func (r *ModelRouter) GetDefaultModel() string {
+	return r.defaultModel
+}
+
+// providerFor returns the provider serving model, creating it if needed.
+func (r *ModelRouter) providerFor(model string) (LLMProvider, error) {
+	if model == "" || model == r.defaultModel {
+		return r.defaultProvider, nil
+	}
+	if err := ValidateModel(model); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if p, ok := r.adapters[model]; ok {
+		return p, nil
+	}
+
+	creds := r.credentialsFor(model)
+	p, err := NewAnyLLMAdapter(model, creds.APIKey, creds.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("model %s: %w", model, err)
+	}
+	r.adapters[model] = p
+	return p, nil
+}
+
+// credentialsFor picks the credentials for model: an exact match first, then
+// the first configured model from the same provider family. With neither,
+// the provider SDK falls back to its own environment variables.
+func (r *ModelRouter) credentialsFor(model string) ModelCredentials {
+	providerName, _ := canonicalModel(model)
+	var family *ModelCredentials
+	for i, c := range r.credentials {
+		if c.Model == model {
+			return c
+		}
+		if p, _ := canonicalModel(c.Model); p == providerName && family == nil {
+			family = &r.credentials[i]
+		}
+	}
+	if family != nil {
+		return ModelCredentials{Model: model, APIKey: family.APIKey, BaseURL: family.BaseURL}
+	}
+	return ModelCredentials{Model: model}
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"testing"
+)
+
+func TestModelRouter_DefaultModelUsesDefaultProvider(t *testing.T) {
+	def := &scriptedProvider{content: "default"}
+	r := NewModelRouter(def, "openai/gpt-test", nil)
+
+	for _, model := range []string{"", "openai/gpt-test"} {
+		resp, err := r.Chat(context.Background(), nil, nil, model, nil)
+		if err != nil {
+			t.Fatalf("Chat(%q) failed: %v", model, err)
+		}
+		if resp.Content != "default" {
+			t.Errorf("Chat(%q) content = %q, want %q", model, resp.Content, "default")
+		}
+	}
+	if len(r.adapters) != 0 {
+		t.Errorf("no adapter should be built for the default model, got %d", len(r.adapters))
+	}
+}
+
+func TestModelRouter_BuildsAdapterForOtherFamily(t *testing.T) {
+	r := NewModelRouter(&scriptedProvider{}, "openai/gpt-test", []ModelCredentials{
+		{Model: "openai/gpt-test", APIKey: "sk-openai"},
+		{Model: "gemini/gemini-test", APIKey: "gemini-key"},
+	})
+
+	p, err := r.providerFor("google/gemini-other")
+	if err != nil {
+		t.Fatalf("providerFor failed: %v", err)
+	}
+	adapter, ok := p.(*AnyLLMAdapter)
+	if !ok {
+		t.Fatalf("provider = %T, want *AnyLLMAdapter", p)
+	}
+	if adapter.providerName != "gemini" {
+		t.Errorf("providerName = %q, want %q", adapter.providerName, "gemini")
+	}
+
+	again, _ := r.providerFor("google/gemini-other")
+	if again != p {
+		t.Error("adapter should be cached per model")
+	}
+}
+
+func TestModelRouter_RejectsInvalidModel(t *testing.T) {
+	r := NewModelRouter(&scriptedProvider{}, "openai/gpt-test", nil)
+	if _, err := r.Chat(context.Background(), nil, nil, "nope", nil); err == nil {
+		t.Error("expected error for model without provider prefix")
+	}
+	if _, err := r.Chat(context.Background(), nil, nil, "mistral/large", nil); err == nil {
+		t.Error("expected error for unsupported provider")
+	}
+}
+
+func TestModelRouter_CredentialsFor(t *testing.T) {
+	r := NewModelRouter(nil, "openai/gpt-test", []ModelCredentials{
+		{Model: "openai/gpt-test", APIKey: "primary", BaseURL: "https://example.com/v1"},
+		{Model: "anthropic/claude-test", APIKey: "anthropic-key"},
+	})
+
+	tests := []struct {
+		model   string
+		wantKey string
+		wantURL string
+	}{
+		{"anthropic/claude-test", "anthropic-key", ""},
+		{"claude/claude-other", "anthropic-key", ""},
+		{"openai/gpt-other", "primary", "https://example.com/v1"},
+		{"gemini/gemini-test", "", ""},
+	}
+	for _, tt := range tests {
+		c := r.credentialsFor(tt.model)
+		if c.APIKey != tt.wantKey || c.BaseURL != tt.wantURL {
+			t.Errorf("credentialsFor(%q) = (%q, %q), want (%q, %q)", tt.model, c.APIKey, c.BaseURL, tt.wantKey, tt.wantURL)
+		}
+	}
+}
+
+func TestAnyLLMAdapter_ResolveModel(t *testing.T) {
+	a := &AnyLLMAdapter{providerName: "openai", defaultModel: "openai/gpt-test", modelName: "gpt-test"}
+
+	tests := []struct {
+		model    string
+		wantFull string
+		wantName string
+		wantErr  bool
+	}{
+		{"", "openai/gpt-test", "gpt-test", false},
+		{"openai/gpt-other", "openai/gpt-other", "gpt-other", false},
+		{"gpt-bare", "openai/gpt-bare", "gpt-bare", false},
+		{"anthropic/claude-test", "", "", true},
+	}
+	for _, tt := range tests {
+		full, name, err := a.resolveModel(tt.model)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveModel(%q) error = %v, wantErr %v", tt.model, err, tt.wantErr)
+			continue
+		}
+		if full != tt.wantFull || name != tt.wantName {
+			t.Errorf("resolveModel(%q) = (%q, %q), want (%q, %q)", tt.model, full, name, tt.wantFull, tt.wantName)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package providers
+
+import (
+	"strings"
+
+	anyllm "github.com/mozilla-ai/any-llm-go"
+)
+
+// streamAccumulator assembles streamed chunks into a complete response.
+//
+// Provider families deliver tool calls differently while streaming:
+//   - OpenAI sends the ID and name once, then argument fragments with an empty ID.
+//   - Anthropic resends the same ID with the arguments accumulated so far.
+//   - Gemini sends each tool call complete, in a single chunk.
+type streamAccumulator struct {
+	content      strings.Builder
+	toolCalls    []anyllm.ToolCall
+	finishReason string
+	usage        *anyllm.Usage
+}
+
+// add merges a chunk and returns the new text it carried, if any.
+func (s *streamAccumulator) add(chunk anyllm.ChatCompletionChunk) string {
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	var text strings.Builder
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != "" {
+			s.finishReason = choice.FinishReason
+		}
+		if choice.Delta.Content != "" {
+			text.WriteString(choice.Delta.Content)
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			s.addToolCall(tc)
+		}
+	}
+
+	s.content.WriteString(text.String())
+	return text.String()
+}
+
+// addToolCall merges a (possibly partial) tool call delta.
+func (s *streamAccumulator) addToolCall(tc anyllm.ToolCall) {
+	last := len(s.toolCalls) - 1
+
+	switch {
+	case tc.ID == "" && last >= 0:
+		// Argument fragment for the call in progress.
+		s.toolCalls[last].Function.Arguments += tc.Function.Arguments
+		if s.toolCalls[last].Function.Name == "" {
+			s.toolCalls[last].Function.Name = tc.Function.Name
+		}
+	case tc.ID != "" && last >= 0 && s.toolCalls[last].ID == tc.ID:
+		// Same call resent with cumulative arguments.
+		s.toolCalls[last].Function.Arguments = tc.Function.Arguments
+		if tc.Function.Name != "" {
+			s.toolCalls[last].Function.Name = tc.Function.Name
+		}
+	default:
+		s.toolCalls = append(s.toolCalls, tc)
+	}
+}
+
+// response returns the assembled response.
+func (s *streamAccumulator) response() *LLMResponse {
+	return convertAnyLLMResult(&anyllm.ChatCompletion{
+		Choices: []anyllm.Choice{{
+			Message: anyllm.Message{
+				Role:      anyllm.RoleAssistant,
+				Content:   s.content.String(),
+				ToolCalls: s.toolCalls,
+			},
+			FinishReason: s.finishReason,
+		}},
+		Usage: s.usage,
+	})
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"testing"
+
+	anyllm "github.com/mozilla-ai/any-llm-go"
+)
+
+func deltaChunk(delta anyllm.ChunkDelta, finish string) anyllm.ChatCompletionChunk {
+	return anyllm.ChatCompletionChunk{
+		Choices: []anyllm.ChunkChoice{{Delta: delta, FinishReason: finish}},
+	}
+}
+
+func toolDelta(id, name, args string) anyllm.ChunkDelta {
+	return anyllm.ChunkDelta{ToolCalls: []anyllm.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: anyllm.FunctionCall{Name: name, Arguments: args},
+	}}}
+}
+
+func TestStreamAccumulator_Text(t *testing.T) {
+	var acc streamAccumulator
+	var got string
+	for _, part := range []string{"Hel", "lo", ", world"} {
+		got += acc.add(deltaChunk(anyllm.ChunkDelta{Content: part}, ""))
+	}
+	acc.add(anyllm.ChatCompletionChunk{
+		Choices: []anyllm.ChunkChoice{{FinishReason: anyllm.FinishReasonStop}},
+		Usage:   &anyllm.Usage{PromptTokens: 10, CompletionTokens: 3, TotalTokens: 13},
+	})
+
+	if got != "Hello, world" {
+		t.Errorf("deltas = %q, want %q", got, "Hello, world")
+	}
+	resp := acc.response()
+	if resp.Content != "Hello, world" {
+		t.Errorf("Content = %q, want %q", resp.Content, "Hello, world")
+	}
+	if resp.FinishReason != anyllm.FinishReasonStop {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, anyllm.FinishReasonStop)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 13 {
+		t.Errorf("Usage = %+v, want TotalTokens 13", resp.Usage)
+	}
+}
+
+func TestStreamAccumulator_FragmentedToolCalls(t *testing.T) {
+	// OpenAI style: ID and name first, then argument fragments without ID.
+	var acc streamAccumulator
+	acc.add(deltaChunk(toolDelta("call_1", "read_file", ""), ""))
+	acc.add(deltaChunk(toolDelta("", "", `{"path":`), ""))
+	acc.add(deltaChunk(toolDelta("", "", `"a.txt"}`), ""))
+	acc.add(deltaChunk(toolDelta("call_2", "list_dir", `{"path":"."}`), ""))
+	acc.add(deltaChunk(anyllm.ChunkDelta{}, anyllm.FinishReasonToolCalls))
+
+	resp := acc.response()
+	if len(resp.ToolCalls) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(resp.ToolCalls))
+	}
+	if tc := resp.ToolCalls[0]; tc.ID != "call_1" || tc.Name != "read_file" || tc.Arguments["path"] != "a.txt" {
+		t.Errorf("first call = %+v", tc)
+	}
+	if tc := resp.ToolCalls[1]; tc.ID != "call_2" || tc.Name != "list_dir" || tc.Arguments["path"] != "." {
+		t.Errorf("second call = %+v", tc)
+	}
+}
+
+func TestStreamAccumulator_CumulativeToolCalls(t *testing.T) {
+	// Anthropic style: the same ID is resent with arguments accumulated so far.
+	var acc streamAccumulator
+	acc.add(deltaChunk(anyllm.ChunkDelta{Content: "Checking."}, ""))
+	acc.add(deltaChunk(toolDelta("toolu_1", "exec", `{"command":`), ""))
+	acc.add(deltaChunk(toolDelta("toolu_1", "exec", `{"command":"ls"}`), ""))
+
+	resp := acc.response()
+	if resp.Content != "Checking." {
+		t.Errorf("Content = %q, want %q", resp.Content, "Checking.")
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(resp.ToolCalls))
+	}
+	if got := resp.ToolCalls[0].Arguments["command"]; got != "ls" {
+		t.Errorf("command = %v, want %q", got, "ls")
+	}
+}
@@ -0,0 +1,313 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Per-item framing overheads, following OpenAI's published accounting.
+const (
+	messageOverhead  = 3 // Role and delimiters of each message
+	replyOverhead    = 3 // Priming of the assistant reply
+	toolCallOverhead = 4 // Call ID and function framing
+	toolDefOverhead  = 8 // Function wrapper of each tool definition
+)
+
+// TokenCounter counts tokens for one provider family.
+type TokenCounter interface {
+	// CountText returns the tokens of a plain text fragment.
+	CountText(text string) int
+	// ImageTokens returns the cost of one attached image.
+	ImageTokens() int
+}
+
+// NewTokenCounter returns the counter for a model ("provider/model_name").
+// OpenAI models use their BPE encoding once it is available in the BPE
+// directory (see SetBPEDir); other families, whose tokenizers are not
+// public, use character-based approximations tuned per family.
+func NewTokenCounter(model string) TokenCounter {
+	providerName, modelName := canonicalModel(model)
+	switch providerName {
+	case "openai":
+		if enc := bpeEncoding(openAIEncoding(modelName)); enc != nil {
+			return bpeCounter{enc: enc}
+		}
+		return approxCounter{charsPerToken: 4, wideCharsPerToken: 1, imageTokens: 765}
+	case "anthropic":
+		return approxCounter{charsPerToken: 3.5, wideCharsPerToken: 1, imageTokens: 1600}
+	case "gemini":
+		return approxCounter{charsPerToken: 4, wideCharsPerToken: 1.5, imageTokens: 258}
+	default:
+		return defaultCounter
+	}
+}
+
+// defaultCounter is a conservative 2.5 characters per token for unknown
+// families, which also covers CJK text.
+var defaultCounter = approxCounter{charsPerToken: 2.5, wideCharsPerToken: 2.5, imageTokens: 1000}
+
+// CountMessages returns the tokens a request uses: message contents, tool
+// calls and their arguments, attached images and tool definitions. The
+// system prompt is counted as the first message.
+func CountMessages(c TokenCounter, messages []Message, tools []ToolDefinition) int {
+	total := 0
+	for _, m := range messages {
+		total += countMessage(c, m)
+	}
+	if len(messages) > 0 {
+		total += replyOverhead
+	}
+	for _, t := range tools {
+		total += toolDefOverhead + c.CountText(t.Function.Name) + c.CountText(t.Function.Description)
+		if len(t.Function.Parameters) > 0 {
+			params, _ := json.Marshal(t.Function.Parameters)
+			total += c.CountText(string(params))
+		}
+	}
+	return total
+}
+
+func countMessage(c TokenCounter, m Message) int {
+	total := messageOverhead + c.CountText(m.Role) + c.CountText(m.Content)
+	total += len(m.Media) * c.ImageTokens()
+	for _, tc := range m.ToolCalls {
+		name, args := tc.Name, ""
+		if tc.Function != nil {
+			if name == "" {
+				name = tc.Function.Name
+			}
+			args = tc.Function.Arguments
+		}
+		if args == "" && len(tc.Arguments) > 0 {
+			data, _ := json.Marshal(tc.Arguments)
+			args = string(data)
+		}
+		total += toolCallOverhead + c.CountText(name) + c.CountText(args)
+	}
+	return total
+}
+
+// bpeCounter counts tokens exactly with a tiktoken encoding.
+type bpeCounter struct {
+	enc *tiktoken.Tiktoken
+}
+
+func (b bpeCounter) CountText(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(b.enc.EncodeOrdinary(text))
+}
+
+// ImageTokens is the cost of a 1024x1024 image at high detail.
+func (b bpeCounter) ImageTokens() int {
+	return 765
+}
+
+// approxCounter estimates tokens from character counts. Wide (CJK) runes
+// take far more tokens per character than Latin text, so they are weighted
+// separately.
+type approxCounter struct {
+	charsPerToken     float64
+	wideCharsPerToken float64
+	imageTokens       int
+}
+
+func (a approxCounter) CountText(text string) int {
+	if text == "" {
+		return 0
+	}
+	narrow, wide := 0, 0
+	for _, r := range text {
+		if isWideRune(r) {
+			wide++
+		} else {
+			narrow++
+		}
+	}
+	return int(float64(narrow)/a.charsPerToken + float64(wide)/a.wideCharsPerToken + 0.5)
+}
+
+func (a approxCounter) ImageTokens() int {
+	return a.imageTokens
+}
+
+func isWideRune(r rune) bool {
+	return r >= utf8.RuneSelf && (unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF))
+}
+
+// openAIEncoding returns the BPE encoding used by an OpenAI model. Legacy
+// GPT-4 and GPT-3.5 models use cl100k_base; newer ones use o200k_base.
+func openAIEncoding(model string) string {
+	if strings.HasPrefix(model, "gpt-3.5") || model == "gpt-4" || strings.HasPrefix(model, "gpt-4-") {
+		return tiktoken.MODEL_CL100K_BASE
+	}
+	return tiktoken.MODEL_O200K_BASE
+}
+
+var (
+	bpeMu        sync.Mutex
+	bpeDir       string
+	bpeEncodings = make(map[string]*tiktoken.Tiktoken)
+)
+
+// errBPEPending reports that an encoding file is not on disk yet.
+var errBPEPending = errors.New("BPE file not downloaded yet")
+
+// SetBPEDir sets the directory BPE encoding files are read from. A missing
+// file is downloaded there in the background on first use, and token counts
+// use the approximation until it arrives. With no directory set, OpenAI
+// models always use the approximation.
+func SetBPEDir(dir string) {
+	bpeMu.Lock()
+	defer bpeMu.Unlock()
+	bpeDir = dir
+	bpeEncodings = make(map[string]*tiktoken.Tiktoken)
+	tiktoken.SetBpeLoader(fileBPELoader{dir: dir})
+}
+
+// bpeEncoding loads an encoding from the BPE directory on first use and
+// caches it. It returns nil when the encoding is not available.
+func bpeEncoding(name string) *tiktoken.Tiktoken {
+	bpeMu.Lock()
+	defer bpeMu.Unlock()
+	if bpeDir == "" {
+		return nil
+	}
+	if enc, ok := bpeEncodings[name]; ok {
+		return enc
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if errors.Is(err, errBPEPending) {
+		return nil // Try again once the download finishes
+	}
+	if err != nil {
+		logger.WarnCF("providers", "Failed to load BPE encoding, using approximation",
+			map[string]interface{}{"encoding": name, "error": err.Error()})
+	}
+	bpeEncodings[name] = enc // Cache failures too, so loading is tried once
+	return enc
+}
+
+// fileBPELoader reads tiktoken BPE files from a directory, named after the
+// last element of their download URL.
+type fileBPELoader struct {
+	dir string
+}
+
+func (l fileBPELoader) LoadTiktokenBpe(url string) (map[string]int, error) {
+	file := filepath.Join(l.dir, path.Base(url))
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		startBPEDownload(url, file)
+		return nil, errBPEPending
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseBPE(data)
+}
+
+var (
+	bpeFetchMu sync.Mutex
+	bpeFetched = make(map[string]bool)
+)
+
+// downloadBPE fetches a BPE file; tests replace it to stay offline.
+var downloadBPE = func(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// startBPEDownload downloads url to file in the background. Each file is
+// fetched at most once per run; after a failure the approximation stays in
+// use until restart.
+func startBPEDownload(url, file string) {
+	bpeFetchMu.Lock()
+	defer bpeFetchMu.Unlock()
+	if bpeFetched[file] {
+		return
+	}
+	bpeFetched[file] = true
+
+	go func() {
+		if err := saveBPE(url, file); err != nil {
+			logger.WarnCF("providers", "Failed to download BPE encoding, using approximation",
+				map[string]interface{}{"url": url, "error": err.Error()})
+			return
+		}
+		logger.InfoCF("providers", "Downloaded BPE encoding", map[string]interface{}{"file": file})
+	}()
+}
+
+func saveBPE(url, file string) error {
+	data, err := downloadBPE(url)
+	if err != nil {
+		return err
+	}
+	if _, err := parseBPE(data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// parseBPE parses a tiktoken file: one "base64-token rank" pair per line.
+func parseBPE(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		parts := bytes.SplitN(line, []byte(" "), 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid BPE line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid BPE token: %w", err)
+		}
+		rank, err := strconv.Atoi(string(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid BPE rank: %w", err)
+		}
+		ranks[string(token)] = rank
+	}
+	if len(ranks) == 0 {
+		return nil, errors.New("empty BPE file")
+	}
+	return ranks, nil
+}
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useByteBPE points the BPE directory at a temp dir holding an o200k_base
+// file with only single-byte tokens, so every byte is one token.
+func useByteBPE(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "o200k_base.tiktoken"), []byte(sb.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetBPEDir(dir)
+	t.Cleanup(func() { SetBPEDir("") })
+}
+
+func TestNewTokenCounter_PicksFamily(t *testing.T) {
+	useByteBPE(t)
+	tests := []struct {
+		model string
+		bpe   bool
+	}{
+		{"openai/gpt-4o", true},
+		{"anthropic/claude-sonnet-4", false},
+		{"google/gemini-2.5-flash", false},
+		{"test-model", false},
+	}
+	for _, tt := range tests {
+		_, isBPE := NewTokenCounter(tt.model).(bpeCounter)
+		if isBPE != tt.bpe {
+			t.Errorf("NewTokenCounter(%q) BPE = %v, want %v", tt.model, isBPE, tt.bpe)
+		}
+	}
+
+	if got := openAIEncoding("gpt-4-turbo"); got != "cl100k_base" {
+		t.Errorf("gpt-4-turbo encoding = %q, want cl100k_base", got)
+	}
+	if got := openAIEncoding("gpt-5"); got != "o200k_base" {
+		t.Errorf("gpt-5 encoding = %q, want o200k_base", got)
+	}
+}
+
+func TestFileBPELoader_DownloadsMissingFile(t *testing.T) {
+	loader := fileBPELoader{dir: t.TempDir()}
+	fetched := make(chan string, 1)
+	orig := downloadBPE
+	downloadBPE = func(url string) ([]byte, error) {
+		fetched <- url
+		return []byte(base64.StdEncoding.EncodeToString([]byte("a")) + " 0\n"), nil
+	}
+	t.Cleanup(func() { downloadBPE = orig })
+
+	const url = "https://example.com/encodings/cl100k_base.tiktoken"
+	if _, err := loader.LoadTiktokenBpe(url); err != errBPEPending {
+		t.Fatalf("missing file error = %v, want errBPEPending", err)
+	}
+	select {
+	case got := <-fetched:
+		if got != url {
+			t.Errorf("downloaded %q, want %q", got, url)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("missing file was not downloaded")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ranks, err := loader.LoadTiktokenBpe(url)
+		if err == nil {
+			if ranks["a"] != 0 || len(ranks) != 1 {
+				t.Errorf("ranks = %v, want {a: 0}", ranks)
+			}
+			break
+		}
+		if err != errBPEPending || time.Now().After(deadline) {
+			t.Fatalf("load after download: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNewTokenCounter_NoBPEDirUsesApproximation(t *testing.T) {
+	SetBPEDir("")
+	if _, isBPE := NewTokenCounter("openai/gpt-4o").(bpeCounter); isBPE {
+		t.Error("BPE counter used without a BPE directory")
+	}
+}
+
+func TestTokenCounter_CountText(t *testing.T) {
+	useByteBPE(t)
+	if got := NewTokenCounter("openai/gpt-4o").CountText("hello world"); got != 11 {
+		t.Errorf("BPE CountText(hello world) = %d, want 11", got)
+	}
+
+	claude := NewTokenCounter("anthropic/claude-sonnet-4")
+	if got := claude.CountText(strings.Repeat("a", 35)); got != 10 {
+		t.Errorf("approx CountText(35 latin chars) = %d, want 10", got)
+	}
+	// CJK text costs about one token per character
+	if got := claude.CountText("こんにちは世界"); got != 7 {
+		t.Errorf("approx CountText(7 CJK chars) = %d, want 7", got)
+	}
+}
+
+func TestCountMessages_IncludesToolsCallsAndMedia(t *testing.T) {
+	c := NewTokenCounter("anthropic/claude-sonnet-4")
+	base := []Message{{Role: "user", Content: "hi"}}
+	plain := CountMessages(c, base, nil)
+
+	withMedia := CountMessages(c, []Message{{Role: "user", Content: "hi", Media: []string{"data:image/png;base64,AAAA"}}}, nil)
+	if withMedia-plain != c.ImageTokens() {
+		t.Errorf("image cost = %d, want %d", withMedia-plain, c.ImageTokens())
+	}
+
+	call := Message{Role: "assistant", ToolCalls: []ToolCall{{
+		ID: "c1", Name: "read_file", Arguments: map[string]interface{}{"path": strings.Repeat("x", 350)},
+	}}}
+	if got := CountMessages(c, append(base, call), nil) - plain; got < 100 {
+		t.Errorf("tool call with long arguments counted as %d tokens, want >= 100", got)
+	}
+
+	tool := ToolDefinition{Type: "function", Function: ToolFunctionDefinition{
+		Name:        "read_file",
+		Description: strings.Repeat("Read a file. ", 30),
+		Parameters:  map[string]interface{}{"type": "object"},
+	}}
+	if got := CountMessages(c, base, []ToolDefinition{tool}) - plain; got < 100 {
+		t.Errorf("tool definition counted as %d tokens, want >= 100", got)
+	}
+}
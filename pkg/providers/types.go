@@ -20,6 +20,7 @@ type LLMResponse struct {
 	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
 	FinishReason string     `json:"finish_reason"`
 	Usage        *UsageInfo `json:"usage,omitempty"`
+	Model        string     `json:"model,omitempty"` // Model that produced the response ("provider/model_name")
 }
 
 type UsageInfo struct {
@@ -51,3 +52,14 @@ type ToolFunctionDefinition struct {
 	Description string                 `json:"description"`
 	Parameters  map[string]interface{} `json:"parameters"`
 }
+
+// StreamCallback receives each text fragment as a streaming response is generated.
+type StreamCallback func(delta string)
+
+// StreamingProvider is an optional interface for providers that can deliver
+// responses incrementally. ChatStream calls onDelta for every text fragment
+// and returns the fully assembled response, including tool calls, once the
+// stream ends.
+type StreamingProvider interface {
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta StreamCallback) (*LLMResponse, error)
+}
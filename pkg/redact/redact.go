@@ -0,0 +1,296 @@
+// Package redact masks secrets and personal data in text before it leaves
+// the agent: API keys, email addresses, phone numbers, credentials from the
+// config, and custom patterns.
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+// Built-in detector names.
+const (
+	APIKey       = "api_key"
+	Email        = "email"
+	Phone        = "phone"
+	ConfigSecret = "config_secret"
+)
+
+// minSecretLen is the shortest config value treated as a secret; shorter
+// values would mask ordinary words.
+const minSecretLen = 8
+
+// apiKeyPatterns match well-known credential formats. When a pattern has a
+// group, only the group is masked.
+var apiKeyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),                                  // OpenAI, Anthropic, DeepSeek, ...
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),                                     // AWS access key ID
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),                                  // Google API key
+	regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_\w{22,})`),      // GitHub
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}|\bxapp-[A-Za-z0-9-]{10,}`), // Slack
+	regexp.MustCompile(`\b\d{8,10}:[A-Za-z0-9_-]{35}\b`),                           // Telegram bot token
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{20,}=*)`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	// JSON credential fields, e.g. "api_key": "..." in config.json
+	regexp.MustCompile(`(?i)"(?:[a-z_]*api_key|[a-z_]*token|[a-z_]*secret|password)"\s*:\s*"([^"]{8,})"`),
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+
+// phonePattern matches grouped numbers such as 090-1234-5678,
+// (555) 123-4567 and +1 555 123 4567, or + followed by the digits.
+var phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?|\d{1,4}[ .-])\d{2,4}[ .-]\d{3,4}\b|\+\d{10,15}\b`)
+
+// Redactor masks the matches of the detectors enabled for a channel.
+type Redactor struct {
+	enabled     bool
+	toolResults bool
+	detectors   []string
+	channels    map[string]config.RedactionChannelConfig
+	custom      map[string]*regexp.Regexp
+	secrets     func() []string
+}
+
+// New creates a Redactor. secrets are the values the config_secret detector
+// masks. Invalid custom patterns are logged and skipped.
+func New(cfg config.RedactionConfig, secrets []string) *Redactor {
+	r := newRedactor(cfg)
+	secrets = secretList(secrets)
+	r.secrets = func() []string { return secrets }
+	return r
+}
+
+// NewForConfig creates a Redactor whose config_secret detector masks the
+// current cfg.Secrets(), read under the config read lock, so credentials set
+// by a later config update are masked too.
+func NewForConfig(cfg *config.Config) *Redactor {
+	cfg.RLock()
+	r := newRedactor(cfg.Redaction)
+	cfg.RUnlock()
+	r.secrets = func() []string {
+		cfg.RLock()
+		defer cfg.RUnlock()
+		return secretList(cfg.Secrets())
+	}
+	return r
+}
+
+func newRedactor(cfg config.RedactionConfig) *Redactor {
+	r := &Redactor{
+		enabled:     cfg.Enabled,
+		toolResults: cfg.ToolResults,
+		detectors:   cfg.Detectors,
+		channels:    cfg.Channels,
+		custom:      make(map[string]*regexp.Regexp),
+	}
+	for name, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			logger.WarnCF("redact", "Invalid redaction pattern",
+				map[string]interface{}{"name": name, "error": err.Error()})
+			continue
+		}
+		r.custom[name] = re
+	}
+	return r
+}
+
+// secretList returns the values long enough to mask, longest first, so a
+// secret containing another is masked whole.
+func secretList(values []string) []string {
+	var secrets []string
+	for _, s := range values {
+		if len(s) >= minSecretLen {
+			secrets = append(secrets, s)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// ToolResults reports whether tool results are redacted before they are
+// saved to session history.
+func (r *Redactor) ToolResults() bool {
+	return r != nil && r.enabled && r.toolResults
+}
+
+// Detectors returns the detectors applied on a channel.
+func (r *Redactor) Detectors(channel string) []string {
+	if r == nil || !r.enabled {
+		return nil
+	}
+	policy := r.channels[channel]
+	exempt := make(map[string]bool, len(policy.Exempt))
+	for _, name := range policy.Exempt {
+		if name == "*" {
+			return nil
+		}
+		exempt[name] = true
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append(append([]string(nil), r.detectors...), policy.Detectors...) {
+		if !exempt[name] && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// span is a masked range of text.
+type span struct {
+	start, end int
+	name       string
+}
+
+// Redact masks the secrets and personal data in text that the channel's
+// detectors find, replacing each with [REDACTED:<detector>]. It returns the
+// masked text and the number of masked ranges.
+func (r *Redactor) Redact(channel, text string) (string, int) {
+	if text == "" {
+		return text, 0
+	}
+	var spans []span
+	for _, name := range r.Detectors(channel) {
+		spans = append(spans, r.find(name, text)...)
+	}
+	if len(spans) == 0 {
+		return text, 0
+	}
+
+	// Earliest first, longest first among equal starts; drop overlaps
+	sort.Slice(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	var sb strings.Builder
+	last, count := 0, 0
+	for _, s := range spans {
+		if s.start < last {
+			continue
+		}
+		sb.WriteString(text[last:s.start])
+		sb.WriteString("[REDACTED:" + s.name + "]")
+		last = s.end
+		count++
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), count
+}
+
+// RedactPartial redacts text that is still being streamed. A secret may be
+// cut off at the end of it and not match yet, so the trailing token, a
+// trailing run of spaced digits and an unfinished private key block are held
+// back until more text arrives.
+func (r *Redactor) RedactPartial(channel, text string) (string, int) {
+	if len(r.Detectors(channel)) == 0 {
+		return text, 0
+	}
+	return r.Redact(channel, text[:settledPrefix(text)])
+}
+
+// settledPrefix returns the length of the part of a partial text that more
+// text cannot turn into a match.
+func settledPrefix(text string) int {
+	if i := strings.LastIndex(text, "-----BEGIN "); i >= 0 {
+		rest := text[i+len("-----BEGIN "):]
+		end := strings.Index(rest, "-----END ")
+		if end < 0 || !strings.Contains(rest[end+len("-----END "):], "-----") {
+			return i
+		}
+	}
+	cut := len(text)
+	for cut > 0 {
+		c, size := utf8.DecodeLastRuneInString(text[:cut])
+		if unicode.IsSpace(c) {
+			// Spaces inside a phone number such as 090 1234 5678
+			prev, _ := utf8.DecodeLastRuneInString(text[:cut-size])
+			next, _ := utf8.DecodeRuneInString(text[cut:])
+			if c != ' ' || !(unicode.IsDigit(prev) || prev == ')') ||
+				(cut < len(text) && !unicode.IsDigit(next) && next != '(') {
+				break
+			}
+		}
+		cut -= size
+	}
+	return cut
+}
+
+// find returns the ranges a detector matches in text.
+func (r *Redactor) find(name, text string) []span {
+	var spans []span
+	switch name {
+	case APIKey:
+		for _, re := range apiKeyPatterns {
+			spans = append(spans, matchSpans(re, name, text)...)
+		}
+	case Email:
+		spans = matchSpans(emailPattern, name, text)
+	case Phone:
+		for _, s := range matchSpans(phonePattern, name, text) {
+			if plausiblePhone(text, s) {
+				spans = append(spans, s)
+			}
+		}
+	case ConfigSecret:
+		for _, secret := range r.secrets() {
+			for offset := 0; ; {
+				i := strings.Index(text[offset:], secret)
+				if i < 0 {
+					break
+				}
+				start := offset + i
+				spans = append(spans, span{start, start + len(secret), name})
+				offset = start + len(secret)
+			}
+		}
+	default:
+		if re, ok := r.custom[name]; ok {
+			spans = matchSpans(re, name, text)
+		}
+	}
+	return spans
+}
+
+// matchSpans returns the matches of re, or of its first group if it has one.
+func matchSpans(re *regexp.Regexp, name, text string) []span {
+	var spans []span
+	for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start < end {
+			spans = append(spans, span{start, end, name})
+		}
+	}
+	return spans
+}
+
+// plausiblePhone rejects matches that are part of a longer token or have
+// too few or too many digits for a phone number.
+func plausiblePhone(text string, s span) bool {
+	if s.start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(text[:s.start])
+		if unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '.' || prev == '-' {
+			return false
+		}
+	}
+	digits := 0
+	for _, c := range text[s.start:s.end] {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 10 && digits <= 15
+}
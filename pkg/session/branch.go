@@ -0,0 +1,201 @@
+package session
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/providers"
+)
+
+// MainBranch is the name of a session's original branch.
+const MainBranch = "main"
+
+var (
+	ErrInvalidBranch  = errors.New("invalid branch name")
+	ErrBranchExists   = errors.New("branch already exists")
+	ErrBranchNotFound = errors.New("branch not found")
+)
+
+var branchNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Branch is an inactive line of conversation kept alongside the session.
+// The active branch lives in Session.Messages and Session.Summary.
+type Branch struct {
+	Messages []providers.Message `json:"messages"`
+	Summary  string              `json:"summary,omitempty"`
+	Updated  time.Time           `json:"updated"`
+}
+
+// EachMessage calls fn for every stored message: the active and inactive
+// branches of all sessions. fn must not call back into the manager.
+func (sm *SessionManager) EachMessage(fn func(providers.Message)) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for _, session := range sm.sessions {
+		for _, m := range session.Messages {
+			fn(m)
+		}
+		for _, b := range session.Branches {
+			for _, m := range b.Messages {
+				fn(m)
+			}
+		}
+	}
+}
+
+// branchName returns the session's active branch name.
+func (s *Session) branchName() string {
+	if s.Branch == "" {
+		return MainBranch
+	}
+	return s.Branch
+}
+
+// CurrentBranch returns the name of the session's active branch.
+func (sm *SessionManager) CurrentBranch(key string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return MainBranch
+	}
+	return session.branchName()
+}
+
+// Branches returns the names of all branches of a session, sorted, including
+// the active one.
+func (sm *SessionManager) Branches(key string) []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return []string{MainBranch}
+	}
+	names := []string{session.branchName()}
+	for name := range session.Branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fork copies the active branch into a new branch and switches to it.
+func (sm *SessionManager) Fork(key, name string) error {
+	if !branchNamePattern.MatchString(name) {
+		return ErrInvalidBranch
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session := sm.getOrCreateLocked(key)
+	if _, exists := session.Branches[name]; exists || name == session.branchName() {
+		return ErrBranchExists
+	}
+
+	session.stash()
+	session.Messages = append([]providers.Message{}, session.Messages...)
+	session.Branch = name
+	session.Updated = time.Now()
+	return nil
+}
+
+// Checkout switches the session to an existing branch, keeping the active
+// one as an inactive branch.
+func (sm *SessionManager) Checkout(key, name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session := sm.getOrCreateLocked(key)
+	if name == session.branchName() {
+		return nil
+	}
+	target, ok := session.Branches[name]
+	if !ok {
+		return ErrBranchNotFound
+	}
+
+	session.stash()
+	delete(session.Branches, name)
+	session.Messages = target.Messages
+	session.Summary = target.Summary
+	session.Branch = name
+	if name == MainBranch {
+		session.Branch = ""
+	}
+	session.Updated = time.Now()
+	return nil
+}
+
+// Undo removes the last exchange of the active branch: the last user message
+// and everything after it. Notes the agent adds as user messages
+// ("[System: ...]") do not start an exchange. It returns the removed messages.
+func (sm *SessionManager) Undo(key string) []providers.Message {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return nil
+	}
+	for i := len(session.Messages) - 1; i >= 0; i-- {
+		if m := session.Messages[i]; m.Role == "user" && !strings.HasPrefix(m.Content, "[System:") {
+			removed := session.Messages[i:]
+			session.Messages = append([]providers.Message{}, session.Messages[:i]...)
+			session.Updated = time.Now()
+			return removed
+		}
+	}
+	return nil
+}
+
+// Reset clears the messages and summary of the active branch. Other branches
+// are kept. It returns the removed messages.
+func (sm *SessionManager) Reset(key string) []providers.Message {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return nil
+	}
+	removed := session.Messages
+	session.Messages = []providers.Message{}
+	session.Summary = ""
+	session.Updated = time.Now()
+	return removed
+}
+
+// stash saves the active branch into the inactive branches.
+func (s *Session) stash() {
+	if s.Branches == nil {
+		s.Branches = make(map[string]*Branch)
+	}
+	s.Branches[s.branchName()] = &Branch{
+		Messages: s.Messages,
+		Summary:  s.Summary,
+		Updated:  time.Now(),
+	}
+}
+
+// getOrCreateLocked returns the session for key, creating it if needed.
+// The caller must hold sm.mu.
+func (sm *SessionManager) getOrCreateLocked(key string) *Session {
+	session, ok := sm.sessions[key]
+	if !ok {
+		session = &Session{
+			Key:      key,
+			Messages: []providers.Message{},
+			Created:  time.Now(),
+			Updated:  time.Now(),
+		}
+		sm.sessions[key] = session
+	}
+	return session
+}
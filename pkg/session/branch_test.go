@@ -0,0 +1,109 @@
+package session
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func contents(sm *SessionManager, key string) []string {
+	var out []string
+	for _, m := range sm.GetHistory(key) {
+		out = append(out, m.Content)
+	}
+	return out
+}
+
+func TestBranches_ForkAndCheckoutPersist(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	key := "telegram:123"
+	sm.AddMessage(key, "user", "q1")
+	sm.AddMessage(key, "assistant", "a1")
+	sm.SetSummary(key, "main summary")
+
+	if err := sm.Fork(key, "idea"); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	sm.AddMessage(key, "user", "q2 on idea")
+	sm.SetSummary(key, "idea summary")
+
+	if err := sm.Fork(key, "idea"); !errors.Is(err, ErrBranchExists) {
+		t.Errorf("Fork existing = %v, want ErrBranchExists", err)
+	}
+	if err := sm.Fork(key, "bad name"); !errors.Is(err, ErrInvalidBranch) {
+		t.Errorf("Fork invalid = %v, want ErrInvalidBranch", err)
+	}
+	if err := sm.Checkout(key, "nope"); !errors.Is(err, ErrBranchNotFound) {
+		t.Errorf("Checkout missing = %v, want ErrBranchNotFound", err)
+	}
+
+	if err := sm.Checkout(key, MainBranch); err != nil {
+		t.Fatalf("Checkout main failed: %v", err)
+	}
+	if got := contents(sm, key); !slices.Equal(got, []string{"q1", "a1"}) {
+		t.Errorf("main history = %v, want [q1 a1]", got)
+	}
+	if got := sm.GetSummary(key); got != "main summary" {
+		t.Errorf("main summary = %q", got)
+	}
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Branches survive a reload
+	sm2 := NewSessionManager(tmpDir)
+	if got := sm2.Branches(key); !slices.Equal(got, []string{"idea", MainBranch}) {
+		t.Errorf("branches after reload = %v", got)
+	}
+	if err := sm2.Checkout(key, "idea"); err != nil {
+		t.Fatalf("Checkout idea after reload failed: %v", err)
+	}
+	if got := contents(sm2, key); !slices.Equal(got, []string{"q1", "a1", "q2 on idea"}) {
+		t.Errorf("idea history = %v", got)
+	}
+	if got, want := sm2.CurrentBranch(key), "idea"; got != want {
+		t.Errorf("current branch = %q, want %q", got, want)
+	}
+}
+
+func TestUndoAndReset(t *testing.T) {
+	sm := NewSessionManager("")
+	key := "test"
+	if removed := sm.Undo(key); removed != nil {
+		t.Errorf("Undo on missing session removed %v", removed)
+	}
+
+	sm.AddMessage(key, "user", "q1")
+	sm.AddMessage(key, "assistant", "a1")
+	sm.AddMessage(key, "user", "q2")
+	sm.AddMessage(key, "assistant", "")
+	sm.AddMessage(key, "tool", "result")
+	sm.AddMessage(key, "user", "[System: You have called read_file ...]")
+	sm.AddMessage(key, "assistant", "a2")
+
+	if removed := sm.Undo(key); len(removed) != 5 {
+		t.Errorf("Undo removed %d messages, want 5", len(removed))
+	}
+	if got := contents(sm, key); !slices.Equal(got, []string{"q1", "a1"}) {
+		t.Errorf("history after undo = %v", got)
+	}
+
+	if err := sm.Fork(key, "alt"); err != nil {
+		t.Fatalf("Fork failed: %v", err)
+	}
+	sm.SetSummary(key, "s")
+	if removed := sm.Reset(key); len(removed) != 2 {
+		t.Errorf("Reset removed %d messages, want 2", len(removed))
+	}
+	if len(sm.GetHistory(key)) != 0 || sm.GetSummary(key) != "" {
+		t.Error("Reset left messages or summary")
+	}
+	// Reset only clears the active branch
+	if err := sm.Checkout(key, MainBranch); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if got := contents(sm, key); !slices.Equal(got, []string{"q1", "a1"}) {
+		t.Errorf("main history after reset on alt = %v", got)
+	}
+}
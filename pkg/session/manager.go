@@ -15,6 +15,9 @@ type Session struct {
 	Key      string              `json:"key"`
 	Messages []providers.Message `json:"messages"`
 	Summary  string              `json:"summary,omitempty"`
+	Model    string              `json:"model,omitempty"`    // Per-session model override ("provider/model_name")
+	Branch   string              `json:"branch,omitempty"`   // Active branch name ("" = main)
+	Branches map[string]*Branch  `json:"branches,omitempty"` // Inactive branches by name
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
 }
@@ -122,6 +125,37 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	}
 }
 
+// GetModel returns the session's model override, or "" when the default applies.
+func (sm *SessionManager) GetModel(key string) string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		return ""
+	}
+	return session.Model
+}
+
+// SetModel sets the session's model override, creating the session if needed.
+// An empty model clears the override.
+func (sm *SessionManager) SetModel(key string, model string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.sessions[key]
+	if !ok {
+		session = &Session{
+			Key:      key,
+			Messages: []providers.Message{},
+			Created:  time.Now(),
+		}
+		sm.sessions[key] = session
+	}
+	session.Model = model
+	session.Updated = time.Now()
+}
+
 func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -180,6 +214,8 @@ func (sm *SessionManager) Save(key string) error {
 	snapshot := Session{
 		Key:     stored.Key,
 		Summary: stored.Summary,
+		Model:   stored.Model,
+		Branch:  stored.Branch,
 		Created: stored.Created,
 		Updated: stored.Updated,
 	}
@@ -189,6 +225,13 @@ func (sm *SessionManager) Save(key string) error {
 	} else {
 		snapshot.Messages = []providers.Message{}
 	}
+	if len(stored.Branches) > 0 {
+		// Inactive branches are replaced, never modified in place
+		snapshot.Branches = make(map[string]*Branch, len(stored.Branches))
+		for name, b := range stored.Branches {
+			snapshot.Branches[name] = b
+		}
+	}
 	sm.mu.RUnlock()
 
 	data, err := json.MarshalIndent(snapshot, "", "  ")
@@ -72,3 +72,24 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestSetModel_PersistsWithSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+
+	key := "telegram:123456"
+	sm.SetModel(key, "gemini/gemini-2.5-pro")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save(%q) failed: %v", key, err)
+	}
+
+	sm2 := NewSessionManager(tmpDir)
+	if got := sm2.GetModel(key); got != "gemini/gemini-2.5-pro" {
+		t.Errorf("GetModel() after reload = %q, want %q", got, "gemini/gemini-2.5-pro")
+	}
+
+	sm2.SetModel(key, "")
+	if got := sm2.GetModel(key); got != "" {
+		t.Errorf("GetModel() after clearing = %q, want empty", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -224,7 +225,18 @@ func (sl *SkillsLoader) LoadSkillsForContext(skillNames []string) string {
 }
 
 func (sl *SkillsLoader) BuildSkillsSummary() string {
+	return sl.BuildSkillsSummaryFor(nil)
+}
+
+// BuildSkillsSummaryFor builds the summary of the named skills only. An empty
+// list includes every skill.
+func (sl *SkillsLoader) BuildSkillsSummaryFor(names []string) string {
 	allSkills := sl.ListSkills()
+	if len(names) > 0 {
+		allSkills = slices.DeleteFunc(allSkills, func(s SkillInfo) bool {
+			return !slices.Contains(names, s.Name)
+		})
+	}
 	if len(allSkills) == 0 {
 		return ""
 	}
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/constants"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// ApprovalWaiter is a package-level shared instance used to deliver approval
+// decisions from channels (buttons or typed replies) to the waiting tool call.
+var ApprovalWaiter = NewResponseWaiter()
+
+// ApprovalRequestType is the outbound message type of approval prompts.
+const ApprovalRequestType = "approval_request"
+
+// defaultApprovalTimeout applies when the config leaves the timeout unset.
+const defaultApprovalTimeout = 120 * time.Second
+
+// pendingApprovals records where each waiting request was asked and who may
+// decide it, so a decision from another chat or person is rejected.
+var pendingApprovals = struct {
+	sync.Mutex
+	requests map[string]pendingApproval
+}{requests: make(map[string]pendingApproval)}
+
+type pendingApproval struct {
+	channel   string
+	chatID    string
+	approvers []string // Requester IDs; empty = anyone allowed in the chat
+}
+
+// allows reports whether senderID may decide the request. Compound
+// "id|username" sender IDs match on their ID part.
+func (p pendingApproval) allows(senderID string) bool {
+	if len(p.approvers) == 0 {
+		return true
+	}
+	senderPart, _, _ := strings.Cut(senderID, "|")
+	for _, id := range p.approvers {
+		idPart, _, _ := strings.Cut(id, "|")
+		if id == senderID || idPart == senderPart {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliverApproval passes a decision for a pending approval request from
+// senderID in channel/chatID. It reports whether the decision was accepted:
+// unknown or expired request IDs, and decisions from another chat or from
+// someone other than the requester, are ignored.
+func DeliverApproval(requestID string, approved bool, channel, chatID, senderID string) bool {
+	pendingApprovals.Lock()
+	pending, ok := pendingApprovals.requests[requestID]
+	pendingApprovals.Unlock()
+	if !ok || pending.channel != channel || pending.chatID != chatID || !pending.allows(senderID) {
+		logger.WarnCF("tool", "Approval decision rejected",
+			map[string]interface{}{
+				"approval_id": requestID,
+				"channel":     channel,
+				"chat_id":     chatID,
+				"sender_id":   senderID,
+			})
+		return false
+	}
+
+	decision := "deny"
+	if approved {
+		decision = "approve"
+	}
+	ApprovalWaiter.Deliver(requestID, decision+":"+channel+":"+senderID)
+	return true
+}
+
+// ApprovalRequest describes a tool call waiting for a decision.
+type ApprovalRequest struct {
+	ID      string                 `json:"id"`
+	Tool    string                 `json:"tool"`
+	Action  string                 `json:"action,omitempty"` // Value of the "action" argument, if any
+	Args    map[string]interface{} `json:"args,omitempty"`
+	Channel string                 `json:"channel"`
+	ChatID  string                 `json:"chat_id"`
+}
+
+// ApprovalPrompt sends an approval request to the user. Implementations must
+// publish the request ID so the channel can report the decision back.
+type ApprovalPrompt func(ctx context.Context, req ApprovalRequest) error
+
+// Requester identifies who triggered a turn's tool calls.
+type Requester struct {
+	IDs    []string // User directory ID and/or channel sender ID
+	Locale string
+}
+
+type requesterKey struct{}
+
+// WithRequester attaches the requester to ctx for per-user approval policies.
+func WithRequester(ctx context.Context, r Requester) context.Context {
+	return context.WithValue(ctx, requesterKey{}, r)
+}
+
+// RequesterFrom returns the requester attached to ctx, if any.
+func RequesterFrom(ctx context.Context) Requester {
+	r, _ := ctx.Value(requesterKey{}).(Requester)
+	return r
+}
+
+// approvalRecord is one line of the approval audit log.
+type approvalRecord struct {
+	Time time.Time `json:"time"`
+	ApprovalRequest
+	Requester []string `json:"requester,omitempty"`
+	Decision  string   `json:"decision"` // "approved", "denied", "timeout", "cancelled", "error"
+	By        string   `json:"by,omitempty"`
+}
+
+// ApprovalPolicy pauses tool calls matching its rules until the user approves
+// them through the originating channel.
+type ApprovalPolicy struct {
+	timeout time.Duration
+	require []string
+	users   map[string]config.ApprovalUserConfig
+	prompt  ApprovalPrompt
+	logPath string
+	mu      sync.Mutex // Serializes audit log writes
+}
+
+// NewApprovalPolicy creates a policy from config. Decisions are appended to
+// the JSONL file at logPath (empty disables the audit log).
+func NewApprovalPolicy(cfg config.ApprovalConfig, prompt ApprovalPrompt, logPath string) *ApprovalPolicy {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultApprovalTimeout
+	}
+	return &ApprovalPolicy{
+		timeout: timeout,
+		require: cfg.Require,
+		users:   cfg.Users,
+		prompt:  prompt,
+		logPath: logPath,
+	}
+}
+
+// Requires reports whether a call needs approval for the given requester IDs.
+func (p *ApprovalPolicy) Requires(name string, args map[string]interface{}, requester []string) bool {
+	required := matchesRule(p.require, name, args)
+	for _, id := range requester {
+		user, ok := p.users[id]
+		if !ok {
+			continue
+		}
+		if matchesRule(user.Exempt, name, args) {
+			return false
+		}
+		if matchesRule(user.Require, name, args) {
+			required = true
+		}
+	}
+	return required
+}
+
+// matchesRule reports whether rules cover a tool ("exec"), one of its actions
+// ("android:dial"), or everything ("*"). Calls that run a shell command
+// outside exec are also covered by "exec" rules.
+func matchesRule(rules []string, name string, args map[string]interface{}) bool {
+	action, _ := args["action"].(string)
+	shell := runsShellCommand(name, action, args)
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "*" || rule == name {
+			return true
+		}
+		if action != "" && rule == name+":"+action {
+			return true
+		}
+		if rule == "exec" && shell {
+			return true
+		}
+	}
+	return false
+}
+
+// runsShellCommand reports whether a call runs a shell command through a
+// tool other than exec: starting a background process, or scheduling a cron
+// job with a command.
+func runsShellCommand(name, action string, args map[string]interface{}) bool {
+	switch name {
+	case "process":
+		return action == "start"
+	case "cron":
+		command, _ := args["command"].(string)
+		return action == "add" && command != ""
+	}
+	return false
+}
+
+// Check asks for approval when the call requires it. It returns nil when the
+// call may run, or the result to report to the LLM instead.
+func (p *ApprovalPolicy) Check(ctx context.Context, name string, args map[string]interface{}, channel, chatID string) *ToolResult {
+	// Internal channels (CLI) are driven by the local operator
+	if constants.IsInternalChannel(channel) {
+		return nil
+	}
+
+	requester := RequesterFrom(ctx)
+	if !p.Requires(name, args, requester.IDs) {
+		return nil
+	}
+
+	action, _ := args["action"].(string)
+	req := ApprovalRequest{
+		ID:      uuid.New().String(),
+		Tool:    name,
+		Action:  action,
+		Args:    args,
+		Channel: channel,
+		ChatID:  chatID,
+	}
+
+	if channel == "" || chatID == "" || p.prompt == nil {
+		p.record(req, requester, "error", "")
+		return ErrorResult(fmt.Sprintf("%s requires user approval, but there is no chat to ask in", toolLabel(name, action)))
+	}
+
+	// Register waiter before sending to avoid race
+	respCh := ApprovalWaiter.Register(req.ID)
+	pendingApprovals.Lock()
+	pendingApprovals.requests[req.ID] = pendingApproval{channel: channel, chatID: chatID, approvers: requester.IDs}
+	pendingApprovals.Unlock()
+	defer func() {
+		pendingApprovals.Lock()
+		delete(pendingApprovals.requests, req.ID)
+		pendingApprovals.Unlock()
+	}()
+	if err := p.prompt(ctx, req); err != nil {
+		ApprovalWaiter.Cleanup(req.ID)
+		p.record(req, requester, "error", "")
+		return ErrorResult(fmt.Sprintf("failed to request approval: %v", err))
+	}
+
+	logger.InfoCF("tool", "Waiting for approval",
+		map[string]interface{}{
+			"tool":        name,
+			"action":      action,
+			"approval_id": req.ID,
+		})
+
+	select {
+	case content := <-respCh:
+		decision, by, _ := strings.Cut(content, ":")
+		if decision == "approve" {
+			p.record(req, requester, "approved", by)
+			return nil
+		}
+		p.record(req, requester, "denied", by)
+		return ErrorResult(fmt.Sprintf("The user denied %s. Do not retry it unless the user asks.", toolLabel(name, action)))
+	case <-time.After(p.timeout):
+		ApprovalWaiter.Cleanup(req.ID)
+		p.record(req, requester, "timeout", "")
+		return ErrorResult(fmt.Sprintf("Approval for %s timed out after %s; the call was not run.", toolLabel(name, action), p.timeout))
+	case <-ctx.Done():
+		ApprovalWaiter.Cleanup(req.ID)
+		p.record(req, requester, "cancelled", "")
+		return ErrorResult("approval request cancelled")
+	}
+}
+
+func toolLabel(name, action string) string {
+	if action != "" {
+		return name + ":" + action
+	}
+	return name
+}
+
+// record logs a decision and appends it to the audit log.
+func (p *ApprovalPolicy) record(req ApprovalRequest, requester Requester, decision, by string) {
+	logger.InfoCF("tool", "Approval decision",
+		map[string]interface{}{
+			"tool":        req.Tool,
+			"action":      req.Action,
+			"approval_id": req.ID,
+			"decision":    decision,
+			"by":          by,
+		})
+
+	if p.logPath == "" {
+		return
+	}
+	data, err := json.Marshal(approvalRecord{
+		Time:            time.Now(),
+		ApprovalRequest: req,
+		Requester:       requester.IDs,
+		Decision:        decision,
+		By:              by,
+	})
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_ = os.MkdirAll(filepath.Dir(p.logPath), 0755)
+	f, err := os.OpenFile(p.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+	_, _ = f.Write(append(data, '\n'))
+}
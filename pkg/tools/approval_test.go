@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+func TestApprovalPolicy_Requires(t *testing.T) {
+	p := NewApprovalPolicy(config.ApprovalConfig{
+		Require: config.FlexibleStringSlice{"exec", "android:dial"},
+		Users: map[string]config.ApprovalUserConfig{
+			"owner": {Exempt: config.FlexibleStringSlice{"*"}},
+			"guest": {Require: config.FlexibleStringSlice{"web_fetch"}},
+		},
+	}, nil, "")
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]interface{}
+		requester []string
+		want      bool
+	}{
+		{"whole tool", "exec", nil, nil, true},
+		{"listed action", "android", map[string]interface{}{"action": "dial"}, nil, true},
+		{"other action", "android", map[string]interface{}{"action": "screenshot"}, nil, false},
+		{"process start as exec", "process", map[string]interface{}{"action": "start"}, nil, true},
+		{"process poll", "process", map[string]interface{}{"action": "poll"}, nil, false},
+		{"cron command as exec", "cron", map[string]interface{}{"action": "add", "command": "df -h"}, nil, true},
+		{"cron reminder", "cron", map[string]interface{}{"action": "add", "message": "stretch"}, nil, false},
+		{"unlisted tool", "read_file", nil, nil, false},
+		{"exempt user", "exec", nil, []string{"123", "owner"}, false},
+		{"user rule", "web_fetch", nil, []string{"guest"}, true},
+		{"user rule for others", "web_fetch", nil, []string{"someone"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Requires(tt.tool, tt.args, tt.requester); got != tt.want {
+				t.Errorf("Requires(%s) = %v, want %v", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApprovalPolicy_CheckDecisions(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "approvals", "approvals.jsonl")
+	var decide func(id string)
+	var prompted []ApprovalRequest
+	p := NewApprovalPolicy(config.ApprovalConfig{Require: config.FlexibleStringSlice{"exec"}}, func(ctx context.Context, req ApprovalRequest) error {
+		prompted = append(prompted, req)
+		go decide(req.ID)
+		return nil
+	}, logPath)
+	args := map[string]interface{}{"command": "rm -rf build"}
+
+	decide = func(id string) { DeliverApproval(id, true, "telegram", "1", "42") }
+	if res := p.Check(context.Background(), "exec", args, "telegram", "1"); res != nil {
+		t.Fatalf("approved call returned %+v", res)
+	}
+
+	decide = func(id string) { DeliverApproval(id, false, "telegram", "1", "42") }
+	res := p.Check(context.Background(), "exec", args, "telegram", "1")
+	if res == nil || !res.IsError || !strings.Contains(res.ForLLM, "denied") {
+		t.Fatalf("denied call returned %+v", res)
+	}
+
+	decide = func(string) {}
+	p.timeout = 10 * time.Millisecond
+	res = p.Check(context.Background(), "exec", args, "telegram", "1")
+	if res == nil || !strings.Contains(res.ForLLM, "timed out") {
+		t.Fatalf("unanswered call returned %+v", res)
+	}
+
+	// CLI runs and calls outside the rules are never prompted
+	if res := p.Check(context.Background(), "exec", args, "cli", "direct"); res != nil {
+		t.Errorf("cli call returned %+v", res)
+	}
+	if res := p.Check(context.Background(), "read_file", nil, "telegram", "1"); res != nil {
+		t.Errorf("read_file returned %+v", res)
+	}
+
+	if len(prompted) != 3 || prompted[0].Channel != "telegram" || prompted[0].ChatID != "1" {
+		t.Errorf("prompted = %+v, want 3 requests to telegram:1", prompted)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("reading audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("audit log has %d lines, want 3:\n%s", len(lines), data)
+	}
+	for i, want := range []string{`"decision":"approved"`, `"decision":"denied"`, `"decision":"timeout"`} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("audit line %d = %s, want %s", i, lines[i], want)
+		}
+	}
+}
+
+func TestToolRegistry_ApprovalPolicyBlocksDeniedCalls(t *testing.T) {
+	r := NewToolRegistry()
+	r.Register(NewListDirTool(t.TempDir(), true))
+	r.SetApprovalPolicy(NewApprovalPolicy(config.ApprovalConfig{Require: config.FlexibleStringSlice{"list_dir"}}, func(ctx context.Context, req ApprovalRequest) error {
+		go DeliverApproval(req.ID, false, "discord", "1", "42")
+		return nil
+	}, ""))
+
+	res := r.ExecuteWithContext(context.Background(), "list_dir", map[string]interface{}{"path": "."}, "discord", "1", nil)
+	if !res.IsError || !strings.Contains(res.ForLLM, "denied") {
+		t.Errorf("result = %+v, want a denial", res)
+	}
+}
+
+func TestDeliverApproval_OnlyRequesterInOriginChat(t *testing.T) {
+	var decisions []bool
+	var id string
+	p := NewApprovalPolicy(config.ApprovalConfig{Require: config.FlexibleStringSlice{"exec"}}, func(ctx context.Context, req ApprovalRequest) error {
+		id = req.ID
+		decisions = append(decisions,
+			DeliverApproval(req.ID, true, "telegram", "2", "42"),        // another chat
+			DeliverApproval(req.ID, true, "discord", "1", "42"),         // another channel
+			DeliverApproval(req.ID, true, "telegram", "1", "7|mallory"), // another person
+			DeliverApproval(req.ID, false, "telegram", "1", "42|alice"),
+		)
+		return nil
+	}, "")
+
+	ctx := WithRequester(context.Background(), Requester{IDs: []string{"42", "alice-user"}})
+	res := p.Check(ctx, "exec", map[string]interface{}{"command": "ls"}, "telegram", "1")
+	if res == nil || !strings.Contains(res.ForLLM, "denied") {
+		t.Fatalf("result = %+v, want the requester's denial", res)
+	}
+	if want := []bool{false, false, false, true}; fmt.Sprint(decisions) != fmt.Sprint(want) {
+		t.Errorf("accepted = %v, want %v", decisions, want)
+	}
+	if len(id) != 36 {
+		t.Errorf("request ID %q is not a full UUID", id)
+	}
+	if DeliverApproval(id, true, "telegram", "1", "42") {
+		t.Error("decision accepted after the request finished")
+	}
+}
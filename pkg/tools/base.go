@@ -24,6 +24,13 @@ type ActivatableTool interface {
 	IsActive() bool
 }
 
+// ConcurrentTool is an optional interface for tools that are safe to run
+// alongside other calls from the same LLM response. Tools that do not
+// implement it (or return false) always run on their own, in order.
+type ConcurrentTool interface {
+	ConcurrencySafe() bool
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -28,18 +28,15 @@ type CronTool struct {
 	mu          sync.RWMutex
 }
 
-// NewCronTool creates a new CronTool
-func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, workspace string, restrict bool, execEnabled bool) *CronTool {
-	var execTool *ExecTool
-	if execEnabled {
-		execTool = NewExecTool(workspace, restrict)
-	}
+// NewCronTool creates a new CronTool. Scheduled commands run through
+// execTool; nil disables them.
+func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bus.MessageBus, execTool *ExecTool) *CronTool {
 	return &CronTool{
 		cronService: cronService,
 		executor:    executor,
 		msgBus:      msgBus,
 		execTool:    execTool,
-		execEnabled: execEnabled,
+		execEnabled: execTool != nil,
 	}
 }
 
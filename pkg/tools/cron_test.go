@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/cron"
+)
+
+func TestCronTool_CommandHeldForExecApproval(t *testing.T) {
+	service := cron.NewCronService(filepath.Join(t.TempDir(), "jobs.json"), nil)
+	tool := NewCronTool(service, nil, nil, NewExecTool(t.TempDir(), false))
+	r := NewToolRegistry()
+	r.Register(tool)
+	var prompted []ApprovalRequest
+	r.SetApprovalPolicy(NewApprovalPolicy(config.ApprovalConfig{Require: config.FlexibleStringSlice{"exec"}}, func(ctx context.Context, req ApprovalRequest) error {
+		prompted = append(prompted, req)
+		go DeliverApproval(req.ID, false, "telegram", "chat1", "42")
+		return nil
+	}, ""))
+	var hooked []string
+	hooks := NewHooks()
+	hooks.Add(HookPre, "exec", func(ctx context.Context, call HookCall) (HookDecision, error) {
+		hooked = append(hooked, call.Tool)
+		return HookDecision{}, nil
+	})
+	r.SetHooks(hooks)
+	ctx := WithSession(context.Background(), "telegram:chat1")
+
+	result := r.ExecuteWithContext(ctx, "cron", map[string]interface{}{
+		"action": "add", "message": "disk check", "command": "df -h", "at_seconds": float64(1),
+	}, "telegram", "chat1", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "denied") {
+		t.Fatalf("add = %s, want a denial", result.ForLLM)
+	}
+	if len(prompted) != 1 || prompted[0].Tool != "cron" {
+		t.Errorf("prompted = %+v", prompted)
+	}
+	if len(hooked) != 1 {
+		t.Errorf("exec hooks ran for %v, want the cron command", hooked)
+	}
+	if jobs := service.ListJobs(true); len(jobs) != 0 {
+		t.Errorf("denied command was scheduled: %+v", jobs)
+	}
+
+	// Reminders without a command are not exec
+	r.ExecuteWithContext(ctx, "cron", map[string]interface{}{
+		"action": "add", "message": "stretch", "at_seconds": float64(60),
+	}, "telegram", "chat1", nil)
+	if len(prompted) != 1 || len(hooked) != 1 {
+		t.Errorf("reminder treated as exec: prompted=%d hooked=%d", len(prompted), len(hooked))
+	}
+}
@@ -1,11 +1,20 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // validatePath ensures the given path is within the workspace if restrict is true.
@@ -77,6 +86,24 @@ func isWithinWorkspace(candidate, workspace string) bool {
 	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
 }
 
+const (
+	readFileDefaultLimit  = 2000
+	readFileMaxBytes      = 40000
+	readFileMaxLineLength = 2000
+	readFileMaxImageBytes = 5 << 20
+	readFileMaxDecodeSize = 20 << 20
+	readFileHexPreview    = 512
+)
+
+// readFileImageTypes are the image formats passed to the LLM as media.
+var readFileImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+}
+
 type ReadFileTool struct {
 	workspace string
 	restrict  bool
@@ -91,7 +118,7 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read a file. Text is returned with line numbers (\"N: \" prefixes, not part of the file) and a header giving the total line count; use offset/limit to page through large files. Images are attached for viewing, other binary files return a hexdump preview."
 }
 
 func (t *ReadFileTool) Parameters() map[string]interface{} {
@@ -102,28 +129,230 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Line number to start from (1-based, default 1)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of lines to read (default %d)", readFileDefaultLimit),
+			},
 		},
 		"required": []string{"path"},
 	}
 }
 
+func (t *ReadFileTool) ConcurrencySafe() bool {
+	return true
+}
+
 func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
 		return ErrorResult("path is required")
 	}
+	offset := intArg(args, "offset", 1)
+	if offset < 1 {
+		offset = 1
+	}
+	limit := intArg(args, "limit", readFileDefaultLimit)
+	if limit < 1 {
+		limit = readFileDefaultLimit
+	}
 
 	resolvedPath, err := validatePath(path, t.workspace, t.restrict)
 	if err != nil {
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	if info.IsDir() {
+		return ErrorResult(fmt.Sprintf("%s is a directory; use list_dir", path))
+	}
+
+	head := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	head = head[:n]
+
+	mime := http.DetectContentType(head)
+	if readFileImageTypes[mime] {
+		return readImageFile(f, path, mime, info.Size())
+	}
+
+	var text io.Reader = f
+	encoding := ""
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}), bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		if info.Size() > readFileMaxDecodeSize {
+			return ErrorResult(fmt.Sprintf("UTF-16 file is too large to decode (%s)", formatSize(info.Size())))
+		}
+		data, err := readAllFrom(f)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		}
+		text = strings.NewReader(decodeUTF16(data))
+		encoding = "UTF-16"
+	case isBinary(head) || !looksLikeUTF8(head, n == binarySniffLen):
+		preview := head[:min(n, readFileHexPreview)]
+		return NewToolResult(fmt.Sprintf("[%s: binary or non-UTF-8 file (%s, %s); first %d bytes]\n%s",
+			path, mime, formatSize(info.Size()), len(preview), hex.Dump(preview)))
+	default:
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		}
+	}
+
+	return readTextLines(text, path, encoding, offset, limit)
+}
+
+// readImageFile returns an image as a data URL for vision-capable models.
+func readImageFile(f *os.File, path, mime string, size int64) *ToolResult {
+	if size > readFileMaxImageBytes {
+		return ErrorResult(fmt.Sprintf("image is too large to attach (%s, max %s)", formatSize(size), formatSize(readFileMaxImageBytes)))
+	}
+	data, err := readAllFrom(f)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
+	return &ToolResult{
+		ForLLM: fmt.Sprintf("[%s: %s image, %s; attached]", path, mime, formatSize(size)),
+		Media:  []string{"data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)},
+	}
+}
 
-	return NewToolResult(string(content))
+func readAllFrom(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(f)
+}
+
+// readTextLines formats lines offset..offset+limit-1 with line numbers,
+// stopping early at readFileMaxBytes. The whole file is scanned to report
+// the total line count.
+func readTextLines(r io.Reader, path, encoding string, offset, limit int) *ToolResult {
+	br := bufio.NewReader(r)
+	var body strings.Builder
+	total, end, next := 0, 0, 0
+	for {
+		line, truncated, err := readLine(br, readFileMaxLineLength)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		}
+		total++
+		if total == 1 {
+			line = strings.TrimPrefix(line, "\uFEFF")
+		}
+		if total < offset || total >= offset+limit || next > 0 {
+			continue
+		}
+		if truncated {
+			line = truncateLine(line, readFileMaxLineLength) + " [line truncated]"
+		}
+		entry := fmt.Sprintf("%d: %s\n", total, line)
+		if body.Len() > 0 && body.Len()+len(entry) > readFileMaxBytes {
+			next = total
+			continue
+		}
+		body.WriteString(entry)
+		end = total
+	}
+
+	if total == 0 {
+		return NewToolResult(fmt.Sprintf("[%s: empty file]", path))
+	}
+	if offset > total {
+		return ErrorResult(fmt.Sprintf("offset %d is past the end of the file (%d lines)", offset, total))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s: lines %d-%d of %d", path, offset, end, total)
+	if encoding != "" {
+		sb.WriteString(", " + encoding)
+	}
+	sb.WriteString("]\n")
+	sb.WriteString(body.String())
+	switch {
+	case next > 0:
+		fmt.Fprintf(&sb, "[Output limit reached; continue with offset=%d]", next)
+	case end < total:
+		fmt.Fprintf(&sb, "[%d more lines; continue with offset=%d]", total-end, end+1)
+	}
+	return NewToolResult(sb.String())
+}
+
+// readLine reads the next line without its line ending. Lines longer than
+// max are reported as truncated and only their first bytes are kept. It
+// returns io.EOF once no lines remain.
+func readLine(br *bufio.Reader, max int) (string, bool, error) {
+	var line []byte
+	n := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		n += len(chunk)
+		// Keep room for a "\r\n" ending so lines up to max stay whole
+		if room := max + 2 - len(line); room > 0 {
+			line = append(line, chunk[:min(room, len(chunk))]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return "", false, err
+		}
+		if n == 0 {
+			return "", false, io.EOF
+		}
+		if n > len(line) {
+			return string(line), true, nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		return string(line), len(line) > max, nil
+	}
+}
+
+// looksLikeUTF8 reports whether data is valid UTF-8, ignoring a rune cut
+// off at the end when data is only the start of a file.
+func looksLikeUTF8(data []byte, truncated bool) bool {
+	if truncated {
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				data = data[:i]
+				break
+			}
+		}
+	}
+	return utf8.Valid(data)
+}
+
+// decodeUTF16 decodes text starting with a UTF-16 byte order mark.
+func decodeUTF16(data []byte) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 0xFE {
+		order = binary.BigEndian
+	}
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units))
 }
 
 type WriteFileTool struct {
@@ -218,6 +447,10 @@ func (t *ListDirTool) Parameters() map[string]interface{} {
 	}
 }
 
+func (t *ListDirTool) ConcurrencySafe() bool {
+	return true
+}
+
 func (t *ListDirTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	path, ok := args["path"].(string)
 	if !ok {
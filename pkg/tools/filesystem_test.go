@@ -2,10 +2,13 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 // TestFilesystemTool_ReadFile_Success verifies successful file reading
@@ -79,6 +82,107 @@ func TestFilesystemTool_ReadFile_MissingPath(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ReadFile_Range verifies line ranges, numbering and continuation hints
+func TestFilesystemTool_ReadFile_Range(t *testing.T) {
+	tmpDir := t.TempDir()
+	var content strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&content, "line %d\r\n", i)
+	}
+	testFile := filepath.Join(tmpDir, "log.txt")
+	_ = os.WriteFile(testFile, []byte(content.String()), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"path":   "log.txt",
+		"offset": float64(3),
+		"limit":  float64(2),
+	})
+	want := "[log.txt: lines 3-4 of 10]\n3: line 3\n4: line 4\n[6 more lines; continue with offset=5]"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("got %q, want %q", result.ForLLM, want)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "log.txt", "offset": float64(11)})
+	if !result.IsError {
+		t.Errorf("Expected error for offset past the end, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_ByteCap verifies large files stop at the byte cap
+func TestFilesystemTool_ReadFile_ByteCap(t *testing.T) {
+	tmpDir := t.TempDir()
+	line := strings.Repeat("x", 99) + "\n"
+	longLine := strings.Repeat("é", readFileMaxLineLength) + "\n"
+	_ = os.WriteFile(filepath.Join(tmpDir, "big.txt"), []byte(longLine+strings.Repeat(line, 1000)), 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "big.txt"})
+	if result.IsError || len(result.ForLLM) > readFileMaxBytes+200 {
+		t.Fatalf("Expected output under the byte cap, got %d bytes", len(result.ForLLM))
+	}
+	if !strings.Contains(result.ForLLM, "of 1001]") || !strings.Contains(result.ForLLM, "[Output limit reached; continue with offset=") {
+		t.Errorf("Expected total lines and continuation hint, got: %s", result.ForLLM[len(result.ForLLM)-200:])
+	}
+	first := strings.SplitN(result.ForLLM, "\n", 3)[1]
+	if !strings.HasSuffix(first, "... [line truncated]") || !utf8.ValidString(first) {
+		t.Errorf("Expected truncated first line, got: %q", first[len(first)-40:])
+	}
+}
+
+// TestFilesystemTool_ReadFile_Binary verifies binary files return a hexdump preview
+func TestFilesystemTool_ReadFile_Binary(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := append([]byte("ELF"), make([]byte, 2000)...)
+	_ = os.WriteFile(filepath.Join(tmpDir, "app.bin"), data, 0644)
+	_ = os.WriteFile(filepath.Join(tmpDir, "sjis.txt"), []byte{0x82, 0xb1, 0x82, 0xf1}, 0644)
+
+	tool := NewReadFileTool(tmpDir, true)
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": "app.bin"})
+	if result.IsError || !strings.Contains(result.ForLLM, "binary or non-UTF-8 file") || !strings.Contains(result.ForLLM, "first 512 bytes") {
+		t.Errorf("Expected hexdump preview, got: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "00000000  45 4c 46 00") {
+		t.Errorf("Expected hexdump, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"path": "sjis.txt"})
+	if !strings.Contains(result.ForLLM, "binary or non-UTF-8 file") {
+		t.Errorf("Expected non-UTF-8 text to be previewed, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_UTF16 verifies UTF-16 files are decoded
+func TestFilesystemTool_ReadFile_UTF16(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := []byte{0xFF, 0xFE}
+	for _, r := range "héllo\nworld\n" {
+		data = append(data, byte(r), byte(r>>8))
+	}
+	_ = os.WriteFile(filepath.Join(tmpDir, "utf16.txt"), data, 0644)
+
+	result := NewReadFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "utf16.txt"})
+	want := "[utf16.txt: lines 1-2 of 2, UTF-16]\n1: héllo\n2: world\n"
+	if result.ForLLM != want {
+		t.Errorf("got %q, want %q", result.ForLLM, want)
+	}
+}
+
+// TestFilesystemTool_ReadFile_Image verifies images are attached as media
+func TestFilesystemTool_ReadFile_Image(t *testing.T) {
+	tmpDir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	_ = os.WriteFile(filepath.Join(tmpDir, "pic.png"), png, 0644)
+
+	result := NewReadFileTool(tmpDir, true).Execute(context.Background(), map[string]interface{}{"path": "pic.png"})
+	if result.IsError || !strings.Contains(result.ForLLM, "image/png image") {
+		t.Fatalf("Expected image result, got: %s", result.ForLLM)
+	}
+	if len(result.Media) != 1 || result.Media[0] != "data:image/png;base64,"+base64.StdEncoding.EncodeToString(png) {
+		t.Errorf("Expected PNG data URL, got: %v", result.Media)
+	}
+}
+
 // TestFilesystemTool_WriteFile_Success verifies successful file writing
 func TestFilesystemTool_WriteFile_Success(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+// HookStage is when a hook runs relative to the tool call.
+type HookStage string
+
+const (
+	HookPre  HookStage = "pre"  // Before the call; may veto it or rewrite its arguments
+	HookPost HookStage = "post" // After the call; may rewrite its result
+)
+
+// defaultHookTimeout applies to command hooks whose config leaves it unset.
+const defaultHookTimeout = 10 * time.Second
+
+// HookCall is what a hook receives: the call, and for post hooks its result.
+// Command hooks read it as JSON on stdin.
+type HookCall struct {
+	Stage     HookStage              `json:"stage"`
+	Tool      string                 `json:"tool"`
+	Args      map[string]interface{} `json:"args"`
+	Channel   string                 `json:"channel,omitempty"`
+	ChatID    string                 `json:"chat_id,omitempty"`
+	Requester []string               `json:"requester,omitempty"`
+	Result    *HookResult            `json:"result,omitempty"` // Post hooks only
+}
+
+// HookResult is the part of a tool result hooks see.
+type HookResult struct {
+	ForLLM  string `json:"for_llm"`
+	ForUser string `json:"for_user,omitempty"`
+	IsError bool   `json:"is_error"`
+}
+
+// HookDecision is what a hook returns. The zero value lets the call proceed
+// unchanged. Command hooks print it as JSON on stdout; empty output is the
+// zero value.
+type HookDecision struct {
+	Deny    bool                   `json:"deny,omitempty"`     // Pre: block the call
+	Reason  string                 `json:"reason,omitempty"`   // Pre: why it was blocked (shown to the LLM)
+	Args    map[string]interface{} `json:"args,omitempty"`     // Pre: replacement arguments
+	ForLLM  *string                `json:"for_llm,omitempty"`  // Post: replacement result content for the LLM
+	ForUser *string                `json:"for_user,omitempty"` // Post: replacement content for the user
+}
+
+// HookFunc is a hook implemented in Go.
+type HookFunc func(ctx context.Context, call HookCall) (HookDecision, error)
+
+type hook struct {
+	rule string // Tool name, tool:action, or "*"
+	name string // For logs
+	fn   HookFunc
+}
+
+// Hooks holds the hooks run around tool calls. Pre hooks run in order before
+// the approval check, each seeing the arguments left by the previous one; a
+// pre hook that fails blocks the call. Post hooks run in order on the result;
+// a post hook that fails is logged and skipped.
+type Hooks struct {
+	mu   sync.RWMutex
+	pre  []hook
+	post []hook
+}
+
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+// NewHooksFromConfig creates hooks running the configured commands in dir.
+func NewHooksFromConfig(cfgs []config.ToolHookConfig, dir string) *Hooks {
+	h := NewHooks()
+	for _, c := range cfgs {
+		stage := HookStage(c.Stage)
+		if (stage != HookPre && stage != HookPost) || len(c.Command) == 0 {
+			logger.WarnCF("tool", "Ignoring invalid tool hook",
+				map[string]interface{}{
+					"tool":  c.Tool,
+					"stage": c.Stage,
+				})
+			continue
+		}
+		timeout := time.Duration(c.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = defaultHookTimeout
+		}
+		h.add(stage, c.Tool, strings.Join(c.Command, " "), CommandHook(c.Command, dir, timeout))
+	}
+	return h
+}
+
+// Add registers a Go hook for calls matching rule: a tool name, a tool and
+// action ("android:dial"), or "*" for every tool.
+func (h *Hooks) Add(stage HookStage, rule string, fn HookFunc) {
+	h.add(stage, rule, "func", fn)
+}
+
+func (h *Hooks) add(stage HookStage, rule, name string, fn HookFunc) {
+	if rule == "" {
+		rule = "*"
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if stage == HookPre {
+		h.pre = append(h.pre, hook{rule: rule, name: name, fn: fn})
+	} else {
+		h.post = append(h.post, hook{rule: rule, name: name, fn: fn})
+	}
+}
+
+// matching returns the hooks of a stage that cover a call.
+func (h *Hooks) matching(stage HookStage, name string, args map[string]interface{}) []hook {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	all := h.post
+	if stage == HookPre {
+		all = h.pre
+	}
+	var matched []hook
+	for _, hk := range all {
+		if matchesRule([]string{hk.rule}, name, args) {
+			matched = append(matched, hk)
+		}
+	}
+	return matched
+}
+
+// RunPre runs the pre hooks of a call. It returns the arguments to use, or a
+// result to report to the LLM instead of running the call.
+func (h *Hooks) RunPre(ctx context.Context, call HookCall) (map[string]interface{}, *ToolResult) {
+	if h == nil {
+		return call.Args, nil
+	}
+	call.Stage = HookPre
+	for _, hk := range h.matching(HookPre, call.Tool, call.Args) {
+		d, err := hk.fn(ctx, call)
+		if err != nil {
+			logger.WarnCF("tool", "Pre-execution hook failed, blocking call",
+				map[string]interface{}{
+					"tool":  call.Tool,
+					"hook":  hk.name,
+					"error": err.Error(),
+				})
+			return nil, ErrorResult(fmt.Sprintf("%s was blocked because a pre-execution hook failed: %v", call.Tool, err)).WithError(err)
+		}
+		if d.Deny {
+			logger.InfoCF("tool", "Tool call denied by hook",
+				map[string]interface{}{
+					"tool":   call.Tool,
+					"hook":   hk.name,
+					"reason": d.Reason,
+				})
+			reason := d.Reason
+			if reason == "" {
+				reason = "denied by policy"
+			}
+			return nil, ErrorResult(fmt.Sprintf("%s was blocked: %s", call.Tool, reason))
+		}
+		if d.Args != nil {
+			call.Args = d.Args
+		}
+	}
+	return call.Args, nil
+}
+
+// RunPost runs the post hooks of a call on its result, rewriting the result
+// in place.
+func (h *Hooks) RunPost(ctx context.Context, call HookCall, result *ToolResult) {
+	if h == nil || result == nil {
+		return
+	}
+	call.Stage = HookPost
+	for _, hk := range h.matching(HookPost, call.Tool, call.Args) {
+		call.Result = &HookResult{ForLLM: result.ForLLM, ForUser: result.ForUser, IsError: result.IsError}
+		d, err := hk.fn(ctx, call)
+		if err != nil {
+			logger.WarnCF("tool", "Post-execution hook failed",
+				map[string]interface{}{
+					"tool":  call.Tool,
+					"hook":  hk.name,
+					"error": err.Error(),
+				})
+			continue
+		}
+		if d.ForLLM != nil {
+			result.ForLLM = *d.ForLLM
+		}
+		if d.ForUser != nil {
+			result.ForUser = *d.ForUser
+		}
+	}
+}
+
+// CommandHook returns a hook that runs an external command in dir with the
+// call as JSON on stdin and reads its decision as JSON from stdout. A
+// non-zero exit status is an error, which blocks the call in the pre stage.
+func CommandHook(argv []string, dir string, timeout time.Duration) HookFunc {
+	return func(ctx context.Context, call HookCall) (HookDecision, error) {
+		input, err := json.Marshal(call)
+		if err != nil {
+			return HookDecision{}, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Env = append(os.Environ(),
+			"CLAWDROID_HOOK_STAGE="+string(call.Stage),
+			"CLAWDROID_HOOK_TOOL="+call.Tool,
+		)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return HookDecision{}, fmt.Errorf("timed out after %s", timeout)
+			}
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return HookDecision{}, fmt.Errorf("%w: %s", err, msg)
+			}
+			return HookDecision{}, err
+		}
+
+		var d HookDecision
+		if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+			if err := json.Unmarshal(out, &d); err != nil {
+				return HookDecision{}, fmt.Errorf("invalid hook output: %w", err)
+			}
+		}
+		return d, nil
+	}
+}
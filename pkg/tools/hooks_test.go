@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+// echoArgsTool returns its "text" argument.
+type echoArgsTool struct{ calls int }
+
+func (e *echoArgsTool) Name() string        { return "echo" }
+func (e *echoArgsTool) Description() string { return "echo for testing" }
+func (e *echoArgsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+}
+func (e *echoArgsTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	e.calls++
+	text, _ := args["text"].(string)
+	return NewToolResult(text)
+}
+
+func TestHooks_RewriteArgsAndResult(t *testing.T) {
+	tool := &echoArgsTool{}
+	r := NewToolRegistry()
+	r.Register(tool)
+
+	hooks := NewHooks()
+	var seen []string
+	hooks.Add(HookPre, "*", func(ctx context.Context, call HookCall) (HookDecision, error) {
+		seen = append(seen, "pre:"+call.Tool)
+		return HookDecision{Args: map[string]interface{}{"text": "token=secret rewritten"}}, nil
+	})
+	hooks.Add(HookPost, "echo", func(ctx context.Context, call HookCall) (HookDecision, error) {
+		seen = append(seen, "post:"+call.Result.ForLLM)
+		scrubbed := strings.ReplaceAll(call.Result.ForLLM, "secret", "***")
+		return HookDecision{ForLLM: &scrubbed}, nil
+	})
+	hooks.Add(HookPost, "other", func(ctx context.Context, call HookCall) (HookDecision, error) {
+		t.Error("hook for another tool ran")
+		return HookDecision{}, nil
+	})
+	r.SetHooks(hooks)
+
+	res := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "original"})
+	if res.ForLLM != "token=*** rewritten" {
+		t.Errorf("result = %q, want rewritten and scrubbed", res.ForLLM)
+	}
+	if got := strings.Join(seen, ","); got != "pre:echo,post:token=secret rewritten" {
+		t.Errorf("hooks ran as %s", got)
+	}
+}
+
+func TestHooks_VetoAndFailureBlockCall(t *testing.T) {
+	tool := &echoArgsTool{}
+	r := NewToolRegistry()
+	r.Register(tool)
+
+	hooks := NewHooks()
+	hooks.Add(HookPre, "echo", func(ctx context.Context, call HookCall) (HookDecision, error) {
+		if call.Args["text"] == "fail" {
+			return HookDecision{}, errors.New("policy server down")
+		}
+		return HookDecision{Deny: call.Args["text"] == "deny", Reason: "not allowed here"}, nil
+	})
+	r.SetHooks(hooks)
+
+	if res := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "deny"}); !res.IsError || !strings.Contains(res.ForLLM, "not allowed here") {
+		t.Errorf("denied call result = %+v", res)
+	}
+	if res := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "fail"}); !res.IsError || !strings.Contains(res.ForLLM, "policy server down") {
+		t.Errorf("failed hook result = %+v", res)
+	}
+	if tool.calls != 0 {
+		t.Errorf("tool ran %d times, want 0", tool.calls)
+	}
+}
+
+func TestCommandHook_ReadsCallFromStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	// Denies calls mentioning "rm", logs every call to audit.log
+	body := "#!/bin/sh\ninput=$(cat)\necho \"$CLAWDROID_HOOK_STAGE $input\" >> audit.log\n" +
+		"case \"$input\" in *rm*) echo '{\"deny\": true, \"reason\": \"no rm\"}' ;; esac\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := NewHooksFromConfig([]config.ToolHookConfig{
+		{Tool: "echo", Stage: "pre", Command: []string{script}},
+		{Tool: "echo", Stage: "bogus", Command: []string{script}},
+	}, dir)
+	r := NewToolRegistry()
+	r.Register(&echoArgsTool{})
+	r.SetHooks(hooks)
+
+	if res := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "ls"}); res.ForLLM != "ls" {
+		t.Errorf("allowed call result = %+v", res)
+	}
+	if res := r.Execute(context.Background(), "echo", map[string]interface{}{"text": "rm -rf /"}); !strings.Contains(res.ForLLM, "no rm") {
+		t.Errorf("denied call result = %+v", res)
+	}
+
+	audit, err := os.ReadFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(audit)), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], `pre {"stage":"pre","tool":"echo"`) {
+		t.Errorf("audit log = %q", audit)
+	}
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+// DefaultMaxOutputs is how many stored outputs a session keeps.
+const DefaultMaxOutputs = 200
+
+// MaxResultChars is the size from which a registry with an output store
+// stores a tool result and gives the LLM a preview instead.
+const MaxResultChars = 50000
+
+// read_output limits: characters per page by default and at most, matching
+// lines returned by a search, and lines returned by a slice by default.
+const (
+	readOutputLimit      = 8000
+	readOutputMaxLimit   = 20000
+	readOutputMaxMatches = 200
+	readOutputSliceLines = 200
+)
+
+var outputIDPattern = regexp.MustCompile(`^out-[0-9a-f]{8}$`)
+
+type sessionKeyCtx struct{}
+
+// WithSession attaches the session key to ctx so tools can find
+// session-scoped data such as stored outputs.
+func WithSession(ctx context.Context, sessionKey string) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, sessionKey)
+}
+
+// SessionFrom returns the session key attached to ctx ("" if none).
+func SessionFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyCtx{}).(string)
+	return key
+}
+
+// OutputStore keeps full tool outputs that were too large for the
+// conversation or collapsed out of it, one directory per session, so the LLM
+// can read them back by ID.
+type OutputStore struct {
+	dir    string
+	max    int
+	redact func(string) string // Masks secrets before outputs are written (nil = as is)
+}
+
+func NewOutputStore(dir string, max int) *OutputStore {
+	if max <= 0 {
+		max = DefaultMaxOutputs
+	}
+	return &OutputStore{dir: dir, max: max}
+}
+
+// SetRedact sets a function that masks secrets in outputs before they are
+// written to disk.
+func (s *OutputStore) SetRedact(fn func(string) string) {
+	s.redact = fn
+}
+
+func (s *OutputStore) sessionDir(sessionKey string) string {
+	if sessionKey == "" {
+		sessionKey = "default"
+	}
+	return filepath.Join(s.dir, strings.ReplaceAll(sessionKey, ":", "_"))
+}
+
+// Put stores an output for a session and returns its ID. The session's
+// oldest outputs are removed beyond the store's limit.
+func (s *OutputStore) Put(sessionKey, content string) (string, error) {
+	dir := s.sessionDir(sessionKey)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := "out-" + hex.EncodeToString(b)
+	if s.redact != nil {
+		content = s.redact(content)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id+".txt"), []byte(content), 0644); err != nil {
+		return "", err
+	}
+	s.prune(dir)
+	return id, nil
+}
+
+// Get returns a stored output of a session.
+func (s *OutputStore) Get(sessionKey, id string) (string, error) {
+	if !outputIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid output id: %s", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.sessionDir(sessionKey), id+".txt"))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("output not found: %s", id)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// prune removes the oldest outputs in dir beyond the limit.
+func (s *OutputStore) prune(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) <= s.max {
+		return
+	}
+	type file struct {
+		name string
+		mod  int64
+	}
+	files := make([]file, 0, len(entries))
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil {
+			files = append(files, file{e.Name(), info.ModTime().UnixNano()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod < files[j].mod })
+	for _, f := range files[:len(files)-s.max] {
+		_ = os.Remove(filepath.Join(dir, f.name))
+	}
+}
+
+// Preview returns content unchanged when it fits in limit characters.
+// Otherwise the full content is stored for the session in ctx and a head and
+// tail preview naming its ID is returned, so the rest can be read with
+// read_output. Without a store, or if storing fails, the middle is dropped.
+func (s *OutputStore) Preview(ctx context.Context, content string, limit int) string {
+	runes := []rune(content)
+	if len(runes) <= limit {
+		return content
+	}
+	head := limit * 2 / 3
+	tail := limit - head
+	omitted := len(runes) - head - tail
+
+	note := fmt.Sprintf("\n... (truncated, %d more chars) ...\n", omitted)
+	if s != nil {
+		id, err := s.Put(SessionFrom(ctx), content)
+		if err == nil {
+			note = fmt.Sprintf("\n... (%d of %d chars omitted; full output stored as %s, use read_output to page, grep or slice it) ...\n",
+				omitted, len(runes), id)
+		} else {
+			logger.WarnCF("tool", "Failed to store tool output",
+				map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return string(runes[:head]) + note + string(runes[len(runes)-tail:])
+}
+
+// ReadOutputTool reads back stored outputs of the current session.
+type ReadOutputTool struct {
+	store *OutputStore
+}
+
+func NewReadOutputTool(store *OutputStore) *ReadOutputTool {
+	return &ReadOutputTool{store: store}
+}
+
+func (t *ReadOutputTool) Name() string {
+	return "read_output"
+}
+
+func (t *ReadOutputTool) Description() string {
+	return "Read a stored tool output by its ID (e.g. out-1a2b3c4d). Oversized and collapsed tool outputs are stored this way. Page through it with offset/limit, search it with pattern, or slice lines with start_line/end_line."
+}
+
+func (t *ReadOutputTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Output ID",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset to start reading from (default 0)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum characters to return (default %d, max %d)", readOutputLimit, readOutputMaxLimit),
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression; returns the matching lines with their line numbers",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line to return (1-based)",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last line to return (inclusive, default start_line + 199)",
+			},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *ReadOutputTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	id, _ := args["id"].(string)
+	if id == "" {
+		return ErrorResult("id is required")
+	}
+	content, err := t.store.Get(SessionFrom(ctx), id)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	if pattern, _ := args["pattern"].(string); pattern != "" {
+		return grepOutput(id, content, pattern)
+	}
+	if _, ok := args["start_line"]; ok {
+		return sliceOutput(id, content, intArg(args, "start_line", 1), intArg(args, "end_line", 0))
+	}
+	return pageOutput(id, content, intArg(args, "offset", 0), intArg(args, "limit", readOutputLimit))
+}
+
+// pageOutput returns limit characters from offset.
+func pageOutput(id, content string, offset, limit int) *ToolResult {
+	runes := []rune(content)
+	if offset < 0 || offset > len(runes) {
+		return ErrorResult(fmt.Sprintf("offset %d is outside the output (%d characters)", offset, len(runes)))
+	}
+	if limit <= 0 {
+		limit = readOutputLimit
+	}
+	if limit > readOutputMaxLimit {
+		limit = readOutputMaxLimit
+	}
+	end := offset + limit
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	header := fmt.Sprintf("[%s: characters %d-%d of %d]\n", id, offset, end, len(runes))
+	footer := ""
+	if end < len(runes) {
+		footer = fmt.Sprintf("\n[Continue with offset=%d]", end)
+	}
+	return SilentResult(header + string(runes[offset:end]) + footer)
+}
+
+// grepOutput returns the lines matching pattern, numbered.
+func grepOutput(id, content, pattern string) *ToolResult {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+
+	var sb strings.Builder
+	matches := 0
+	for i, line := range strings.Split(content, "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		matches++
+		if matches > readOutputMaxMatches || sb.Len() > readOutputMaxLimit {
+			continue // Keep counting
+		}
+		fmt.Fprintf(&sb, "%d: %s\n", i+1, line)
+	}
+
+	if matches == 0 {
+		return SilentResult(fmt.Sprintf("[%s: no lines match %q]", id, pattern))
+	}
+	header := fmt.Sprintf("[%s: %d lines match %q]\n", id, matches, pattern)
+	if shown := strings.Count(sb.String(), "\n"); shown < matches {
+		header = fmt.Sprintf("[%s: %d lines match %q, showing the first %d]\n", id, matches, pattern, shown)
+	}
+	return SilentResult(header + sb.String())
+}
+
+// sliceOutput returns lines start through end (1-based, inclusive).
+func sliceOutput(id, content string, start, end int) *ToolResult {
+	lines := strings.Split(content, "\n")
+	if start < 1 || start > len(lines) {
+		return ErrorResult(fmt.Sprintf("start_line %d is outside the output (%d lines)", start, len(lines)))
+	}
+	if end <= 0 {
+		end = start + readOutputSliceLines - 1
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	if end < start {
+		return ErrorResult(fmt.Sprintf("end_line %d is before start_line %d", end, start))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s: lines %d-%d of %d]\n", id, start, end, len(lines))
+	for i := start; i <= end; i++ {
+		if sb.Len() > readOutputMaxLimit {
+			fmt.Fprintf(&sb, "[Output limit reached; continue with start_line=%d]", i)
+			break
+		}
+		fmt.Fprintf(&sb, "%d: %s\n", i, lines[i-1])
+	}
+	return SilentResult(sb.String())
+}
+
+// intArg reads an integer argument, which arrives from JSON as float64.
+func intArg(args map[string]interface{}, name string, def int) int {
+	switch v := args[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOutputStore_SessionScopedAndPruned(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 2)
+	var ids []string
+	for _, content := range []string{"first", "second", "third"} {
+		id, err := store.Put("telegram:1", content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	if got, err := store.Get("telegram:1", ids[2]); err != nil || got != "third" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if _, err := store.Get("telegram:2", ids[2]); err == nil {
+		t.Error("outputs should not be visible to other sessions")
+	}
+	if _, err := store.Get("telegram:1", ids[0]); err == nil {
+		t.Error("oldest output should have been pruned")
+	}
+	if _, err := store.Get("telegram:1", "../../etc/passwd"); err == nil {
+		t.Error("invalid IDs should be rejected")
+	}
+}
+
+func TestOutputStore_RedactsBeforeWriting(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 0)
+	store.SetRedact(func(text string) string { return strings.ReplaceAll(text, "hunter2", "[REDACTED]") })
+	id, err := store.Put("telegram:1", "password hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.Get("telegram:1", id); got != "password [REDACTED]" {
+		t.Errorf("stored = %q", got)
+	}
+}
+
+func TestReadOutputTool_Pages(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 0)
+	id, err := store.Put("s1", "abcdefghij")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewReadOutputTool(store)
+	ctx := WithSession(context.Background(), "s1")
+
+	res := tool.Execute(ctx, map[string]interface{}{"id": id, "offset": float64(2), "limit": float64(4)})
+	if res.IsError || !strings.Contains(res.ForLLM, "\ncdef\n") || !strings.HasSuffix(res.ForLLM, "[Continue with offset=6]") {
+		t.Errorf("page = %q", res.ForLLM)
+	}
+	res = tool.Execute(ctx, map[string]interface{}{"id": id, "offset": float64(20)})
+	if !res.IsError {
+		t.Error("offset past the end should be an error")
+	}
+}
+
+func TestOutputStore_PreviewKeepsHeadAndTail(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 0)
+	ctx := WithSession(context.Background(), "s1")
+	content := "HEAD" + strings.Repeat("x", 1000) + "TAIL"
+
+	if got := store.Preview(ctx, "short", 100); got != "short" {
+		t.Errorf("short content changed: %q", got)
+	}
+	preview := store.Preview(ctx, content, 90)
+	if !strings.HasPrefix(preview, "HEAD") || !strings.HasSuffix(preview, "TAIL") {
+		t.Errorf("preview lost head or tail: %q", preview)
+	}
+	id := regexp.MustCompile(`out-[0-9a-f]{8}`).FindString(preview)
+	if full, err := store.Get("s1", id); err != nil || full != content {
+		t.Errorf("stored output %q = %d chars, %v", id, len(full), err)
+	}
+
+	var nilStore *OutputStore
+	if got := nilStore.Preview(ctx, content, 90); !strings.Contains(got, "(truncated, 918 more chars)") {
+		t.Errorf("preview without store = %q", got)
+	}
+}
+
+func TestReadOutputTool_GrepAndSlice(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 0)
+	id, err := store.Put("s1", "alpha\nERROR disk full\nbeta\nERROR retry\ngamma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewReadOutputTool(store)
+	ctx := WithSession(context.Background(), "s1")
+
+	res := tool.Execute(ctx, map[string]interface{}{"id": id, "pattern": "^ERROR"})
+	if !strings.Contains(res.ForLLM, "2 lines match") || !strings.Contains(res.ForLLM, "2: ERROR disk full\n4: ERROR retry\n") {
+		t.Errorf("grep = %q", res.ForLLM)
+	}
+	res = tool.Execute(ctx, map[string]interface{}{"id": id, "start_line": float64(3), "end_line": float64(4)})
+	if !strings.HasSuffix(res.ForLLM, "lines 3-4 of 5]\n3: beta\n4: ERROR retry\n") {
+		t.Errorf("slice = %q", res.ForLLM)
+	}
+	if res := tool.Execute(ctx, map[string]interface{}{"id": id, "pattern": "("}); !res.IsError {
+		t.Error("invalid pattern should be an error")
+	}
+}
+
+func TestRegistry_StoresOversizedResults(t *testing.T) {
+	store := NewOutputStore(t.TempDir(), 0)
+	r := NewToolRegistry()
+	r.Register(&echoArgsTool{})
+	r.SetOutputStore(store)
+
+	big := strings.Repeat("y", MaxResultChars+10)
+	res := r.Execute(WithSession(context.Background(), "s1"), "echo", map[string]interface{}{"text": big})
+	if !strings.Contains(res.ForLLM, "full output stored as out-") {
+		t.Errorf("oversized result was not stored: %d chars", len(res.ForLLM))
+	}
+}
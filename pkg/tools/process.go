@@ -0,0 +1,599 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/logger"
+)
+
+// Background process limits.
+const (
+	maxProcessOutput    = 1 << 20 // Output kept per process; older output is dropped
+	maxProcessPoll      = 10000   // Output bytes returned by one poll
+	maxRunningProcesses = 8
+	maxProcessRecords   = 32 // Finished processes beyond this are forgotten
+	processNotifyTail   = 2000
+	processWaitDelay    = 2 * time.Second // Output pipes held open by orphans are closed after this
+
+	// DefaultProcessRuntime is how long a background process may run before
+	// it is killed, unless SetMaxRuntime sets another limit.
+	DefaultProcessRuntime = time.Hour
+)
+
+// processStartWait is how long start waits so commands that fail right away
+// are reported with their output.
+var processStartWait = 300 * time.Millisecond
+
+// ProcessManager runs the process tool's background commands. A process
+// belongs to the session that started it: other sessions cannot see it,
+// and it is killed when the session is reset. When a process exits on its
+// own or hits the runtime limit, a notification is published on the system
+// channel.
+type ProcessManager struct {
+	mu         sync.Mutex
+	procs      map[string]*backgroundProcess
+	order      []string // IDs in start order
+	nextID     int
+	bus        *bus.MessageBus
+	maxRuntime time.Duration
+}
+
+type backgroundProcess struct {
+	id         string
+	command    string
+	sessionKey string
+	channel    string
+	chatID     string
+	senderID   string // Sender who started the process
+	started    time.Time
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	out        *processOutput
+	done       chan struct{}
+	limit      *time.Timer // Kills the process at the runtime limit
+
+	mu       sync.Mutex
+	ended    time.Time
+	status   string // Set on exit, e.g. "exit status 1"
+	killed   bool   // Killed by the agent or the session; no notification
+	expired  bool   // Killed at the runtime limit
+	starting bool   // start still waits for an early exit, which it reports itself
+}
+
+// NewProcessManager creates a ProcessManager publishing exit notifications
+// to msgBus (nil = no notifications).
+func NewProcessManager(msgBus *bus.MessageBus) *ProcessManager {
+	return &ProcessManager{
+		procs:      make(map[string]*backgroundProcess),
+		nextID:     1,
+		bus:        msgBus,
+		maxRuntime: DefaultProcessRuntime,
+	}
+}
+
+// SetMaxRuntime sets how long processes started afterwards may run before
+// they are killed. Values <= 0 keep the current limit.
+func (m *ProcessManager) SetMaxRuntime(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxRuntime = d
+}
+
+// start runs cmd in the background for the session.
+func (m *ProcessManager) start(cmd *exec.Cmd, command, sessionKey, channel, chatID, senderID string) (*backgroundProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running := 0
+	for _, p := range m.procs {
+		if p.running() {
+			running++
+		}
+	}
+	if running >= maxRunningProcesses {
+		return nil, fmt.Errorf("too many background processes running (%d); kill one first", running)
+	}
+
+	p := &backgroundProcess{
+		id:         fmt.Sprintf("proc-%d", m.nextID),
+		command:    command,
+		sessionKey: sessionKey,
+		channel:    channel,
+		chatID:     chatID,
+		senderID:   senderID,
+		cmd:        cmd,
+		out:        &processOutput{},
+		done:       make(chan struct{}),
+		starting:   true,
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdin = stdin
+	cmd.Stdout = p.out
+	cmd.Stderr = p.out
+	cmd.WaitDelay = processWaitDelay
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p.started = time.Now()
+	p.limit = time.AfterFunc(m.maxRuntime, p.expire)
+
+	m.nextID++
+	m.procs[p.id] = p
+	m.order = append(m.order, p.id)
+	m.prune()
+
+	logger.InfoCF("process", "Started background process",
+		map[string]interface{}{
+			"id":          p.id,
+			"pid":         cmd.Process.Pid,
+			"command":     command,
+			"session_key": sessionKey,
+		})
+
+	go m.wait(p)
+	return p, nil
+}
+
+// prune forgets the oldest finished processes beyond maxProcessRecords.
+// Callers must hold m.mu.
+func (m *ProcessManager) prune() {
+	for i := 0; len(m.order) > maxProcessRecords && i < len(m.order); {
+		id := m.order[i]
+		if m.procs[id].running() {
+			i++
+			continue
+		}
+		delete(m.procs, id)
+		m.order = append(m.order[:i], m.order[i+1:]...)
+	}
+}
+
+func (m *ProcessManager) wait(p *backgroundProcess) {
+	err := p.cmd.Wait()
+	p.limit.Stop()
+	_ = p.stdin.Close()
+
+	status := "exited"
+	if p.cmd.ProcessState != nil {
+		status = p.cmd.ProcessState.String()
+	} else if err != nil {
+		status = err.Error()
+	}
+	p.mu.Lock()
+	p.ended = time.Now()
+	p.status = status
+	killed := p.killed
+	notify := !killed && !p.starting
+	p.mu.Unlock()
+	close(p.done)
+
+	logger.InfoCF("process", "Background process exited",
+		map[string]interface{}{
+			"id":     p.id,
+			"status": status,
+			"killed": killed,
+		})
+
+	if notify {
+		m.notify(p)
+	}
+}
+
+// notify tells the agent that a process exited on its own.
+func (m *ProcessManager) notify(p *backgroundProcess) {
+	if m.bus == nil || p.channel == "" || p.chatID == "" {
+		return
+	}
+	tail, _, _ := p.out.read(p.out.size()-processNotifyTail, processNotifyTail)
+	content := fmt.Sprintf("Background process %s (%s) %s.", p.id, p.command, p.describe())
+	if tail != "" {
+		content += "\n\nLast output:\n" + tail
+	}
+	m.bus.PublishInbound(bus.InboundMessage{
+		Channel:  "system",
+		SenderID: "process:" + p.id,
+		// Format: "original_channel:original_chat_id" for routing back
+		ChatID:     fmt.Sprintf("%s:%s", p.channel, p.chatID),
+		Content:    content,
+		SessionKey: p.sessionKey, // Queued behind the session's running turn
+		Metadata:   map[string]string{"origin_sender": p.senderID},
+	})
+}
+
+// get returns the session's process with the given ID.
+func (m *ProcessManager) get(id, sessionKey string) (*backgroundProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.procs[id]
+	if !ok || p.sessionKey != sessionKey {
+		return nil, fmt.Errorf("no background process %q in this session", id)
+	}
+	return p, nil
+}
+
+// list returns the session's processes in start order.
+func (m *ProcessManager) list(sessionKey string) []*backgroundProcess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*backgroundProcess
+	for _, id := range m.order {
+		if p := m.procs[id]; p.sessionKey == sessionKey {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// KillSession kills the running processes of a session, e.g. on /reset.
+func (m *ProcessManager) KillSession(sessionKey string) {
+	for _, p := range m.list(sessionKey) {
+		p.kill()
+	}
+}
+
+// StopAll kills every running process, on shutdown.
+func (m *ProcessManager) StopAll() {
+	m.mu.Lock()
+	procs := make([]*backgroundProcess, 0, len(m.procs))
+	for _, p := range m.procs {
+		procs = append(procs, p)
+	}
+	m.mu.Unlock()
+	for _, p := range procs {
+		p.kill()
+	}
+}
+
+func (p *backgroundProcess) running() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// kill kills the process group without notifying the agent, and waits
+// briefly for it to exit.
+func (p *backgroundProcess) kill() {
+	if !p.running() {
+		return
+	}
+	p.mu.Lock()
+	p.killed = true
+	p.mu.Unlock()
+	_ = signalProcess(p.cmd, processSignals["KILL"])
+	select {
+	case <-p.done:
+	case <-time.After(processWaitDelay + time.Second):
+	}
+}
+
+// expire kills the process group at the runtime limit. Unlike kill, the
+// agent is notified of the exit.
+func (p *backgroundProcess) expire() {
+	if !p.running() {
+		return
+	}
+	p.mu.Lock()
+	p.expired = true
+	p.mu.Unlock()
+	logger.WarnCF("process", "Background process reached its time limit",
+		map[string]interface{}{"id": p.id, "command": p.command})
+	_ = signalProcess(p.cmd, processSignals["KILL"])
+}
+
+// describe returns the process state, e.g. "running for 12s" or
+// "exit status 1 after 3s".
+func (p *backgroundProcess) describe() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.status == "" {
+		return fmt.Sprintf("running for %s (pid %d)", time.Since(p.started).Round(time.Second), p.cmd.Process.Pid)
+	}
+	status := p.status
+	if p.killed {
+		status = "killed"
+	} else if p.expired {
+		status = "killed at the time limit"
+	}
+	return fmt.Sprintf("%s after %s", status, p.ended.Sub(p.started).Round(time.Second))
+}
+
+// processOutput is the combined stdout and stderr of a process. It keeps
+// the last maxProcessOutput bytes; offsets count from the first byte ever
+// written, so they stay valid as old output is dropped.
+type processOutput struct {
+	mu      sync.Mutex
+	buf     []byte
+	dropped int64 // Bytes dropped from the front of buf
+}
+
+func (o *processOutput) Write(b []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.buf = append(o.buf, b...)
+	if excess := len(o.buf) - maxProcessOutput; excess > 0 {
+		o.buf = append(o.buf[:0], o.buf[excess:]...)
+		o.dropped += int64(excess)
+	}
+	return len(b), nil
+}
+
+// size returns the total number of bytes written.
+func (o *processOutput) size() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.dropped + int64(len(o.buf))
+}
+
+// read returns up to limit bytes from offset, cut at a UTF-8 boundary, the
+// offset they actually start at (later than offset when that output was
+// dropped), and the offset to continue from.
+func (o *processOutput) read(offset int64, limit int) (string, int64, int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	end := o.dropped + int64(len(o.buf))
+	offset = max(offset, o.dropped)
+	offset = min(offset, end)
+	data := o.buf[offset-o.dropped:]
+	if len(data) > limit {
+		n := limit
+		for n > 0 && !utf8.RuneStart(data[n]) {
+			n--
+		}
+		data = data[:n]
+	}
+	return string(data), offset, offset + int64(len(data))
+}
+
+// ProcessTool lets the agent run long-lived commands (builds, servers,
+// watchers) in the background and check on them across turns. Commands go
+// through the exec tool's safety guard and sandbox.
+type ProcessTool struct {
+	manager *ProcessManager
+	exec    *ExecTool
+	mu      sync.Mutex
+	channel string
+	chatID  string
+}
+
+// NewProcessTool creates a ProcessTool starting commands like execTool.
+func NewProcessTool(manager *ProcessManager, execTool *ExecTool) *ProcessTool {
+	return &ProcessTool{manager: manager, exec: execTool}
+}
+
+func (t *ProcessTool) Name() string {
+	return "process"
+}
+
+func (t *ProcessTool) Description() string {
+	return "Run long-lived shell commands (builds, dev servers, watchers) in the background. Actions: 'start' a command (returns an ID), 'poll' its output from an offset, 'write' to its stdin, send it a 'signal', 'list' processes, or 'kill' one. You are notified when a started process exits on its own. Use exec for short commands."
+}
+
+func (t *ProcessTool) Parameters() map[string]interface{} {
+	signals := make([]string, 0, len(processSignals))
+	for name := range processSignals {
+		signals = append(signals, name)
+	}
+	sort.Strings(signals)
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"start", "poll", "write", "signal", "list", "kill"},
+				"description": "What to do",
+			},
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Shell command to start (start)",
+			},
+			"working_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional working directory (start)",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Process ID returned by start (poll, write, signal, kill)",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Output offset to read from; use the next offset of the previous poll (poll, default 0)",
+			},
+			"input": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to write to stdin; include a trailing newline to submit a line (write)",
+			},
+			"eof": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Close stdin after writing (write)",
+			},
+			"signal": map[string]interface{}{
+				"type":        "string",
+				"enum":        signals,
+				"description": "Signal to send to the process and its children (signal)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ProcessTool) SetContext(channel, chatID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.channel = channel
+	t.chatID = chatID
+}
+
+func (t *ProcessTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	sessionKey := SessionFrom(ctx)
+
+	switch action {
+	case "start":
+		// The exit notice is handled as the sender who started the process
+		var senderID string
+		if ids := RequesterFrom(ctx).IDs; len(ids) > 0 {
+			senderID = ids[0]
+		}
+		return t.start(sessionKey, senderID, args)
+	case "list":
+		return t.list(sessionKey)
+	case "poll", "write", "signal", "kill":
+	default:
+		return ErrorResult("action must be one of start, poll, write, signal, list, kill")
+	}
+
+	id, _ := args["id"].(string)
+	if id == "" {
+		return ErrorResult("id is required")
+	}
+	p, err := t.manager.get(id, sessionKey)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	switch action {
+	case "poll":
+		return t.poll(p, args)
+	case "write":
+		return t.write(p, args)
+	case "signal":
+		return t.signal(p, args)
+	default:
+		p.kill()
+		return SilentResult(fmt.Sprintf("Process %s: %s", p.id, p.describe()))
+	}
+}
+
+func (t *ProcessTool) start(sessionKey, senderID string, args map[string]interface{}) *ToolResult {
+	command, _ := args["command"].(string)
+	if command == "" {
+		return ErrorResult("command is required")
+	}
+	cwd := t.exec.commandDir(args)
+	if guardError := t.exec.guardCommand(command, cwd); guardError != "" {
+		return ErrorResult(guardError)
+	}
+	// Not tied to the turn: the process outlives it until killed
+	cmd, err := t.exec.shellCommand(context.Background(), command, cwd)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	t.mu.Lock()
+	channel, chatID := t.channel, t.chatID
+	t.mu.Unlock()
+	p, err := t.manager.start(cmd, command, sessionKey, channel, chatID, senderID)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to start process: %v", err))
+	}
+
+	select {
+	case <-p.done:
+	case <-time.After(processStartWait):
+	}
+	p.mu.Lock()
+	p.starting = false
+	exited := p.status != ""
+	p.mu.Unlock()
+	if exited {
+		<-p.done
+		output, _, next := p.out.read(0, maxProcessPoll)
+		msg := fmt.Sprintf("Process %s %s.\n%s", p.id, p.describe(), outputBlock(output, 0, next, p.out.size()))
+		return &ToolResult{ForLLM: msg, IsError: !p.cmd.ProcessState.Success()}
+	}
+	return NewToolResult(fmt.Sprintf("Started process %s (pid %d). Poll it with {\"action\": \"poll\", \"id\": %q}; you will be notified when it exits.",
+		p.id, p.cmd.Process.Pid, p.id))
+}
+
+func (t *ProcessTool) list(sessionKey string) *ToolResult {
+	procs := t.manager.list(sessionKey)
+	if len(procs) == 0 {
+		return SilentResult("No background processes in this session.")
+	}
+	var sb strings.Builder
+	for _, p := range procs {
+		fmt.Fprintf(&sb, "%s: %s, %d bytes of output: %s\n", p.id, p.describe(), p.out.size(), p.command)
+	}
+	return SilentResult(strings.TrimRight(sb.String(), "\n"))
+}
+
+func (t *ProcessTool) poll(p *backgroundProcess, args map[string]interface{}) *ToolResult {
+	var offset int64
+	if v, ok := args["offset"].(float64); ok && v > 0 {
+		offset = int64(v)
+	}
+	output, from, next := p.out.read(offset, maxProcessPoll)
+	header := fmt.Sprintf("Process %s %s.", p.id, p.describe())
+	if from > offset {
+		header += fmt.Sprintf("\nOutput before offset %d was dropped.", from)
+	}
+	return SilentResult(header + "\n" + outputBlock(output, from, next, p.out.size()))
+}
+
+func (t *ProcessTool) write(p *backgroundProcess, args map[string]interface{}) *ToolResult {
+	if !p.running() {
+		return ErrorResult(fmt.Sprintf("process %s is not running", p.id))
+	}
+	input, _ := args["input"].(string)
+	if input != "" {
+		if _, err := io.WriteString(p.stdin, input); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to write to process %s: %v", p.id, err))
+		}
+	}
+	eof, _ := args["eof"].(bool)
+	if eof {
+		_ = p.stdin.Close()
+	}
+	msg := fmt.Sprintf("Wrote %d bytes to process %s.", len(input), p.id)
+	if eof {
+		msg += " Closed stdin."
+	}
+	return SilentResult(msg)
+}
+
+func (t *ProcessTool) signal(p *backgroundProcess, args map[string]interface{}) *ToolResult {
+	name, _ := args["signal"].(string)
+	name = strings.TrimPrefix(strings.ToUpper(name), "SIG")
+	sig, ok := processSignals[name]
+	if !ok {
+		return ErrorResult(fmt.Sprintf("unsupported signal %q", name))
+	}
+	if !p.running() {
+		return ErrorResult(fmt.Sprintf("process %s is not running", p.id))
+	}
+	if err := signalProcess(p.cmd, sig); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to signal process %s: %v", p.id, err))
+	}
+	return SilentResult(fmt.Sprintf("Sent SIG%s to process %s.", name, p.id))
+}
+
+// outputBlock formats a chunk of process output with its offsets.
+func outputBlock(output string, from, next, total int64) string {
+	if output == "" {
+		return fmt.Sprintf("No new output (next offset %d).", next)
+	}
+	block := fmt.Sprintf("Output %d-%d of %d bytes (next offset %d):\n%s", from, next, total, next, output)
+	if next < total {
+		block += "\n(more output available)"
+	}
+	return block
+}
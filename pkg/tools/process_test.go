@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/bus"
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+func newTestProcessTool(t *testing.T) (*ProcessTool, *ProcessManager, *bus.MessageBus) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("process tests use a POSIX shell")
+	}
+	msgBus := bus.NewMessageBus()
+	manager := NewProcessManager(msgBus)
+	t.Cleanup(manager.StopAll)
+	tool := NewProcessTool(manager, NewExecTool(t.TempDir(), false))
+	tool.SetContext("telegram", "chat1")
+	return tool, manager, msgBus
+}
+
+// pollUntil polls the process until its output contains want.
+func pollUntil(t *testing.T, ctx context.Context, tool *ProcessTool, id, want string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		result := tool.Execute(ctx, map[string]interface{}{"action": "poll", "id": id})
+		if strings.Contains(result.ForLLM, want) {
+			return result.ForLLM
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("output never contained %q: %s", want, result.ForLLM)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestProcessTool_StartPollWriteKill(t *testing.T) {
+	tool, _, msgBus := newTestProcessTool(t)
+	ctx := WithSession(context.Background(), "telegram:chat1")
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "echo ready; cat"})
+	if result.IsError || !strings.Contains(result.ForLLM, "Started process proc-1") {
+		t.Fatalf("start = %s", result.ForLLM)
+	}
+	pollUntil(t, ctx, tool, "proc-1", "ready")
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "write", "id": "proc-1", "input": "hello\n"})
+	if result.IsError {
+		t.Fatalf("write = %s", result.ForLLM)
+	}
+	output := pollUntil(t, ctx, tool, "proc-1", "hello")
+	if !strings.Contains(output, "next offset 12") {
+		t.Errorf("poll = %s", output)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "poll", "id": "proc-1", "offset": float64(12)})
+	if !strings.Contains(result.ForLLM, "No new output (next offset 12)") {
+		t.Errorf("poll from offset = %s", result.ForLLM)
+	}
+
+	// Other sessions cannot see the process
+	other := WithSession(context.Background(), "telegram:chat2")
+	if result := tool.Execute(other, map[string]interface{}{"action": "poll", "id": "proc-1"}); !result.IsError {
+		t.Errorf("other session polled the process: %s", result.ForLLM)
+	}
+	if result := tool.Execute(other, map[string]interface{}{"action": "list"}); !strings.Contains(result.ForLLM, "No background processes") {
+		t.Errorf("other session list = %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "kill", "id": "proc-1"})
+	if !strings.Contains(result.ForLLM, "killed") {
+		t.Errorf("kill = %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "list"}); !strings.Contains(result.ForLLM, "proc-1: killed") {
+		t.Errorf("list = %s", result.ForLLM)
+	}
+
+	// Killed processes are not announced
+	waitCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if msg, ok := msgBus.ConsumeInbound(waitCtx); ok {
+		t.Errorf("unexpected notification: %+v", msg)
+	}
+}
+
+func TestProcessTool_NotifiesOnExit(t *testing.T) {
+	tool, _, msgBus := newTestProcessTool(t)
+	ctx := WithSession(context.Background(), "telegram:chat1")
+	ctx = WithRequester(ctx, Requester{IDs: []string{"123", "u-1"}})
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "sleep 0.5; echo built; exit 3"})
+	if result.IsError {
+		t.Fatalf("start = %s", result.ForLLM)
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(waitCtx)
+	if !ok {
+		t.Fatal("no exit notification")
+	}
+	if msg.Channel != "system" || msg.SenderID != "process:proc-1" || msg.ChatID != "telegram:chat1" || msg.SessionKey != "telegram:chat1" || msg.Metadata["origin_sender"] != "123" {
+		t.Errorf("notification = %+v", msg)
+	}
+	if !strings.Contains(msg.Content, "exit status 3") || !strings.Contains(msg.Content, "built") {
+		t.Errorf("notification content = %q", msg.Content)
+	}
+}
+
+func TestProcessTool_KillsAtTimeLimit(t *testing.T) {
+	tool, manager, msgBus := newTestProcessTool(t)
+	manager.SetMaxRuntime(time.Second)
+	ctx := WithSession(context.Background(), "telegram:chat1")
+
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "sleep 30"}); result.IsError {
+		t.Fatalf("start = %s", result.ForLLM)
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(waitCtx)
+	if !ok {
+		t.Fatal("process was not stopped at the time limit")
+	}
+	if !strings.Contains(msg.Content, "killed at the time limit") {
+		t.Errorf("notification content = %q", msg.Content)
+	}
+}
+
+func TestProcessTool_ReportsEarlyExit(t *testing.T) {
+	tool, _, msgBus := newTestProcessTool(t)
+	ctx := WithSession(context.Background(), "telegram:chat1")
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "echo oops >&2; exit 1"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "oops") || !strings.Contains(result.ForLLM, "exit status 1") {
+		t.Errorf("start = %s", result.ForLLM)
+	}
+
+	// start reported the exit itself
+	waitCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if msg, ok := msgBus.ConsumeInbound(waitCtx); ok {
+		t.Errorf("unexpected notification: %+v", msg)
+	}
+
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "rm -rf /"}); !result.IsError {
+		t.Errorf("guarded command started: %s", result.ForLLM)
+	}
+}
+
+func TestProcessOutput_DropsOldOutput(t *testing.T) {
+	var out processOutput
+	chunk := strings.Repeat("x", maxProcessOutput/2)
+	for i := 0; i < 3; i++ {
+		_, _ = out.Write([]byte(chunk))
+	}
+	data, from, next := out.read(0, 10)
+	if from != int64(len(chunk)) || next != from+10 || data != "xxxxxxxxxx" {
+		t.Errorf("read(0) = %q from %d next %d", data, from, next)
+	}
+	if total := out.size(); total != int64(3*len(chunk)) {
+		t.Errorf("size = %d", total)
+	}
+}
+
+func TestProcessTool_StartHeldForExecApproval(t *testing.T) {
+	tool, _, _ := newTestProcessTool(t)
+	r := NewToolRegistry()
+	r.Register(tool)
+	var prompted []ApprovalRequest
+	r.SetApprovalPolicy(NewApprovalPolicy(config.ApprovalConfig{Require: config.FlexibleStringSlice{"exec"}}, func(ctx context.Context, req ApprovalRequest) error {
+		prompted = append(prompted, req)
+		go DeliverApproval(req.ID, false, "telegram", "chat1", "42")
+		return nil
+	}, ""))
+	ctx := WithSession(context.Background(), "telegram:chat1")
+
+	result := r.ExecuteWithContext(ctx, "process", map[string]interface{}{"action": "start", "command": "echo ran"}, "telegram", "chat1", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "denied") {
+		t.Fatalf("start = %s, want a denial", result.ForLLM)
+	}
+	if len(prompted) != 1 || prompted[0].Action != "start" {
+		t.Errorf("prompted = %+v", prompted)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "list"}); !strings.Contains(result.ForLLM, "No background processes") {
+		t.Errorf("denied process started: %s", result.ForLLM)
+	}
+
+	// Other actions run without asking
+	r.ExecuteWithContext(ctx, "process", map[string]interface{}{"action": "list"}, "telegram", "chat1", nil)
+	if len(prompted) != 1 {
+		t.Errorf("list was held for approval")
+	}
+}
@@ -0,0 +1,19 @@
+package tools
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// processSignals are the signals the process tool can send. Windows can
+// only kill processes.
+var processSignals = map[string]syscall.Signal{
+	"KILL": syscall.SIGKILL,
+	"TERM": syscall.SIGTERM,
+}
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcess(cmd *exec.Cmd, sig syscall.Signal) error {
+	return cmd.Process.Kill()
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,8 +12,11 @@ import (
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools    map[string]Tool
+	approval *ApprovalPolicy
+	hooks    *Hooks
+	outputs  *OutputStore
+	mu       sync.RWMutex
 }
 
 func NewToolRegistry() *ToolRegistry {
@@ -27,6 +31,48 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// SetApprovalPolicy makes calls matching the policy wait for user approval.
+func (r *ToolRegistry) SetApprovalPolicy(p *ApprovalPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approval = p
+}
+
+// SetHooks sets the hooks run before and after each tool call.
+func (r *ToolRegistry) SetHooks(h *Hooks) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = h
+}
+
+// SetOutputStore stores results over MaxResultChars, giving the LLM a
+// preview and an ID to read the rest with read_output.
+func (r *ToolRegistry) SetOutputStore(s *OutputStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.outputs = s
+}
+
+// Subset returns a registry holding only the named tools that are registered
+// here. Tool instances, the approval policy, hooks and output store are shared.
+func (r *ToolRegistry) Subset(names []string) *ToolRegistry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub := &ToolRegistry{
+		tools:    make(map[string]Tool, len(names)),
+		approval: r.approval,
+		hooks:    r.hooks,
+		outputs:  r.outputs,
+	}
+	for _, name := range names {
+		if tool, ok := r.tools[name]; ok {
+			sub.tools[name] = tool
+		}
+	}
+	return sub
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -34,6 +80,17 @@ func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	return tool, ok
 }
 
+// IsConcurrencySafe reports whether the named tool may run concurrently with
+// other tool calls. Unknown tools are not.
+func (r *ToolRegistry) IsConcurrencySafe(name string) bool {
+	tool, ok := r.Get(name)
+	if !ok {
+		return false
+	}
+	ct, ok := tool.(ConcurrentTool)
+	return ok && ct.ConcurrencySafe()
+}
+
 func (r *ToolRegistry) Execute(ctx context.Context, name string, args map[string]interface{}) *ToolResult {
 	return r.ExecuteWithContext(ctx, name, args, "", "", nil)
 }
@@ -57,6 +114,26 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	r.mu.RLock()
+	approval, hooks, outputs := r.approval, r.hooks, r.outputs
+	r.mu.RUnlock()
+
+	// Hooks may veto the call or rewrite its arguments before approval, so
+	// the user approves what actually runs
+	call := HookCall{Tool: name, Args: args, Channel: channel, ChatID: chatID, Requester: RequesterFrom(ctx).IDs}
+	args, blocked := hooks.RunPre(ctx, call)
+	if blocked != nil {
+		return blocked
+	}
+	call.Args = args
+
+	// Pause for the user's decision when the approval policy covers this call
+	if approval != nil {
+		if denied := approval.Check(ctx, name, args, channel, chatID); denied != nil {
+			return denied
+		}
+	}
+
 	// If tool implements ContextualTool, set context
 	if contextualTool, ok := tool.(ContextualTool); ok && channel != "" && chatID != "" {
 		contextualTool.SetContext(channel, chatID)
@@ -74,6 +151,10 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
+	hooks.RunPost(ctx, call, result)
+	if outputs != nil {
+		result.ForLLM = outputs.Preview(ctx, result.ForLLM, MaxResultChars)
+	}
 
 	// Log based on result type
 	if result.IsError {
@@ -170,7 +251,8 @@ func (r *ToolRegistry) Count() int {
 }
 
 // GetSummaries returns human-readable summaries of all registered tools.
-// Returns a slice of "name - description" strings.
+// Returns a slice of "name - description" strings, sorted by name so the
+// system prompt is stable between turns.
 func (r *ToolRegistry) GetSummaries() []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -182,5 +264,6 @@ func (r *ToolRegistry) GetSummaries() []string {
 		}
 		summaries = append(summaries, fmt.Sprintf("- `%s` - %s", tool.Name(), tool.Description()))
 	}
+	sort.Strings(summaries)
 	return summaries
 }
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox backends.
+const (
+	SandboxAuto       = "auto"       // bwrap when installed, otherwise namespaces
+	SandboxNamespaces = "namespaces" // Linux user/mount/pid/network namespaces
+	SandboxBwrap      = "bwrap"      // bubblewrap
+)
+
+// sandboxInitArg is the argument the binary is re-executed with to set up
+// a sandbox before running the command; see SandboxMain.
+const sandboxInitArg = "__clawdroid_sandbox_init"
+
+// Sandbox runs shell commands isolated from the host.
+type Sandbox interface {
+	// Name returns the backend name.
+	Name() string
+	// Command returns a command that runs argv in dir inside the sandbox,
+	// looking argv[0] up in the sandbox's PATH. The command is killed when
+	// ctx is done.
+	Command(ctx context.Context, argv []string, dir string) (*exec.Cmd, error)
+}
+
+// SandboxOptions configures what a sandboxed command can see and use.
+type SandboxOptions struct {
+	Workspace     string   // Mounted read-write; commands start here
+	WritablePaths []string // Extra read-write paths
+	ReadOnlyPaths []string // Extra read-only paths
+	Network       bool     // Keep network access (off: loopback only)
+	CPUSeconds    int      // CPU time limit (0 = unlimited)
+	MemoryMB      int      // Address space limit (0 = unlimited)
+	PassEnv       []string // Host variables passed in (names, "*" globs)
+	EnvScrub      []string // Variables kept out even if PassEnv matches them
+}
+
+// systemReadOnlyPaths are what shell commands need from the host. Missing
+// paths are skipped; everything not listed (home directories, /etc/passwd,
+// the data directory, ...) is hidden.
+var systemReadOnlyPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32",
+	"/etc/alternatives", "/etc/ld.so.cache", "/etc/ld.so.conf", "/etc/ld.so.conf.d",
+	"/etc/ssl", "/etc/ca-certificates", "/etc/pki",
+	"/etc/resolv.conf", "/etc/hosts", "/etc/nsswitch.conf", "/etc/localtime",
+	"/system", "/apex", "/vendor", // Android
+}
+
+// sandboxSpec is what the init process needs to set up the sandbox.
+type sandboxSpec struct {
+	Args       []string `json:"args"`
+	Dir        string   `json:"dir"`
+	Env        []string `json:"env"`
+	ReadOnly   []string `json:"read_only,omitempty"`
+	Writable   []string `json:"writable,omitempty"`
+	CPUSeconds int      `json:"cpu_seconds,omitempty"`
+	MemoryMB   int      `json:"memory_mb,omitempty"`
+	Exec       []string `json:"exec,omitempty"` // Apply the limits and run this instead (bwrap backend)
+}
+
+// NewSandbox creates a sandbox backend and checks that it works on this
+// system.
+func NewSandbox(backend string, opts SandboxOptions) (Sandbox, error) {
+	if opts.Workspace == "" {
+		return nil, fmt.Errorf("sandbox needs a workspace")
+	}
+	ws, err := filepath.Abs(opts.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	opts.Workspace = ws
+	return newPlatformSandbox(backend, opts)
+}
+
+// UnavailableSandbox returns a Sandbox whose commands fail with err, so exec
+// refuses to run rather than run unsandboxed when the sandbox is broken.
+func UnavailableSandbox(err error) Sandbox {
+	return unavailableSandbox{err: err}
+}
+
+type unavailableSandbox struct {
+	err error
+}
+
+func (s unavailableSandbox) Name() string {
+	return "unavailable"
+}
+
+func (s unavailableSandbox) Command(ctx context.Context, argv []string, dir string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("sandbox unavailable: %w", s.err)
+}
+
+// readOnlyPaths returns the existing paths mounted read-only.
+func (o SandboxOptions) readOnlyPaths() []string {
+	paths := append([]string(nil), systemReadOnlyPaths...)
+	if prefix := os.Getenv("PREFIX"); prefix != "" {
+		paths = append(paths, prefix) // Termux
+	}
+	paths = append(paths, o.ReadOnlyPaths...)
+	return existingPaths(paths)
+}
+
+// writablePaths returns the existing paths mounted read-write.
+func (o SandboxOptions) writablePaths() []string {
+	return existingPaths(append([]string{o.Workspace}, o.WritablePaths...))
+}
+
+// env returns the environment of sandboxed commands. Only what shells need
+// and PassEnv is passed through, minus EnvScrub as without the sandbox, so
+// credentials in the agent's environment stay out.
+func (o SandboxOptions) env() []string {
+	env := []string{
+		"HOME=" + o.Workspace,
+		"TMPDIR=/tmp",
+		"PATH=" + sandboxPath(),
+	}
+	pass := append([]string{"LANG", "LC_ALL", "TERM", "TZ"}, o.PassEnv...)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if key != "HOME" && key != "TMPDIR" && key != "PATH" && envNameMatches(key, pass) && !envNameMatches(key, o.EnvScrub) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+func sandboxPath() string {
+	if p := os.Getenv("PATH"); p != "" {
+		return p
+	}
+	return "/usr/local/bin:/usr/bin:/bin"
+}
+
+func existingPaths(paths []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if p == "" || !filepath.IsAbs(p) {
+			continue
+		}
+		p = filepath.Clean(p)
+		if seen[p] {
+			continue
+		}
+		if _, err := os.Lstat(p); err == nil {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,419 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// prSetNoNewPrivs is PR_SET_NO_NEW_PRIVS, missing from package syscall.
+const prSetNoNewPrivs = 38
+
+// sandboxDevices are the device nodes available inside the namespaces
+// sandbox.
+var sandboxDevices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+func newPlatformSandbox(backend string, opts SandboxOptions) (Sandbox, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating executable: %w", err)
+	}
+
+	var sb Sandbox
+	switch backend {
+	case "", SandboxAuto:
+		if path, err := exec.LookPath("bwrap"); err == nil {
+			sb = &bwrapSandbox{opts: opts, self: self, bwrap: path}
+		} else {
+			sb = &namespaceSandbox{opts: opts, self: self}
+		}
+	case SandboxNamespaces:
+		sb = &namespaceSandbox{opts: opts, self: self}
+	case SandboxBwrap:
+		path, err := exec.LookPath("bwrap")
+		if err != nil {
+			return nil, fmt.Errorf("bwrap sandbox unavailable: %w", err)
+		}
+		sb = &bwrapSandbox{opts: opts, self: self, bwrap: path}
+	default:
+		return nil, fmt.Errorf("unknown sandbox backend %q (use %q, %q or %q)", backend, SandboxAuto, SandboxNamespaces, SandboxBwrap)
+	}
+
+	if err := probeSandbox(sb, opts.Workspace); err != nil {
+		return nil, fmt.Errorf("%s sandbox unavailable: %w", sb.Name(), err)
+	}
+	return sb, nil
+}
+
+// probeSandbox runs a no-op command to check the backend works here
+// (user namespaces can be disabled by the kernel or a container runtime).
+func probeSandbox(sb Sandbox, dir string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cmd, err := sb.Command(ctx, []string{"true"}, dir)
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// initCommand returns the command re-running this binary as the sandbox
+// init process.
+func initCommand(ctx context.Context, self string, spec sandboxSpec) (*exec.Cmd, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, self, sandboxInitArg, string(data))
+	cmd.Env = spec.Env
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+	return cmd, nil
+}
+
+// namespaceSandbox runs commands in new user, mount, PID, IPC, UTS and
+// (unless network is allowed) network namespaces, with a root filesystem
+// built from read-only and read-write bind mounts.
+type namespaceSandbox struct {
+	opts SandboxOptions
+	self string
+}
+
+func (s *namespaceSandbox) Name() string {
+	return SandboxNamespaces
+}
+
+func (s *namespaceSandbox) Command(ctx context.Context, argv []string, dir string) (*exec.Cmd, error) {
+	cmd, err := initCommand(ctx, s.self, sandboxSpec{
+		Args:       argv,
+		Dir:        dir,
+		Env:        s.opts.env(),
+		ReadOnly:   s.opts.readOnlyPaths(),
+		Writable:   s.opts.writablePaths(),
+		CPUSeconds: s.opts.CPUSeconds,
+		MemoryMB:   s.opts.MemoryMB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	flags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !s.opts.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+	cmd.SysProcAttr.Cloneflags = uintptr(flags)
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	cmd.SysProcAttr.GidMappingsEnableSetgroups = false
+	return cmd, nil
+}
+
+// bwrapSandbox runs commands under bubblewrap. The init process only applies
+// the resource limits before starting bwrap.
+type bwrapSandbox struct {
+	opts  SandboxOptions
+	self  string
+	bwrap string
+}
+
+func (s *bwrapSandbox) Name() string {
+	return SandboxBwrap
+}
+
+func (s *bwrapSandbox) Command(ctx context.Context, argv []string, dir string) (*exec.Cmd, error) {
+	args := []string{s.bwrap, "--die-with-parent", "--new-session", "--unshare-all", "--hostname", "sandbox"}
+	if s.opts.Network {
+		args = append(args, "--share-net")
+	}
+	// /tmp first so binds below it are not hidden
+	args = append(args, "--tmpfs", "/tmp", "--proc", "/proc", "--dev", "/dev")
+	for _, p := range s.opts.readOnlyPaths() {
+		if target, err := os.Readlink(p); err == nil {
+			args = append(args, "--symlink", target, p)
+		} else {
+			args = append(args, "--ro-bind", p, p)
+		}
+	}
+	for _, p := range s.opts.writablePaths() {
+		args = append(args, "--bind", p, p)
+	}
+	args = append(args, "--chdir", dir)
+	args = append(args, argv...)
+
+	return initCommand(ctx, s.self, sandboxSpec{
+		Env:        s.opts.env(),
+		CPUSeconds: s.opts.CPUSeconds,
+		MemoryMB:   s.opts.MemoryMB,
+		Exec:       args,
+	})
+}
+
+// SandboxMain runs the sandbox init process when this binary was re-executed
+// by a sandbox, and returns otherwise. Call it first thing in main (and in
+// TestMain of packages that run sandboxed commands).
+func SandboxMain() {
+	if len(os.Args) < 3 || os.Args[1] != sandboxInitArg {
+		return
+	}
+	var spec sandboxSpec
+	err := json.Unmarshal([]byte(os.Args[2]), &spec)
+	if err == nil {
+		err = runSandboxInit(spec)
+	}
+	// Only reached when the command could not be started
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// runSandboxInit sets up the sandbox and replaces this process with the
+// command. It only returns on failure.
+func runSandboxInit(spec sandboxSpec) error {
+	runtime.LockOSThread()
+
+	if len(spec.Exec) > 0 {
+		if err := setSandboxLimits(spec); err != nil {
+			return err
+		}
+		return syscall.Exec(spec.Exec[0], spec.Exec, spec.Env)
+	}
+
+	if err := setupSandboxRoot(spec); err != nil {
+		return err
+	}
+	_ = syscall.Sethostname([]byte("sandbox"))
+	if err := syscall.Chdir(spec.Dir); err != nil {
+		return fmt.Errorf("working directory %s is not available in the sandbox: %w", spec.Dir, err)
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("prctl(NO_NEW_PRIVS): %w", errno)
+	}
+
+	if len(spec.Args) == 0 {
+		return fmt.Errorf("no command")
+	}
+	// PATH is the sandbox's: this process runs with spec.Env
+	path, err := exec.LookPath(spec.Args[0])
+	if err != nil {
+		return err
+	}
+	// Limits last: a low address space limit would starve this Go process
+	if err := setSandboxLimits(spec); err != nil {
+		return err
+	}
+	return syscall.Exec(path, spec.Args, spec.Env)
+}
+
+func setSandboxLimits(spec sandboxSpec) error {
+	limits := map[int]uint64{syscall.RLIMIT_CORE: 0}
+	if spec.CPUSeconds > 0 {
+		limits[syscall.RLIMIT_CPU] = uint64(spec.CPUSeconds)
+	}
+	if spec.MemoryMB > 0 {
+		limits[syscall.RLIMIT_AS] = uint64(spec.MemoryMB) << 20
+	}
+	for resource, value := range limits {
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("setrlimit(%d): %w", resource, err)
+		}
+	}
+	return nil
+}
+
+// setupSandboxRoot builds the sandbox's root filesystem and makes it the
+// process root. Like bubblewrap, it first pivots into a tmpfs so the host
+// tree stays reachable at /oldroot while the new root is assembled, then
+// pivots into the new root and detaches the host tree.
+func setupSandboxRoot(spec sandboxSpec) error {
+	base := os.TempDir()
+	steps := []struct {
+		what string
+		fn   func() error
+	}{
+		{"make mounts private", func() error { return syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, "") }},
+		{"mount base tmpfs", func() error {
+			return syscall.Mount("tmpfs", base, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755")
+		}},
+		{"create root directories", func() error {
+			for _, d := range []string{"newroot", "oldroot"} {
+				if err := os.Mkdir(filepath.Join(base, d), 0755); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+		{"pivot into base", func() error {
+			if err := syscall.PivotRoot(base, filepath.Join(base, "oldroot")); err != nil {
+				return err
+			}
+			return syscall.Chdir("/")
+		}},
+		{"bind new root", func() error { return syscall.Mount("/newroot", "/newroot", "", syscall.MS_BIND, "") }},
+		{"mount /tmp", func() error {
+			return mountAt("tmpfs", "/newroot/tmp", "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777")
+		}},
+		{"bind host paths", func() error { return bindHostPaths(spec) }},
+		{"mount /proc", func() error {
+			return mountAt("proc", "/newroot/proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+		}},
+		{"populate /dev", populateDev},
+		{"seal new root", func() error {
+			return syscall.Mount("", "/newroot", "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY, "")
+		}},
+		{"pivot into new root", func() error {
+			if err := syscall.Chdir("/newroot"); err != nil {
+				return err
+			}
+			if err := syscall.PivotRoot(".", "."); err != nil {
+				return err
+			}
+			if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+				return err
+			}
+			return syscall.Chdir("/")
+		}},
+	}
+	for _, step := range steps {
+		if err := step.fn(); err != nil {
+			return fmt.Errorf("%s: %w", step.what, err)
+		}
+	}
+	return nil
+}
+
+// bindHostPaths bind-mounts the host paths into the new root, parents
+// first, then makes the read-only ones read-only.
+func bindHostPaths(spec sandboxSpec) error {
+	writable := make(map[string]bool, len(spec.Writable))
+	paths := append([]string(nil), spec.ReadOnly...)
+	for _, p := range spec.Writable {
+		writable[p] = true
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var readOnly []string
+	for _, p := range paths {
+		bound, err := bindHostPath(p)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		if bound && !writable[p] {
+			readOnly = append(readOnly, p)
+		}
+	}
+	for _, p := range readOnly {
+		// Remounting must keep the flags the host mount is locked with
+		var st syscall.Statfs_t
+		if err := syscall.Statfs("/oldroot"+p, &st); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		flags := uintptr(syscall.MS_REMOUNT | syscall.MS_BIND | syscall.MS_RDONLY)
+		for _, f := range []uintptr{syscall.MS_NOSUID, syscall.MS_NODEV, syscall.MS_NOEXEC, syscall.MS_NOATIME, syscall.MS_NODIRATIME} {
+			if uintptr(st.Flags)&f != 0 {
+				flags |= f
+			}
+		}
+		if uintptr(st.Flags)&4096 != 0 { // ST_RELATIME
+			flags |= syscall.MS_RELATIME
+		}
+		if err := syscall.Mount("", "/newroot"+p, "", flags, ""); err != nil {
+			return fmt.Errorf("%s read-only: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// bindHostPath mirrors a host path in the new root: symlinks are copied,
+// files and directories bind-mounted. It reports whether it mounted.
+func bindHostPath(p string) (bool, error) {
+	src, dst := "/oldroot"+p, "/newroot"+p
+	info, err := os.Lstat(src)
+	if err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Lstat(dst); err == nil {
+			return false, nil // Already provided by a parent mount
+		}
+		return false, os.Symlink(target, dst)
+	}
+	if err := makeMountPoint(dst, info.IsDir()); err != nil {
+		return false, err
+	}
+	return true, syscall.Mount(src, dst, "", syscall.MS_BIND|syscall.MS_REC, "")
+}
+
+// populateDev mounts a tmpfs on /dev with the basic device nodes.
+func populateDev() error {
+	if err := mountAt("tmpfs", "/newroot/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return err
+	}
+	for _, name := range sandboxDevices {
+		src, dst := "/oldroot/dev/"+name, "/newroot/dev/"+name
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := makeMountPoint(dst, false); err != nil {
+			return err
+		}
+		if err := syscall.Mount(src, dst, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	links := map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, "/newroot/dev/"+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountAt creates the directory target and mounts on it.
+func mountAt(source, target, fstype string, flags uintptr, data string) error {
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	return syscall.Mount(source, target, fstype, flags, data)
+}
+
+// makeMountPoint creates an empty directory or file to mount on.
+func makeMountPoint(path string, dir bool) error {
+	if dir {
+		return os.MkdirAll(path, 0755)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain lets the test binary act as the sandbox init process.
+func TestMain(m *testing.M) {
+	SandboxMain()
+	os.Exit(m.Run())
+}
+
+// sandboxedExec returns an exec tool running in a namespaces sandbox, or
+// skips the test where user namespaces are unavailable.
+func sandboxedExec(t *testing.T, opts SandboxOptions) *ExecTool {
+	t.Helper()
+	sb, err := NewSandbox(SandboxNamespaces, opts)
+	if err != nil {
+		t.Skipf("namespaces sandbox unavailable: %v", err)
+	}
+	tool := NewExecTool(opts.Workspace, true)
+	tool.SetSandbox(sb)
+	return tool
+}
+
+func TestSandbox_IsolatesFilesystem(t *testing.T) {
+	workspace := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("hunter2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := sandboxedExec(t, SandboxOptions{Workspace: workspace})
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"command": "echo hello > out.txt && pwd"})
+	if result.IsError || !strings.Contains(result.ForLLM, workspace) {
+		t.Fatalf("workspace write failed: %s", result.ForLLM)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspace, "out.txt")); err != nil || string(data) != "hello\n" {
+		t.Errorf("out.txt = %q, %v", data, err)
+	}
+
+	for _, command := range []string{
+		"cat " + filepath.Join(outside, "secret.txt"),
+		"cat /etc/passwd",
+		"touch /usr/sandbox-test",
+	} {
+		result := tool.Execute(ctx, map[string]interface{}{"command": command})
+		if !result.IsError {
+			t.Errorf("%q succeeded in the sandbox: %s", command, result.ForLLM)
+		}
+	}
+	if _, err := os.Stat("/usr/sandbox-test"); err == nil {
+		_ = os.Remove("/usr/sandbox-test")
+		t.Error("sandboxed command wrote to /usr")
+	}
+}
+
+func TestSandbox_NetworkAndEnvironment(t *testing.T) {
+	t.Setenv("CLAWDROID_SANDBOX_TEST_TOKEN", "hunter2")
+	tool := sandboxedExec(t, SandboxOptions{Workspace: t.TempDir()})
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"command": "env; tail -n +3 /proc/net/dev",
+	})
+	if result.IsError {
+		t.Fatalf("command failed: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "hunter2") {
+		t.Error("agent environment leaked into the sandbox")
+	}
+	for _, line := range strings.Split(result.ForLLM, "\n") {
+		if iface, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && strings.HasPrefix(line, " ") && iface != "lo" {
+			t.Errorf("network interface %q visible with network disabled", iface)
+		}
+	}
+}
+
+func TestSandboxOptions_EnvScrubOverridesPassthrough(t *testing.T) {
+	t.Setenv("CLAWDROID_SANDBOX_TEST_API_KEY", "hunter2")
+	t.Setenv("CLAWDROID_SANDBOX_TEST_MODE", "debug")
+	opts := SandboxOptions{
+		Workspace: t.TempDir(),
+		PassEnv:   []string{"CLAWDROID_SANDBOX_TEST_*"},
+		EnvScrub:  []string{"*_API_KEY"},
+	}
+
+	env := strings.Join(opts.env(), "\n")
+	if strings.Contains(env, "hunter2") {
+		t.Error("scrubbed variable passed into the sandbox")
+	}
+	if !strings.Contains(env, "CLAWDROID_SANDBOX_TEST_MODE=debug") {
+		t.Errorf("passthrough variable missing:\n%s", env)
+	}
+}
+
+func TestSandbox_UnavailableRefusesCommands(t *testing.T) {
+	tool := NewExecTool(t.TempDir(), false)
+	tool.SetSandbox(UnavailableSandbox(os.ErrPermission))
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "echo ran"})
+	if !result.IsError || strings.Contains(result.ForLLM, "ran") {
+		t.Errorf("command ran without its sandbox: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package tools
+
+import "fmt"
+
+func newPlatformSandbox(backend string, opts SandboxOptions) (Sandbox, error) {
+	return nil, fmt.Errorf("sandbox is only supported on Linux")
+}
+
+// SandboxMain is a no-op outside Linux.
+func SandboxMain() {}
@@ -0,0 +1,643 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	grepDefaultResults = 100
+	grepMaxResults     = 500
+	grepMaxContext     = 10
+	grepMaxFileSize    = 5 << 20
+	grepMaxLineLength  = 500
+
+	findDefaultResults = 200
+	findMaxResults     = 1000
+
+	// binarySniffLen is how much of a file is inspected for NUL bytes.
+	binarySniffLen = 8000
+)
+
+// errSearchDone stops a walk once the result cap is reached.
+var errSearchDone = errors.New("search done")
+
+// skippedSearchDirs are never descended into unless they are the search root.
+var skippedSearchDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+}
+
+// isBinary reports whether data looks like binary content.
+func isBinary(data []byte) bool {
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// searchWalker walks a directory tree inside the workspace rules shared by
+// the file tools.
+type searchWalker struct {
+	workspace string
+	restrict  bool
+	root      string
+}
+
+func newSearchWalker(path, workspace string, restrict bool) (*searchWalker, error) {
+	if path == "" {
+		path = "."
+	}
+	root, err := validatePath(path, workspace, restrict)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to access path: %w", err)
+	}
+	return &searchWalker{workspace: workspace, restrict: restrict, root: root}, nil
+}
+
+// walk calls fn for every file and directory below the root, resolving
+// symlinked files and skipping any that escape the workspace. Symlinked
+// directories are not followed.
+func (w *searchWalker) walk(ctx context.Context, fn func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(w.root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path == w.root {
+				return err
+			}
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() && path != w.root && skippedSearchDirs[d.Name()] {
+			return fs.SkipDir
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if _, err := validatePath(path, w.workspace, w.restrict); err != nil {
+				return nil
+			}
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			return fn(path, info)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		return fn(path, info)
+	})
+	if errors.Is(err, errSearchDone) {
+		return nil
+	}
+	return err
+}
+
+// display returns path relative to the workspace when possible.
+func (w *searchWalker) display(path string) string {
+	base := w.workspace
+	if base == "" {
+		base = w.root
+	}
+	if absBase, err := filepath.Abs(base); err == nil && isWithinWorkspace(path, absBase) {
+		if rel, err := filepath.Rel(absBase, path); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// rel returns path relative to the search root in slash form.
+func (w *searchWalker) rel(path string) string {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// searchGlob matches paths against a glob. "*" and "?" stay within one path
+// segment, "**" spans directories and "{a,b}" lists alternatives. Patterns
+// without a slash match the base name; others match the path relative to
+// the search root.
+type searchGlob struct {
+	re       *regexp.Regexp
+	fullPath bool
+}
+
+func compileGlob(pattern string) (*searchGlob, error) {
+	pattern = filepath.ToSlash(strings.TrimPrefix(pattern, "./"))
+	var sb strings.Builder
+	sb.WriteString("^")
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '{':
+			depth++
+			sb.WriteString("(?:")
+		case '}':
+			if depth == 0 {
+				sb.WriteString(`\}`)
+				continue
+			}
+			depth--
+			sb.WriteString(")")
+		case ',':
+			if depth > 0 {
+				sb.WriteString("|")
+			} else {
+				sb.WriteString(",")
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("invalid glob %q: unclosed '{'", pattern)
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+	}
+	return &searchGlob{re: re, fullPath: strings.Contains(pattern, "/")}, nil
+}
+
+// match reports whether the glob matches rel, a slash-separated path
+// relative to the search root.
+func (g *searchGlob) match(rel string) bool {
+	if g.fullPath {
+		return g.re.MatchString(rel)
+	}
+	return g.re.MatchString(rel[strings.LastIndexByte(rel, '/')+1:])
+}
+
+func compileGlobs(args map[string]interface{}, name string) ([]*searchGlob, error) {
+	var patterns []string
+	switch v := args[name].(type) {
+	case string:
+		if v != "" {
+			patterns = []string{v}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				patterns = append(patterns, s)
+			}
+		}
+	case []string:
+		patterns = v
+	}
+	globs := make([]*searchGlob, 0, len(patterns))
+	for _, p := range patterns {
+		g, err := compileGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+func matchAny(globs []*searchGlob, rel string) bool {
+	for _, g := range globs {
+		if g.match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func clampResults(args map[string]interface{}, def, max int) int {
+	n := intArg(args, "max_results", def)
+	if n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+type GrepTool struct {
+	workspace string
+	restrict  bool
+}
+
+func NewGrepTool(workspace string, restrict bool) *GrepTool {
+	return &GrepTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *GrepTool) Name() string {
+	return "grep"
+}
+
+func (t *GrepTool) Description() string {
+	return "Search file contents with a regular expression (RE2 syntax). Returns matching lines as path:line: text. Binary files and .git/node_modules are skipped."
+}
+
+func (t *GrepTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression to search for",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File or directory to search (default: workspace root)",
+			},
+			"include": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only search files matching these globs (e.g. \"*.go\", \"src/**/*.{ts,tsx}\")",
+			},
+			"exclude": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Skip files and directories matching these globs",
+			},
+			"context": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of context to show around each match (max %d)", grepMaxContext),
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match case-insensitively",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matching lines (default %d, max %d)", grepDefaultResults, grepMaxResults),
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *GrepTool) ConcurrencySafe() bool {
+	return true
+}
+
+func (t *GrepTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return ErrorResult("pattern is required")
+	}
+	if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+	}
+	include, err := compileGlobs(args, "include")
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	exclude, err := compileGlobs(args, "exclude")
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	contextLines := intArg(args, "context", 0)
+	if contextLines < 0 {
+		contextLines = 0
+	} else if contextLines > grepMaxContext {
+		contextLines = grepMaxContext
+	}
+	maxResults := clampResults(args, grepDefaultResults, grepMaxResults)
+
+	path, _ := args["path"].(string)
+	walker, err := newSearchWalker(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var sb strings.Builder
+	matches, files, skipped := 0, 0, 0
+	capped := false
+	err = walker.walk(ctx, func(path string, info fs.FileInfo) error {
+		rel := walker.rel(path)
+		if info.IsDir() {
+			if path != walker.root && matchAny(exclude, rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || matchAny(exclude, rel) {
+			return nil
+		}
+		if len(include) > 0 && path != walker.root && !matchAny(include, rel) {
+			return nil
+		}
+		if info.Size() > grepMaxFileSize {
+			skipped++
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || isBinary(data) {
+			return nil
+		}
+
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		name := walker.display(path)
+		last := -1 // last line index written for this file
+		found := false
+		for i, line := range lines {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches >= maxResults {
+				capped = true
+				return errSearchDone
+			}
+			matches++
+			if !found {
+				found = true
+				files++
+			}
+			start := max(i-contextLines, last+1)
+			if contextLines > 0 && last >= 0 && start > last+1 {
+				sb.WriteString("--\n")
+			}
+			for j := start; j < i; j++ {
+				fmt.Fprintf(&sb, "%s-%d- %s\n", name, j+1, grepLine(lines[j]))
+			}
+			fmt.Fprintf(&sb, "%s:%d: %s\n", name, i+1, grepLine(line))
+			last = i
+			for j := i + 1; j <= i+contextLines && j < len(lines); j++ {
+				if re.MatchString(lines[j]) {
+					break
+				}
+				fmt.Fprintf(&sb, "%s-%d- %s\n", name, j+1, grepLine(lines[j]))
+				last = j
+			}
+		}
+		if found && contextLines > 0 {
+			sb.WriteString("--\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("search failed: %v", err))
+	}
+
+	if matches == 0 {
+		return NewToolResult("No matches found")
+	}
+	header := fmt.Sprintf("Found %d matches in %d files", matches, files)
+	if capped {
+		header += fmt.Sprintf(" (stopped at max_results=%d; narrow the pattern or path to see more)", maxResults)
+	}
+	if skipped > 0 {
+		header += fmt.Sprintf(" (%d files over %d MB skipped)", skipped, grepMaxFileSize>>20)
+	}
+	return NewToolResult(header + "\n" + strings.TrimSuffix(sb.String(), "--\n"))
+}
+
+func grepLine(line string) string {
+	return truncateLine(strings.TrimSuffix(line, "\r"), grepMaxLineLength)
+}
+
+// truncateLine shortens line to at most max bytes on a rune boundary.
+func truncateLine(line string, max int) string {
+	if len(line) <= max {
+		return line
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
+
+type FindFilesTool struct {
+	workspace string
+	restrict  bool
+}
+
+func NewFindFilesTool(workspace string, restrict bool) *FindFilesTool {
+	return &FindFilesTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *FindFilesTool) Name() string {
+	return "find_files"
+}
+
+func (t *FindFilesTool) Description() string {
+	return "Find files by name glob, size and modification time. Returns path, size and modification time for each match."
+}
+
+func (t *FindFilesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Glob to match (e.g. \"*.md\", \"docs/**/*.{png,jpg}\"). Globs without '/' match the file name. Default: \"*\"",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to search (default: workspace root)",
+			},
+			"type": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"file", "dir", "any"},
+				"description": "Entry type to return (default: file)",
+			},
+			"min_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "Minimum file size in bytes",
+			},
+			"max_size": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum file size in bytes",
+			},
+			"newer_than": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries modified within this duration (e.g. \"30m\", \"24h\", \"7d\")",
+			},
+			"older_than": map[string]interface{}{
+				"type":        "string",
+				"description": "Only entries modified longer ago than this duration",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of entries (default %d, max %d)", findDefaultResults, findMaxResults),
+			},
+		},
+	}
+}
+
+func (t *FindFilesTool) ConcurrencySafe() bool {
+	return true
+}
+
+func (t *FindFilesTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "*"
+	}
+	glob, err := compileGlob(pattern)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	kind, _ := args["type"].(string)
+	switch kind {
+	case "":
+		kind = "file"
+	case "file", "dir", "any":
+	default:
+		return ErrorResult(fmt.Sprintf("invalid type %q (use file, dir or any)", kind))
+	}
+	minSize := int64(intArg(args, "min_size", -1))
+	maxSize := int64(intArg(args, "max_size", -1))
+
+	now := time.Now()
+	var newerThan, olderThan time.Time
+	if s, _ := args["newer_than"].(string); s != "" {
+		d, err := parseAge(s)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		newerThan = now.Add(-d)
+	}
+	if s, _ := args["older_than"].(string); s != "" {
+		d, err := parseAge(s)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		olderThan = now.Add(-d)
+	}
+	maxResults := clampResults(args, findDefaultResults, findMaxResults)
+
+	path, _ := args["path"].(string)
+	walker, err := newSearchWalker(path, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var sb strings.Builder
+	count := 0
+	capped := false
+	err = walker.walk(ctx, func(path string, info fs.FileInfo) error {
+		if path == walker.root {
+			return nil
+		}
+		isDir := info.IsDir()
+		switch {
+		case kind == "file" && isDir, kind == "dir" && !isDir:
+			return nil
+		case !isDir && !info.Mode().IsRegular():
+			return nil
+		}
+		if !glob.match(walker.rel(path)) {
+			return nil
+		}
+		if !isDir && ((minSize >= 0 && info.Size() < minSize) || (maxSize >= 0 && info.Size() > maxSize)) {
+			return nil
+		}
+		if (!newerThan.IsZero() && info.ModTime().Before(newerThan)) || (!olderThan.IsZero() && info.ModTime().After(olderThan)) {
+			return nil
+		}
+		if count >= maxResults {
+			capped = true
+			return errSearchDone
+		}
+		count++
+		name := walker.display(path)
+		modified := info.ModTime().Format("2006-01-02 15:04")
+		if isDir {
+			fmt.Fprintf(&sb, "%s/  -  %s\n", name, modified)
+		} else {
+			fmt.Fprintf(&sb, "%s  %s  %s\n", name, formatSize(info.Size()), modified)
+		}
+		return nil
+	})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("search failed: %v", err))
+	}
+
+	if count == 0 {
+		return NewToolResult("No files found")
+	}
+	header := fmt.Sprintf("Found %d entries", count)
+	if capped {
+		header += fmt.Sprintf(" (stopped at max_results=%d; narrow the pattern or path to see more)", maxResults)
+	}
+	return NewToolResult(header + "\n" + sb.String())
+}
+
+// parseAge parses a duration, additionally accepting a "d" (days) suffix.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
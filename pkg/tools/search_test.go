@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeSearchTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":              "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"README.md":            "# Hello\nSee main.go\n",
+		"src/app/app.ts":       "export const greeting = 'Hello'\n",
+		"src/app/app_test.ts":  "test('hello')\n",
+		"node_modules/x/x.js":  "hello\n",
+		".git/config":          "hello\n",
+		"assets/logo.bin":      "hello\x00world",
+		"docs/notes/guide.txt": strings.Repeat("filler\n", 5) + "needle\n" + strings.Repeat("filler\n", 5),
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestGrepTool_SearchesWorkspace(t *testing.T) {
+	dir := writeSearchTree(t)
+	tool := NewGrepTool(dir, true)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"pattern": "hello", "ignore_case": true})
+	if result.IsError {
+		t.Fatalf("grep failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{"main.go:4: \tprintln(\"hello\")", "README.md:1: # Hello", "src/app/app.ts:1:", "src/app/app_test.ts:1:"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("missing %q in:\n%s", want, result.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"node_modules", ".git", "logo.bin"} {
+		if strings.Contains(result.ForLLM, unwanted) {
+			t.Errorf("unexpected %q in:\n%s", unwanted, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"pattern": "(?i)hello",
+		"include": []interface{}{"src/**/*.{ts,tsx}"},
+		"exclude": []interface{}{"*_test.ts"},
+	})
+	if !strings.Contains(result.ForLLM, "Found 1 matches in 1 files") || !strings.Contains(result.ForLLM, "src/app/app.ts:1:") {
+		t.Errorf("include/exclude = %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"pattern": "hello", "ignore_case": true, "max_results": float64(2)})
+	if !strings.Contains(result.ForLLM, "Found 2 matches") || !strings.Contains(result.ForLLM, "max_results=2") {
+		t.Errorf("capped = %s", result.ForLLM)
+	}
+
+	if result := tool.Execute(ctx, map[string]interface{}{"pattern": "hello", "path": "/etc"}); !result.IsError {
+		t.Errorf("searched outside the workspace: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"pattern": "("}); !result.IsError {
+		t.Errorf("invalid pattern accepted: %s", result.ForLLM)
+	}
+}
+
+func TestGrepTool_Context(t *testing.T) {
+	dir := writeSearchTree(t)
+	tool := NewGrepTool(dir, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "needle",
+		"path":    "docs",
+		"context": float64(2),
+	})
+	want := "Found 1 matches in 1 files\n" +
+		"docs/notes/guide.txt-4- filler\n" +
+		"docs/notes/guide.txt-5- filler\n" +
+		"docs/notes/guide.txt:6: needle\n" +
+		"docs/notes/guide.txt-7- filler\n" +
+		"docs/notes/guide.txt-8- filler\n"
+	if result.ForLLM != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.ForLLM, want)
+	}
+}
+
+func TestGrepTool_SkipsEscapingSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("hunter2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(dir, "link.txt")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	result := NewGrepTool(dir, true).Execute(context.Background(), map[string]interface{}{"pattern": "hunter2"})
+	if strings.Contains(result.ForLLM, "hunter2") {
+		t.Errorf("followed a symlink out of the workspace: %s", result.ForLLM)
+	}
+	result = NewGrepTool(dir, false).Execute(context.Background(), map[string]interface{}{"pattern": "hunter2"})
+	if !strings.Contains(result.ForLLM, "link.txt:1: hunter2") {
+		t.Errorf("unrestricted grep = %s", result.ForLLM)
+	}
+}
+
+func TestFindFilesTool_Filters(t *testing.T) {
+	dir := writeSearchTree(t)
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "README.md"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewFindFilesTool(dir, true)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		args   map[string]interface{}
+		want   []string
+		unwant []string
+	}{
+		{"basename glob", map[string]interface{}{"pattern": "*.ts"}, []string{"src/app/app.ts", "src/app/app_test.ts"}, []string{"main.go", "node_modules"}},
+		{"path glob", map[string]interface{}{"pattern": "docs/**/*.txt"}, []string{"docs/notes/guide.txt"}, []string{"app.ts"}},
+		{"directories", map[string]interface{}{"type": "dir"}, []string{"src/app/", "docs/notes/"}, []string{"main.go", ".git"}},
+		{"min size", map[string]interface{}{"min_size": float64(60)}, []string{"docs/notes/guide.txt"}, []string{"main.go", "README.md"}},
+		{"newer than", map[string]interface{}{"newer_than": "1d"}, []string{"main.go"}, []string{"README.md"}},
+		{"older than", map[string]interface{}{"older_than": "24h"}, []string{"README.md"}, []string{"main.go"}},
+		{"sub path", map[string]interface{}{"path": "src"}, []string{"src/app/app.ts"}, []string{"main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tool.Execute(ctx, tt.args)
+			if result.IsError {
+				t.Fatalf("find_files failed: %s", result.ForLLM)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.ForLLM, want) {
+					t.Errorf("missing %q in:\n%s", want, result.ForLLM)
+				}
+			}
+			for _, unwanted := range tt.unwant {
+				if strings.Contains(result.ForLLM, unwanted) {
+					t.Errorf("unexpected %q in:\n%s", unwanted, result.ForLLM)
+				}
+			}
+		})
+	}
+
+	if result := tool.Execute(ctx, map[string]interface{}{"path": ".."}); !result.IsError {
+		t.Errorf("searched outside the workspace: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"newer_than": "soon"}); !result.IsError {
+		t.Errorf("invalid duration accepted: %s", result.ForLLM)
+	}
+}
@@ -6,19 +6,31 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
 )
 
+// maxExecOutput is how much command output the LLM sees inline by default.
+const maxExecOutput = 10000
+
 type ExecTool struct {
 	workingDir          string
 	timeout             time.Duration
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+	outputs             *OutputStore // Keeps oversized output for read_output (nil = discard)
+	sandbox             Sandbox      // Runs commands isolated from the host (nil = run directly)
+	maxOutput           int          // Output characters the LLM sees inline
+	shell               string       // Shell program ("" = sh, or PowerShell on Windows)
+	envPassthrough      []string     // When set, only these variables are passed (names, "*" globs)
+	envScrub            []string     // Variables removed from the environment (names, "*" globs)
 }
 
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
@@ -39,9 +51,32 @@ func NewExecTool(workingDir string, restrict bool) *ExecTool {
 		denyPatterns:        denyPatterns,
 		allowPatterns:       nil,
 		restrictToWorkspace: restrict,
+		maxOutput:           maxExecOutput,
 	}
 }
 
+// NewExecToolFromConfig creates an ExecTool with the configured policy. It
+// fails on invalid patterns rather than run with a weaker guard.
+func NewExecToolFromConfig(cfg config.ExecToolsConfig, workingDir string, restrict bool) (*ExecTool, error) {
+	t := NewExecTool(workingDir, restrict)
+	if cfg.Timeout > 0 {
+		t.SetTimeout(time.Duration(cfg.Timeout) * time.Second)
+	}
+	if err := t.SetAllowPatterns(cfg.AllowPatterns); err != nil {
+		return nil, err
+	}
+	if err := t.AddDenyPatterns(cfg.DenyPatterns); err != nil {
+		return nil, err
+	}
+	if cfg.MaxOutput > 0 {
+		t.maxOutput = cfg.MaxOutput
+	}
+	t.shell = cfg.Shell
+	t.envPassthrough = cfg.EnvPassthrough
+	t.envScrub = cfg.EnvScrub
+	return t, nil
+}
+
 func (t *ExecTool) Name() string {
 	return "exec"
 }
@@ -73,18 +108,7 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return ErrorResult("command is required")
 	}
 
-	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		cwd = wd
-	}
-
-	if cwd == "" {
-		wd, err := os.Getwd()
-		if err == nil {
-			cwd = wd
-		}
-	}
-
+	cwd := t.commandDir(args)
 	if guardError := t.guardCommand(command, cwd); guardError != "" {
 		return ErrorResult(guardError)
 	}
@@ -92,21 +116,16 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	cmdCtx, cancel := context.WithTimeout(ctx, t.timeout)
 	defer cancel()
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
-	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
-	}
-	if cwd != "" {
-		cmd.Dir = cwd
+	cmd, err := t.shellCommand(cmdCtx, command, cwd)
+	if err != nil {
+		return ErrorResult(err.Error())
 	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	output := stdout.String()
 	if stderr.Len() > 0 {
 		output += "\nSTDERR:\n" + stderr.String()
@@ -128,10 +147,8 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		output = "(no output)"
 	}
 
-	maxLen := 10000
-	if len(output) > maxLen {
-		output = output[:maxLen] + fmt.Sprintf("\n... (truncated, %d more chars)", len(output)-maxLen)
-	}
+	// The LLM sees the head and tail of long output; the rest is stored
+	output = t.outputs.Preview(ctx, output, t.maxOutput)
 
 	if err != nil {
 		return &ToolResult{
@@ -148,6 +165,90 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
+// commandDir returns the directory a command runs in: the working_dir
+// argument, else the tool's working directory, else the current one.
+func (t *ExecTool) commandDir(args map[string]interface{}) string {
+	cwd := t.workingDir
+	if wd, ok := args["working_dir"].(string); ok && wd != "" {
+		cwd = wd
+	}
+	if cwd == "" {
+		if wd, err := os.Getwd(); err == nil {
+			cwd = wd
+		}
+	}
+	return cwd
+}
+
+// shellCommand returns the command running command through the shell in
+// cwd, inside the sandbox when one is set.
+func (t *ExecTool) shellCommand(ctx context.Context, command, cwd string) (*exec.Cmd, error) {
+	argv := t.shellArgs(command)
+	if t.sandbox != nil {
+		return t.sandbox.Command(ctx, argv, cwd)
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = t.env()
+	if cwd != "" {
+		cmd.Dir = cwd
+	}
+	return cmd, nil
+}
+
+// shellArgs returns the shell invocation running command.
+func (t *ExecTool) shellArgs(command string) []string {
+	shell := t.shell
+	if shell == "" {
+		if runtime.GOOS != "windows" {
+			return []string{"sh", "-c", command}
+		}
+		shell = "powershell"
+	}
+	name := shell[strings.LastIndexAny(shell, `/\`)+1:]
+	switch strings.TrimSuffix(strings.ToLower(name), ".exe") {
+	case "powershell", "pwsh":
+		return []string{shell, "-NoProfile", "-NonInteractive", "-Command", command}
+	case "cmd":
+		return []string{shell, "/C", command}
+	default:
+		return []string{shell, "-c", command}
+	}
+}
+
+// env returns the environment of commands, or nil to inherit the agent's.
+// PATH and HOME are always kept unless scrubbed.
+func (t *ExecTool) env() []string {
+	if len(t.envPassthrough) == 0 && len(t.envScrub) == 0 {
+		return nil
+	}
+	pass := append([]string{"PATH", "HOME"}, t.envPassthrough...)
+	env := []string{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if len(t.envPassthrough) > 0 && !envNameMatches(key, pass) {
+			continue
+		}
+		if envNameMatches(key, t.envScrub) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// envNameMatches reports whether an environment variable name matches one
+// of the patterns: names or globs such as "LC_*" and "*_TOKEN", compared
+// case-insensitively.
+func envNameMatches(name string, patterns []string) bool {
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *ExecTool) guardCommand(command, cwd string) string {
 	cmd := strings.TrimSpace(command)
 	lower := strings.ToLower(cmd)
@@ -171,7 +272,8 @@ func (t *ExecTool) guardCommand(command, cwd string) string {
 		}
 	}
 
-	if t.restrictToWorkspace {
+	// A sandbox enforces the workspace boundary itself
+	if t.restrictToWorkspace && t.sandbox == nil {
 		if strings.Contains(cmd, "..\\") || strings.Contains(cmd, "../") {
 			return "Command blocked by safety guard (path traversal detected)"
 		}
@@ -212,6 +314,30 @@ func (t *ExecTool) SetRestrictToWorkspace(restrict bool) {
 	t.restrictToWorkspace = restrict
 }
 
+// SetOutputStore keeps output over maxExecOutput so the LLM can read the
+// rest with read_output.
+func (t *ExecTool) SetOutputStore(s *OutputStore) {
+	t.outputs = s
+}
+
+// SetSandbox runs commands in the sandbox instead of directly on the host.
+func (t *ExecTool) SetSandbox(sb Sandbox) {
+	t.sandbox = sb
+}
+
+// AddDenyPatterns blocks commands matching the patterns, in addition to the
+// built-in guard.
+func (t *ExecTool) AddDenyPatterns(patterns []string) error {
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("invalid deny pattern %q: %w", p, err)
+		}
+		t.denyPatterns = append(t.denyPatterns, re)
+	}
+	return nil
+}
+
 func (t *ExecTool) SetAllowPatterns(patterns []string) error {
 	t.allowPatterns = make([]*regexp.Regexp, 0, len(patterns))
 	for _, p := range patterns {
@@ -4,9 +4,12 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
 )
 
 // TestShellTool_Success verifies successful command execution
@@ -186,6 +189,28 @@ func TestShellTool_OutputTruncation(t *testing.T) {
 	}
 }
 
+// TestShellTool_OutputStored verifies the full output of a long command is
+// kept for read_output, with its head and tail shown inline
+func TestShellTool_OutputStored(t *testing.T) {
+	tool := NewExecTool("", false)
+	store := NewOutputStore(t.TempDir(), 0)
+	tool.SetOutputStore(store)
+
+	ctx := WithSession(context.Background(), "s1")
+	result := tool.Execute(ctx, map[string]interface{}{
+		"command": "echo START; i=0; while [ $i -lt 3000 ]; do echo line$i; i=$((i+1)); done; echo END",
+	})
+
+	if !strings.HasPrefix(result.ForLLM, "START") || !strings.HasSuffix(strings.TrimSpace(result.ForLLM), "END") {
+		t.Errorf("preview should keep head and tail")
+	}
+	id := regexp.MustCompile(`out-[0-9a-f]{8}`).FindString(result.ForLLM)
+	full, err := store.Get("s1", id)
+	if err != nil || !strings.Contains(full, "line1500\n") {
+		t.Errorf("full output not stored (id %q): %v", id, err)
+	}
+}
+
 // TestShellTool_RestrictToWorkspace verifies workspace restriction
 func TestShellTool_RestrictToWorkspace(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -208,3 +233,70 @@ func TestShellTool_RestrictToWorkspace(t *testing.T) {
 		t.Errorf("Expected 'blocked' message for path traversal, got ForLLM: %s, ForUser: %s", result.ForLLM, result.ForUser)
 	}
 }
+
+// TestShellTool_FromConfig verifies the configured exec policy is applied
+func TestShellTool_FromConfig(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("LC_TEST", "kept")
+	t.Setenv("OTHER_VAR", "dropped")
+
+	cfg := config.DefaultConfig().Tools.Exec
+	cfg.Timeout = 1
+	cfg.AllowPatterns = config.FlexibleStringSlice{`^(echo|env|sleep)\b`}
+	cfg.DenyPatterns = config.FlexibleStringSlice{`\bsecret\b`}
+	cfg.EnvPassthrough = config.FlexibleStringSlice{"LC_*", "OPENAI_API_KEY"}
+	cfg.MaxOutput = 500
+	tool, err := NewExecToolFromConfig(cfg, t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("NewExecToolFromConfig failed: %v", err)
+	}
+	ctx := context.Background()
+
+	for command, want := range map[string]string{
+		"ls":               "not in allowlist",
+		"echo secret":      "dangerous pattern",
+		"sleep 5":          "timed out after 1s",
+		"echo rm -rf /tmp": "dangerous pattern",
+	} {
+		result := tool.Execute(ctx, map[string]interface{}{"command": command})
+		if !result.IsError || !strings.Contains(result.ForLLM, want) {
+			t.Errorf("%q: want error containing %q, got %s", command, want, result.ForLLM)
+		}
+	}
+
+	// Passthrough limits the environment; scrubbing wins over it
+	result := tool.Execute(ctx, map[string]interface{}{"command": "env"})
+	if !strings.Contains(result.ForLLM, "LC_TEST=kept") || !strings.Contains(result.ForLLM, "PATH=") {
+		t.Errorf("passed variables missing: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "OTHER_VAR") || strings.Contains(result.ForLLM, "sk-test") {
+		t.Errorf("environment not filtered: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"command": "echo " + strings.Repeat("x", 2000)})
+	if len(result.ForLLM) > 1000 {
+		t.Errorf("output not limited to max_output: %d chars", len(result.ForLLM))
+	}
+
+	cfg.DenyPatterns = config.FlexibleStringSlice{"("}
+	if _, err := NewExecToolFromConfig(cfg, "", false); err == nil {
+		t.Error("invalid deny pattern accepted")
+	}
+}
+
+// TestShellTool_ShellArgs verifies how the configured shell is invoked
+func TestShellTool_ShellArgs(t *testing.T) {
+	tests := map[string][]string{
+		"bash":               {"bash", "-c", "ls"},
+		"/usr/bin/zsh":       {"/usr/bin/zsh", "-c", "ls"},
+		"pwsh":               {"pwsh", "-NoProfile", "-NonInteractive", "-Command", "ls"},
+		`C:\Windows\cmd.exe`: {`C:\Windows\cmd.exe`, "/C", "ls"},
+	}
+	for shell, want := range tests {
+		tool := NewExecTool("", false)
+		tool.shell = shell
+		if got := tool.shellArgs("ls"); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("shell %q: got %q, want %q", shell, got, want)
+		}
+	}
+}
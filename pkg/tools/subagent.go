@@ -26,6 +26,10 @@ type SubagentManager struct {
 	mu            sync.RWMutex
 	provider      providers.LLMProvider
 	defaultModel  string
+	maxTokens     int
+	temperature   *float64
+	onResponse    ResponseCallback
+	beforeCall    func() error
 	bus           *bus.MessageBus
 	workspace     string
 	tools         *ToolRegistry
@@ -38,6 +42,7 @@ func NewSubagentManager(provider providers.LLMProvider, defaultModel, workspace
 		tasks:         make(map[string]*SubagentTask),
 		provider:      provider,
 		defaultModel:  defaultModel,
+		maxTokens:     4096,
 		bus:           bus,
 		workspace:     workspace,
 		tools:         NewToolRegistry(),
@@ -46,6 +51,60 @@ func NewSubagentManager(provider providers.LLMProvider, defaultModel, workspace
 	}
 }
 
+// SetLLMProfile overrides the model and sampling settings used by subagents.
+// A zero maxTokens keeps the current limit; a nil temperature is not sent.
+func (sm *SubagentManager) SetLLMProfile(model string, maxTokens int, temperature *float64) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.defaultModel = model
+	if maxTokens > 0 {
+		sm.maxTokens = maxTokens
+	}
+	sm.temperature = temperature
+}
+
+// llmOptions returns the options passed to the provider. Callers must hold sm.mu.
+func (sm *SubagentManager) llmOptions() map[string]any {
+	opts := map[string]any{
+		"max_tokens": sm.maxTokens,
+	}
+	if sm.temperature != nil {
+		opts["temperature"] = *sm.temperature
+	}
+	return opts
+}
+
+// ResponseCallback is invoked after each LLM call a subagent makes, with the
+// channel and chat the task originated from.
+type ResponseCallback func(channel, chatID, model string, resp *providers.LLMResponse)
+
+// SetResponseCallback sets the callback invoked after each subagent LLM call.
+func (sm *SubagentManager) SetResponseCallback(cb ResponseCallback) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.onResponse = cb
+}
+
+// SetCallCheck sets a check run before each subagent LLM call. An error
+// stops the subagent, e.g. once the daily hard budget is spent.
+func (sm *SubagentManager) SetCallCheck(check func() error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.beforeCall = check
+}
+
+// responseHook adapts the response callback for RunToolLoop.
+// Callers must hold sm.mu.
+func (sm *SubagentManager) responseHook(channel, chatID, model string) func(*providers.LLMResponse) {
+	cb := sm.onResponse
+	if cb == nil {
+		return nil
+	}
+	return func(resp *providers.LLMResponse) {
+		cb(channel, chatID, model, resp)
+	}
+}
+
 // SetTools sets the tool registry for subagent execution.
 // If not set, subagent will have access to the provided tools.
 func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
@@ -54,6 +113,25 @@ func (sm *SubagentManager) SetTools(tools *ToolRegistry) {
 	sm.tools = tools
 }
 
+type allowedToolsCtx struct{}
+
+// WithAllowedTools limits the tools of subagents started under ctx to names,
+// so a restricted turn cannot reach other tools through a subagent. An
+// empty list allows every tool.
+func WithAllowedTools(ctx context.Context, names []string) context.Context {
+	return context.WithValue(ctx, allowedToolsCtx{}, names)
+}
+
+// toolsFor returns the subagent registry limited to the tools allowed in
+// ctx. Callers hold sm.mu.
+func (sm *SubagentManager) toolsFor(ctx context.Context) *ToolRegistry {
+	names, _ := ctx.Value(allowedToolsCtx{}).([]string)
+	if len(names) == 0 || sm.tools == nil {
+		return sm.tools
+	}
+	return sm.tools.Subset(names)
+}
+
 // RegisterTool registers a tool for subagent execution.
 func (sm *SubagentManager) RegisterTool(tool Tool) {
 	sm.mu.Lock()
@@ -121,18 +199,22 @@ After completing the task, provide a clear summary of what was done.`
 
 	// Run tool loop with access to tools
 	sm.mu.RLock()
-	tools := sm.tools
+	tools := sm.toolsFor(ctx)
 	maxIter := sm.maxIterations
+	model := sm.defaultModel
+	llmOpts := sm.llmOptions()
+	onResponse := sm.responseHook(task.OriginChannel, task.OriginChatID, model)
+	beforeCall := sm.beforeCall
 	sm.mu.RUnlock()
 
 	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
 		Provider:      sm.provider,
-		Model:         sm.defaultModel,
+		Model:         model,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens": 4096,
-		},
+		LLMOptions:    llmOpts,
+		OnResponse:    onResponse,
+		BeforeCall:    beforeCall,
 	}, messages, task.OriginChannel, task.OriginChatID)
 
 	sm.mu.Lock()
@@ -278,18 +360,22 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]interface{})
 	// Use RunToolLoop to execute with tools (same as async SpawnTool)
 	sm := t.manager
 	sm.mu.RLock()
-	tools := sm.tools
+	tools := sm.toolsFor(ctx)
 	maxIter := sm.maxIterations
+	model := sm.defaultModel
+	llmOpts := sm.llmOptions()
+	onResponse := sm.responseHook(t.originChannel, t.originChatID, model)
+	beforeCall := sm.beforeCall
 	sm.mu.RUnlock()
 
 	loopResult, err := RunToolLoop(ctx, ToolLoopConfig{
 		Provider:      sm.provider,
-		Model:         sm.defaultModel,
+		Model:         model,
 		Tools:         tools,
 		MaxIterations: maxIter,
-		LLMOptions: map[string]any{
-			"max_tokens": 4096,
-		},
+		LLMOptions:    llmOpts,
+		OnResponse:    onResponse,
+		BeforeCall:    beforeCall,
 	}, messages, t.originChannel, t.originChatID)
 
 	if err != nil {
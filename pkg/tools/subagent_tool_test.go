@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -313,3 +314,60 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+// toolNamesProvider records the tool names offered in each call
+type toolNamesProvider struct {
+	MockLLMProvider
+	offered []string
+}
+
+func (p *toolNamesProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	for _, td := range tools {
+		p.offered = append(p.offered, td.Function.Name)
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+// TestSubagentTool_Execute_AllowedTools verifies that subagents only get the
+// tools allowed in the context
+func TestSubagentTool_Execute_AllowedTools(t *testing.T) {
+	provider := &toolNamesProvider{}
+	manager := NewSubagentManager(provider, "test-model", "/tmp/test", nil)
+	registry := NewToolRegistry()
+	registry.Register(&echoArgsTool{})
+	registry.Register(NewReadOutputTool(nil))
+	manager.SetTools(registry)
+	tool := NewSubagentTool(manager)
+
+	ctx := WithAllowedTools(context.Background(), []string{"echo"})
+	if result := tool.Execute(ctx, map[string]interface{}{"task": "work"}); result.IsError {
+		t.Fatalf("Execute failed: %s", result.ForLLM)
+	}
+	if strings.Join(provider.offered, ",") != "echo" {
+		t.Errorf("offered tools = %v, want [echo]", provider.offered)
+	}
+
+	provider.offered = nil
+	tool.Execute(context.Background(), map[string]interface{}{"task": "work"})
+	if len(provider.offered) != 2 {
+		t.Errorf("offered tools without an allowlist = %v, want all", provider.offered)
+	}
+}
+
+// TestSubagentTool_Execute_CallCheckStops verifies that a failing call check
+// stops the subagent before its LLM call
+func TestSubagentTool_Execute_CallCheckStops(t *testing.T) {
+	manager := NewSubagentManager(&MockLLMProvider{}, "test-model", "/tmp/test", nil)
+	var llmCalls int
+	manager.SetResponseCallback(func(channel, chatID, model string, resp *providers.LLMResponse) { llmCalls++ })
+	manager.SetCallCheck(func() error { return errors.New("daily hard budget reached") })
+	tool := NewSubagentTool(manager)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"task": "work"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "budget") {
+		t.Errorf("result = %s, want the budget error", result.ForLLM)
+	}
+	if llmCalls != 0 {
+		t.Errorf("LLM called %d times despite the failing check", llmCalls)
+	}
+}
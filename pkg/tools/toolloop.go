@@ -23,6 +23,8 @@ type ToolLoopConfig struct {
 	Tools         *ToolRegistry
 	MaxIterations int
 	LLMOptions    map[string]any
+	OnResponse    func(*providers.LLMResponse) // Called after each successful LLM call (e.g. for usage accounting)
+	BeforeCall    func() error                 // Checked before each LLM call; an error stops the loop (e.g. a spent budget)
 }
 
 // ToolLoopResult contains the result of running the tool loop.
@@ -61,6 +63,11 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 		}
 
 		// 3. Call LLM
+		if config.BeforeCall != nil {
+			if err := config.BeforeCall(); err != nil {
+				return nil, err
+			}
+		}
 		response, err := config.Provider.Chat(ctx, messages, providerToolDefs, config.Model, llmOpts)
 		if err != nil {
 			logger.ErrorCF("toolloop", "LLM call failed",
@@ -70,6 +77,9 @@ func RunToolLoop(ctx context.Context, config ToolLoopConfig, messages []provider
 				})
 			return nil, fmt.Errorf("LLM call failed: %w", err)
 		}
+		if config.OnResponse != nil {
+			config.OnResponse(response)
+		}
 
 		// 4. If no tool calls, we're done
 		if len(response.ToolCalls) == 0 {
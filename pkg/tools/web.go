@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -241,6 +242,10 @@ func (t *WebSearchTool) Parameters() map[string]interface{} {
 	}
 }
 
+func (t *WebSearchTool) ConcurrencySafe() bool {
+	return true
+}
+
 func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	query, ok := args["query"].(string)
 	if !ok {
@@ -267,6 +272,7 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}
 
 type WebFetchTool struct {
 	maxChars int
+	outputs  *OutputStore // Keeps the full text of truncated pages (nil = discard)
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
@@ -278,6 +284,12 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 	}
 }
 
+// SetOutputStore keeps the full text of pages over maxChars so the LLM can
+// read the rest with read_output.
+func (t *WebFetchTool) SetOutputStore(s *OutputStore) {
+	t.outputs = s
+}
+
 func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
@@ -304,6 +316,10 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 	}
 }
 
+func (t *WebFetchTool) ConcurrencySafe() bool {
+	return true
+}
+
 func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	urlStr, ok := args["url"].(string)
 	if !ok {
@@ -387,10 +403,10 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		extractor = "raw"
 	}
 
-	truncated := len(text) > maxChars
-	if truncated {
-		text = text[:maxChars]
-	}
+	// Long pages are shown as a head and tail preview; the full text is
+	// stored for read_output
+	truncated := utf8.RuneCountInString(text) > maxChars
+	text = t.outputs.Preview(ctx, text, maxChars)
 
 	result := map[string]interface{}{
 		"url":       urlStr,
@@ -400,11 +416,12 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		"length":    len(text),
 		"text":      text,
 	}
+	forLLM := fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v)\n\n%s", len(text), urlStr, extractor, truncated, text)
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 
 	return &ToolResult{
-		ForLLM:  fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v)", len(text), urlStr, extractor, truncated),
+		ForLLM:  forLLM,
 		ForUser: string(resultJSON),
 	}
 }
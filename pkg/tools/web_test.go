@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"strings"
 	"testing"
 )
@@ -173,6 +174,36 @@ func TestWebTool_WebFetch_Truncation(t *testing.T) {
 	}
 }
 
+// TestWebTool_WebFetch_StoresTruncatedText verifies that a long page is shown
+// to the LLM as a head and tail preview and stored in full for read_output
+func TestWebTool_WebFetch_StoresTruncatedText(t *testing.T) {
+	page := "HEAD" + strings.Repeat("x", 5000) + "TAIL"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	store := NewOutputStore(t.TempDir(), 0)
+	tool := NewWebFetchTool(1000)
+	tool.SetOutputStore(store)
+	ctx := WithSession(context.Background(), "s1")
+
+	result := tool.Execute(ctx, map[string]interface{}{"url": server.URL})
+	if result.IsError {
+		t.Fatalf("Expected success, got IsError=true: %s", result.ForLLM)
+	}
+	for _, want := range []string{"HEAD", "TAIL", "read_output"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("ForLLM missing %q: %s", want, result.ForLLM)
+		}
+	}
+	id := regexp.MustCompile(`out-[0-9a-f]+`).FindString(result.ForLLM)
+	if full, err := store.Get("s1", id); err != nil || full != page {
+		t.Errorf("stored page %q: err=%v, len=%d", id, err, len(full))
+	}
+}
+
 // TestWebTool_WebSearch_NoApiKey verifies that no tool is created when API key is missing
 func TestWebTool_WebSearch_NoApiKey(t *testing.T) {
 	tool := NewWebSearchTool(WebSearchToolOptions{BraveEnabled: true, BraveAPIKey: ""})
@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// serviceName identifies clawdroid in exported traces.
+const serviceName = "clawdroid"
+
+// OTLP span kind and status codes.
+const (
+	otlpKindInternal = 1
+	otlpStatusError  = 2
+)
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// ToOTLP encodes spans as an OTLP/JSON ExportTraceServiceRequest.
+func ToOTLP(spans []Span) ([]byte, error) {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              otlpKindInternal,
+			StartTimeUnixNano: unixNano(s.Start),
+			EndTimeUnixNano:   unixNano(s.End),
+			Attributes:        otlpAttributes(s.Attributes),
+		}
+		if s.Error != "" {
+			span.Status = &otlpStatus{Code: otlpStatusError, Message: s.Error}
+		}
+		out = append(out, span)
+	}
+
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{{
+			Key: "service.name", Value: map[string]interface{}{"stringValue": serviceName},
+		}}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: serviceName},
+			Spans: out,
+		}},
+	}}})
+}
+
+// otlpAttributes converts attributes to OTLP key-values, sorted by key.
+// OTLP/JSON encodes 64-bit integers as strings.
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		var v map[string]interface{}
+		switch val := attrs[k].(type) {
+		case bool:
+			v = map[string]interface{}{"boolValue": val}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(val)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+		case float64:
+			// Attributes read back from JSONL are float64
+			if val == float64(int64(val)) {
+				v = map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+			} else {
+				v = map[string]interface{}{"doubleValue": val}
+			}
+		case string:
+			v = map[string]interface{}{"stringValue": val}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+		}
+		kvs = append(kvs, otlpKeyValue{Key: k, Value: v})
+	}
+	return kvs
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// Exporter sends traces to an OTLP/HTTP collector endpoint
+// (e.g. http://localhost:4318/v1/traces).
+type Exporter struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewExporter(endpoint string) *Exporter {
+	return &Exporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export posts spans to the collector.
+func (e *Exporter) Export(ctx context.Context, spans []Span) error {
+	body, err := ToOTLP(spans)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("OTLP export failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxTraces is how many traces are kept when the config leaves it unset.
+const DefaultMaxTraces = 500
+
+// Dir returns the trace directory inside a data directory.
+func Dir(dataDir string) string {
+	return filepath.Join(dataDir, "traces")
+}
+
+// Store keeps traces as one JSONL file per trace, one span per line, and
+// deletes the oldest files beyond its limit.
+type Store struct {
+	dir       string
+	maxTraces int
+}
+
+// Summary describes a stored trace.
+type Summary struct {
+	ID       string
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Spans    int
+	Error    string
+}
+
+func NewStore(dir string, maxTraces int) *Store {
+	if maxTraces <= 0 {
+		maxTraces = DefaultMaxTraces
+	}
+	return &Store{dir: dir, maxTraces: maxTraces}
+}
+
+// Save writes a finished trace and prunes old ones.
+func (s *Store) Save(spans []Span) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, span := range spans {
+		line, err := json.Marshal(span)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	path := filepath.Join(s.dir, spans[0].TraceID+".jsonl")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	s.prune()
+	return nil
+}
+
+// Load reads a trace by ID or unique ID prefix.
+func (s *Store) Load(id string) ([]Span, error) {
+	path, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var spans []Span
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var span Span
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filepath.Base(path), err)
+		}
+		spans = append(spans, span)
+	}
+	return spans, scanner.Err()
+}
+
+// List returns the stored traces, newest first.
+func (s *Store) List() []Summary {
+	var summaries []Summary
+	for _, path := range s.files() {
+		id := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		spans, err := s.Load(id)
+		if err != nil || len(spans) == 0 {
+			continue
+		}
+		root := Root(spans)
+		summaries = append(summaries, Summary{
+			ID:       id,
+			Name:     root.Name,
+			Start:    root.Start,
+			Duration: root.Duration(),
+			Spans:    len(spans),
+			Error:    root.Error,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Start.After(summaries[j].Start)
+	})
+	return summaries
+}
+
+// Root returns the span without a parent, or the first span.
+func Root(spans []Span) Span {
+	for _, span := range spans {
+		if span.ParentID == "" {
+			return span
+		}
+	}
+	return spans[0]
+}
+
+// find resolves an ID or unique ID prefix to a trace file.
+func (s *Store) find(id string) (string, error) {
+	var matches []string
+	for _, path := range s.files() {
+		if strings.HasPrefix(filepath.Base(path), id) {
+			matches = append(matches, path)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("trace %s not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("trace ID prefix %s is ambiguous (%d matches)", id, len(matches))
+	}
+}
+
+func (s *Store) files() []string {
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	return paths
+}
+
+// prune deletes the least recently written traces beyond maxTraces.
+func (s *Store) prune() {
+	paths := s.files()
+	if len(paths) <= s.maxTraces {
+		return
+	}
+	modTimes := make(map[string]time.Time, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			modTimes[path] = info.ModTime()
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return modTimes[paths[i]].Before(modTimes[paths[j]])
+	})
+	for _, path := range paths[:len(paths)-s.maxTraces] {
+		_ = os.Remove(path)
+	}
+}
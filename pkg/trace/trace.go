@@ -0,0 +1,154 @@
+// Package trace records the execution of an agent turn as a tree of timed
+// spans (LLM calls, tool executions, compression, summarization), stores each
+// trace as JSONL, and exports traces in OpenTelemetry OTLP/JSON format.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Span is one timed operation of a trace.
+type Span struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// Duration returns how long the span took.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Trace collects the spans of one operation. It is safe for concurrent use:
+// parallel tool calls end their spans from different goroutines.
+type Trace struct {
+	ID string
+
+	mu    sync.Mutex
+	root  *ActiveSpan
+	spans []Span
+}
+
+// ActiveSpan is a span that has not ended yet. All methods are no-ops on a
+// nil span, so callers need not check whether tracing is enabled.
+type ActiveSpan struct {
+	trace *Trace
+	span  Span
+	ended bool
+}
+
+type spanKey struct{}
+
+// New starts a trace whose root span is called name and returns a context
+// carrying it.
+func New(ctx context.Context, name string, attrs map[string]interface{}) (context.Context, *Trace) {
+	t := &Trace{ID: newID(16)}
+	t.root = t.start(name, "", attrs)
+	return context.WithValue(ctx, spanKey{}, t.root), t
+}
+
+// Start starts a child of the span carried by ctx and returns a context
+// carrying the new span. Without a trace in ctx it returns ctx and nil.
+func Start(ctx context.Context, name string, attrs map[string]interface{}) (context.Context, *ActiveSpan) {
+	parent, _ := ctx.Value(spanKey{}).(*ActiveSpan)
+	if parent == nil {
+		return ctx, nil
+	}
+	s := parent.trace.start(name, parent.span.SpanID, attrs)
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (t *Trace) start(name, parentID string, attrs map[string]interface{}) *ActiveSpan {
+	if attrs == nil {
+		attrs = make(map[string]interface{})
+	}
+	return &ActiveSpan{
+		trace: t,
+		span: Span{
+			TraceID:    t.ID,
+			SpanID:     newID(8),
+			ParentID:   parentID,
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: attrs,
+		},
+	}
+}
+
+// Root returns the trace's root span.
+func (t *Trace) Root() *ActiveSpan {
+	if t == nil {
+		return nil
+	}
+	return t.root
+}
+
+// Finish ends the root span and returns all ended spans ordered by start
+// time. Spans still running are left out.
+func (t *Trace) Finish() []Span {
+	if t == nil {
+		return nil
+	}
+	t.root.End()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	spans := append([]Span(nil), t.spans...)
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].Start.Before(spans[j].Start)
+	})
+	return spans
+}
+
+// SetAttr sets an attribute of the span.
+func (s *ActiveSpan) SetAttr(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	s.span.Attributes[key] = value
+}
+
+// SetError marks the span as failed. A nil error is ignored.
+func (s *ActiveSpan) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	s.span.Error = err.Error()
+}
+
+// End records the span in its trace. Only the first call has an effect.
+func (s *ActiveSpan) End() {
+	if s == nil {
+		return
+	}
+	s.trace.mu.Lock()
+	defer s.trace.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	s.trace.spans = append(s.trace.spans, s.span)
+}
+
+// newID returns n random bytes in hex, the form OTLP uses for trace (16
+// bytes) and span (8 bytes) IDs.
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,132 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTrace_RecordsSpanTree(t *testing.T) {
+	ctx, tr := New(context.Background(), "process_message", map[string]interface{}{"channel": "cli"})
+
+	llmCtx, llm := Start(ctx, "llm.chat", nil)
+	llm.SetAttr("prompt_tokens", 10)
+	_, tool := Start(llmCtx, "tool.exec", nil)
+	tool.SetError(errors.New("boom"))
+	tool.End()
+	llm.End()
+	llm.End() // Second End is ignored
+
+	_, running := Start(ctx, "never_ended", nil)
+	_ = running
+
+	spans := tr.Finish()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3 (unended spans are dropped)", len(spans))
+	}
+	root, llmSpan, toolSpan := spans[0], spans[1], spans[2]
+	if root.Name != "process_message" || root.ParentID != "" || root.Attributes["channel"] != "cli" {
+		t.Errorf("root = %+v", root)
+	}
+	if llmSpan.ParentID != root.SpanID || toolSpan.ParentID != llmSpan.SpanID {
+		t.Error("spans are not linked to their parents")
+	}
+	if toolSpan.Error != "boom" {
+		t.Errorf("tool error = %q", toolSpan.Error)
+	}
+	if len(root.TraceID) != 32 || len(root.SpanID) != 16 {
+		t.Errorf("IDs %q/%q are not OTLP-sized", root.TraceID, root.SpanID)
+	}
+}
+
+func TestStart_WithoutTraceIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "llm.chat", nil)
+	if span != nil || ctx != context.Background() {
+		t.Fatal("Start without a trace should return the context and a nil span")
+	}
+	span.SetAttr("k", 1)
+	span.SetError(errors.New("x"))
+	span.End()
+}
+
+func TestStore_SaveLoadListPrune(t *testing.T) {
+	store := NewStore(t.TempDir(), 2)
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ctx, tr := New(context.Background(), "process_message", nil)
+		_, s := Start(ctx, "llm.chat", map[string]interface{}{"model": "m"})
+		s.End()
+		if err := store.Save(tr.Finish()); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		ids = append(ids, tr.ID)
+	}
+
+	list := store.List()
+	if len(list) != 2 {
+		t.Fatalf("List() = %d traces, want 2 after pruning", len(list))
+	}
+	if list[0].ID != ids[2] || list[0].Spans != 2 {
+		t.Errorf("newest trace = %+v, want %s with 2 spans", list[0], ids[2])
+	}
+
+	spans, err := store.Load(ids[2][:8])
+	if err != nil {
+		t.Fatalf("Load by prefix: %v", err)
+	}
+	if spans[1].Attributes["model"] != "m" {
+		t.Errorf("loaded span attributes = %v", spans[1].Attributes)
+	}
+	if _, err := store.Load(ids[0]); err == nil {
+		t.Error("oldest trace should have been pruned")
+	}
+}
+
+func TestToOTLP_EncodesSpans(t *testing.T) {
+	ctx, tr := New(context.Background(), "process_message", nil)
+	_, s := Start(ctx, "tool.exec", map[string]interface{}{"args_bytes": 12, "tool": "exec"})
+	s.SetError(errors.New("denied"))
+	s.End()
+
+	data, err := ToOTLP(tr.Finish())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID           string `json:"traceId"`
+					ParentSpanID      string `json:"parentSpanId"`
+					StartTimeUnixNano string `json:"startTimeUnixNano"`
+					Attributes        []struct {
+						Key   string                 `json:"key"`
+						Value map[string]interface{} `json:"value"`
+					} `json:"attributes"`
+					Status *struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("invalid OTLP JSON: %v", err)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	tool := spans[1]
+	if tool.ParentSpanID == "" || tool.TraceID != tr.ID || strings.TrimLeft(tool.StartTimeUnixNano, "0123456789") != "" {
+		t.Errorf("tool span = %+v", tool)
+	}
+	if tool.Status == nil || tool.Status.Code != otlpStatusError {
+		t.Error("failed span should have error status")
+	}
+	if got := tool.Attributes[0]; got.Key != "args_bytes" || got.Value["intValue"] != "12" {
+		t.Errorf("first attribute = %+v, want args_bytes intValue \"12\"", got)
+	}
+}
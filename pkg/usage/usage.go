@@ -0,0 +1,336 @@
+// Package usage records LLM token usage and cost, aggregated per day by
+// session, user, channel, and model, and enforces daily budgets.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+// dayFormat is the layout of day keys (local time).
+const dayFormat = "2006-01-02"
+
+// retentionDays is how many days of usage are kept on disk.
+const retentionDays = 90
+
+// StorePath returns the usage file location inside a data directory.
+func StorePath(dataDir string) string {
+	return filepath.Join(dataDir, "usage", "usage.json")
+}
+
+// Entry is the usage of a single LLM call.
+type Entry struct {
+	SessionKey       string
+	UserID           string // UserStore ID; empty when the sender is unknown
+	Channel          string
+	Model            string // "provider/model_name"
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Totals accumulates usage for one aggregation key.
+type Totals struct {
+	Calls            int     `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (t *Totals) add(e Entry, cost float64) {
+	t.Calls++
+	t.PromptTokens += int64(e.PromptTokens)
+	t.CompletionTokens += int64(e.CompletionTokens)
+	t.Cost += cost
+}
+
+func (t *Totals) merge(o *Totals) {
+	t.Calls += o.Calls
+	t.PromptTokens += o.PromptTokens
+	t.CompletionTokens += o.CompletionTokens
+	t.Cost += o.Cost
+}
+
+// DayUsage is the usage of one day, in total and broken down by key.
+type DayUsage struct {
+	Total    Totals             `json:"total"`
+	Sessions map[string]*Totals `json:"sessions"`
+	Users    map[string]*Totals `json:"users"`
+	Channels map[string]*Totals `json:"channels"`
+	Models   map[string]*Totals `json:"models"`
+}
+
+func newDayUsage() *DayUsage {
+	return &DayUsage{
+		Sessions: make(map[string]*Totals),
+		Users:    make(map[string]*Totals),
+		Channels: make(map[string]*Totals),
+		Models:   make(map[string]*Totals),
+	}
+}
+
+// clone returns a deep copy of d.
+func (d *DayUsage) clone() DayUsage {
+	c := DayUsage{Total: d.Total}
+	copyMap := func(m map[string]*Totals) map[string]*Totals {
+		out := make(map[string]*Totals, len(m))
+		for k, v := range m {
+			t := *v
+			out[k] = &t
+		}
+		return out
+	}
+	c.Sessions = copyMap(d.Sessions)
+	c.Users = copyMap(d.Users)
+	c.Channels = copyMap(d.Channels)
+	c.Models = copyMap(d.Models)
+	return c
+}
+
+// Merge sums several days of usage into one.
+func Merge(days ...DayUsage) DayUsage {
+	out := newDayUsage()
+	mergeMap := func(dst, src map[string]*Totals) {
+		for k, v := range src {
+			t, ok := dst[k]
+			if !ok {
+				t = &Totals{}
+				dst[k] = t
+			}
+			t.merge(v)
+		}
+	}
+	for _, d := range days {
+		out.Total.merge(&d.Total)
+		mergeMap(out.Sessions, d.Sessions)
+		mergeMap(out.Users, d.Users)
+		mergeMap(out.Channels, d.Channels)
+		mergeMap(out.Models, d.Models)
+	}
+	return *out
+}
+
+// BudgetState reports how today's spend compares to the configured budgets.
+type BudgetState int
+
+const (
+	BudgetOK   BudgetState = iota // Under both budgets
+	BudgetSoft                    // Soft budget reached: downgrade models
+	BudgetHard                    // Hard budget reached: refuse LLM calls
+)
+
+// Tracker records usage and persists the per-day aggregates as JSON.
+type Tracker struct {
+	path       string
+	prices     map[string]config.ModelPrice
+	softBudget float64
+	hardBudget float64
+
+	mu   sync.Mutex
+	days map[string]*DayUsage
+	now  func() time.Time
+}
+
+// NewTracker creates a Tracker stored at path, loading any existing data.
+// An empty path keeps usage in memory only.
+func NewTracker(path string, cfg config.UsageConfig) *Tracker {
+	t := &Tracker{
+		path:       path,
+		prices:     cfg.Prices,
+		softBudget: cfg.DailySoftBudget,
+		hardBudget: cfg.DailyHardBudget,
+		days:       make(map[string]*DayUsage),
+		now:        time.Now,
+	}
+	if path != "" {
+		_ = t.load()
+	}
+	return t
+}
+
+// Record adds an entry to today's totals and saves them. It returns the
+// entry's cost.
+func (t *Tracker) Record(e Entry) (float64, error) {
+	cost := t.Cost(e.Model, e.PromptTokens, e.CompletionTokens)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	day := t.now().Format(dayFormat)
+	d, ok := t.days[day]
+	if !ok {
+		d = newDayUsage()
+		t.days[day] = d
+		t.prune()
+	}
+
+	d.Total.add(e, cost)
+	addTo(d.Sessions, e.SessionKey, e, cost)
+	addTo(d.Users, e.UserID, e, cost)
+	addTo(d.Channels, e.Channel, e, cost)
+	addTo(d.Models, e.Model, e, cost)
+
+	return cost, t.save()
+}
+
+func addTo(m map[string]*Totals, key string, e Entry, cost float64) {
+	if key == "" {
+		key = "unknown"
+	}
+	t, ok := m[key]
+	if !ok {
+		t = &Totals{}
+		m[key] = t
+	}
+	t.add(e, cost)
+}
+
+// Cost returns the price of a call in the price table's currency. Models are
+// looked up by "provider/model_name", then by bare model name; unpriced
+// models cost nothing.
+func (t *Tracker) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := t.prices[model]
+	if !ok {
+		if idx := strings.Index(model, "/"); idx != -1 {
+			price, ok = t.prices[model[idx+1:]]
+		}
+	}
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1_000_000
+}
+
+// Today returns a copy of today's usage.
+func (t *Tracker) Today() DayUsage {
+	return t.Day(t.now().Format(dayFormat))
+}
+
+// Day returns a copy of the usage for a day in YYYY-MM-DD format.
+func (t *Tracker) Day(day string) DayUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	d, ok := t.days[day]
+	if !ok {
+		return newDayUsage().clone()
+	}
+	return d.clone()
+}
+
+// Days returns the recorded days, oldest first.
+func (t *Tracker) Days() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	days := make([]string, 0, len(t.days))
+	for day := range t.days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// Budget reports today's budget state. A zero budget is disabled.
+func (t *Tracker) Budget() BudgetState {
+	spent := t.SpentToday()
+	switch {
+	case t.hardBudget > 0 && spent >= t.hardBudget:
+		return BudgetHard
+	case t.softBudget > 0 && spent >= t.softBudget:
+		return BudgetSoft
+	default:
+		return BudgetOK
+	}
+}
+
+// SpentToday returns today's total cost.
+func (t *Tracker) SpentToday() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if d, ok := t.days[t.now().Format(dayFormat)]; ok {
+		return d.Total.Cost
+	}
+	return 0
+}
+
+// Budgets returns the configured soft and hard daily budgets.
+func (t *Tracker) Budgets() (soft, hard float64) {
+	return t.softBudget, t.hardBudget
+}
+
+// prune drops days older than retentionDays. Must be called with the lock held.
+func (t *Tracker) prune() {
+	cutoff := t.now().AddDate(0, 0, -retentionDays).Format(dayFormat)
+	for day := range t.days {
+		if day < cutoff {
+			delete(t.days, day)
+		}
+	}
+}
+
+// save writes the aggregates atomically. Must be called with the lock held.
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(t.days, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage: %w", err)
+	}
+
+	tempFile := t.path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tempFile, t.path); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// load reads the aggregates from disk.
+func (t *Tracker) load() error {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read usage file: %w", err)
+	}
+
+	days := make(map[string]*DayUsage)
+	if err := json.Unmarshal(data, &days); err != nil {
+		return fmt.Errorf("failed to unmarshal usage: %w", err)
+	}
+	for _, d := range days {
+		empty := newDayUsage()
+		if d.Sessions == nil {
+			d.Sessions = empty.Sessions
+		}
+		if d.Users == nil {
+			d.Users = empty.Users
+		}
+		if d.Channels == nil {
+			d.Channels = empty.Channels
+		}
+		if d.Models == nil {
+			d.Models = empty.Models
+		}
+	}
+	t.days = days
+	return nil
+}
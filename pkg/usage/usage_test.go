@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KarakuriAgent/clawdroid/pkg/config"
+)
+
+func newTestTracker(t *testing.T, cfg config.UsageConfig) *Tracker {
+	t.Helper()
+	tr := NewTracker(filepath.Join(t.TempDir(), "usage", "usage.json"), cfg)
+	tr.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local) }
+	return tr
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestTracker_RecordAggregatesAndPersists(t *testing.T) {
+	cfg := config.UsageConfig{
+		Prices: map[string]config.ModelPrice{
+			"openai/gpt-test": {Input: 2, Output: 8},
+		},
+	}
+	tr := newTestTracker(t, cfg)
+
+	cost, err := tr.Record(Entry{SessionKey: "telegram:1", UserID: "u1", Channel: "telegram", Model: "openai/gpt-test", PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if !almostEqual(cost, 6) {
+		t.Errorf("cost = %v, want 6", cost)
+	}
+	if _, err := tr.Record(Entry{SessionKey: "discord:2", Channel: "discord", Model: "gemini/unpriced", PromptTokens: 100}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	today := tr.Today()
+	if today.Total.Calls != 2 || today.Total.PromptTokens != 1_000_100 || !almostEqual(today.Total.Cost, 6) {
+		t.Errorf("Total = %+v", today.Total)
+	}
+	if u := today.Users["u1"]; u == nil || !almostEqual(u.Cost, 6) {
+		t.Errorf("Users[u1] = %+v", u)
+	}
+	if u := today.Users["unknown"]; u == nil || u.Calls != 1 {
+		t.Errorf("Users[unknown] = %+v, want the unresolved sender", u)
+	}
+
+	reloaded := NewTracker(tr.path, cfg)
+	reloaded.now = tr.now
+	if got := reloaded.Today().Channels["telegram"]; got == nil || got.CompletionTokens != 500_000 {
+		t.Errorf("Channels[telegram] after reload = %+v", got)
+	}
+}
+
+func TestTracker_CostFallsBackToBareModelName(t *testing.T) {
+	tr := newTestTracker(t, config.UsageConfig{
+		Prices: map[string]config.ModelPrice{"claude-test": {Input: 3, Output: 15}},
+	})
+	if got := tr.Cost("anthropic/claude-test", 1_000_000, 0); !almostEqual(got, 3) {
+		t.Errorf("Cost = %v, want 3", got)
+	}
+}
+
+func TestTracker_Budget(t *testing.T) {
+	tr := newTestTracker(t, config.UsageConfig{
+		Prices:          map[string]config.ModelPrice{"openai/gpt-test": {Input: 1}},
+		DailySoftBudget: 1,
+		DailyHardBudget: 2,
+	})
+
+	if got := tr.Budget(); got != BudgetOK {
+		t.Errorf("Budget() = %v, want BudgetOK", got)
+	}
+	_, _ = tr.Record(Entry{Model: "openai/gpt-test", PromptTokens: 1_000_000})
+	if got := tr.Budget(); got != BudgetSoft {
+		t.Errorf("Budget() = %v, want BudgetSoft", got)
+	}
+	_, _ = tr.Record(Entry{Model: "openai/gpt-test", PromptTokens: 1_000_000})
+	if got := tr.Budget(); got != BudgetHard {
+		t.Errorf("Budget() = %v, want BudgetHard", got)
+	}
+
+	// A new day starts from zero
+	tr.now = func() time.Time { return time.Date(2026, 3, 2, 0, 0, 1, 0, time.Local) }
+	if got := tr.Budget(); got != BudgetOK {
+		t.Errorf("Budget() on the next day = %v, want BudgetOK", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tr := newTestTracker(t, config.UsageConfig{})
+	_, _ = tr.Record(Entry{Channel: "telegram", PromptTokens: 10})
+	day1 := tr.Today()
+	tr.now = func() time.Time { return time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local) }
+	_, _ = tr.Record(Entry{Channel: "telegram", PromptTokens: 5})
+
+	merged := Merge(day1, tr.Today())
+	if merged.Total.PromptTokens != 15 || merged.Channels["telegram"].Calls != 2 {
+		t.Errorf("Merge = %+v", merged.Total)
+	}
+	if len(tr.Days()) != 2 {
+		t.Errorf("Days() = %v, want 2 days", tr.Days())
+	}
+}